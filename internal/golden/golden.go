@@ -0,0 +1,109 @@
+// Package golden - the dfxp test-vector corpus shared by the zmqencdec,
+// jsonencdec and zmqclient tests. testdata holds one file per command with
+// the JSON form, the wire bytes and the decoded section of its request and
+// response frames.
+//
+// The JSON forms are the source of the corpus: after a codec change,
+//
+//	go test ./zmqencdec -run TestGoldenCorpus -update
+//
+// recomputes the lengths, hex frames and decoded sections from them.
+package golden
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden corpus from its JSON forms")
+
+// Update - whether the test run was asked to rewrite the corpus
+func Update() bool {
+	return *update
+}
+
+// Frame - one request or response frame
+type Frame struct {
+	// Json - the message as jsonencdec encodes it: Header and section only
+	Json json.RawMessage `json:"json"`
+	// Hex - the frame on the wire
+	Hex string `json:"hex"`
+	// Section - the decoded section, formatted with %+v
+	Section string `json:"section"`
+}
+
+// Vector - the frames of one command
+type Vector struct {
+	Command  string `json:"command"`
+	Request  *Frame `json:"request,omitempty"`
+	Response *Frame `json:"response,omitempty"`
+
+	path string
+}
+
+// Dir - directory of the corpus files
+func Dir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "testdata")
+}
+
+// Load - every vector of the corpus, sorted by file name
+func Load(t testing.TB) []*Vector {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(Dir(), "*.json"))
+	if err != nil {
+		t.Fatalf("Glob failed. Err:%v", err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no golden vectors in %s", Dir())
+	}
+	sort.Strings(paths)
+
+	vectors := make([]*Vector, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile failed. Err:%v", err)
+		}
+		vector := &Vector{path: path}
+		if err := json.Unmarshal(data, vector); err != nil {
+			t.Fatalf("%s: Unmarshal failed. Err:%v", filepath.Base(path), err)
+		}
+		vectors = append(vectors, vector)
+	}
+	return vectors
+}
+
+// Name - file name of the vector without extension, for subtests
+func (vector *Vector) Name() string {
+	return strings.TrimSuffix(filepath.Base(vector.path), ".json")
+}
+
+// Save - rewrite the file of the vector
+func (vector *Vector) Save(t testing.TB) {
+	t.Helper()
+	data, err := json.MarshalIndent(vector, "", "  ")
+	if err != nil {
+		t.Fatalf("MarshalIndent failed. Err:%v", err)
+	}
+	if err := os.WriteFile(vector.path, append(data, '\n'), 0644); err != nil {
+		t.Fatalf("WriteFile failed. Err:%v", err)
+	}
+}
+
+// Bytes - the frame on the wire
+func (frame *Frame) Bytes(t testing.TB) []byte {
+	t.Helper()
+	bytes, err := hex.DecodeString(frame.Hex)
+	if err != nil {
+		t.Fatalf("DecodeString failed. Err:%v", err)
+	}
+	return bytes
+}
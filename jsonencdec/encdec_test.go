@@ -1,7 +1,9 @@
 package jsonencdec
 
 import (
+	"bytes"
 	"fmt"
+	"log/slog"
 	"strings"
 	"testing"
 	"zmqclient/zmqencdec"
@@ -351,3 +353,63 @@ func TestJsonDecodeGetInfoResponse(t *testing.T) {
 }
 
 
+
+func TestJsonDecodeResponseSection(t *testing.T) {
+	expJson := `{"Header":{"Length":17,"Command":1},"StartResponse":{"FlowId":1233,"Publisher":"local:59001"}}`
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{
+			Length:  uint16(17),
+			Command: zmqencdec.ZMQ_CMD_START,
+		},
+		StartResponse: zmqencdec.MsgStartResponse{
+			FlowId:    uint32(1233),
+			Publisher: "local:59001",
+		},
+	}
+
+	jsonMsg, err := jsonEncoder.DecodeResponse(msg)
+	if err != nil {
+		t.Fatalf("Decode failed:%s", err)
+	}
+	assert.Equal(t, expJson, jsonMsg, "\nThe two jsons string should be the same.")
+
+	msg.Header.Command = zmqencdec.ZMQ_CMD_SHUTDOWN
+	_, err = jsonEncoder.DecodeResponse(msg)
+	assert.ErrorContains(t, err, "no response for command SHUTDOWN")
+}
+
+func TestJsonDecodeRequestSection(t *testing.T) {
+	expJson := `{"DelTunnelsRequest":{"FlowId":1234,"Teids":[1001,1002]},"Header":{"Length":14,"Command":5}}`
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{
+			Length:  uint16(14),
+			Command: zmqencdec.ZMQ_CMD_DEL_TUNNELS,
+		},
+		DelTunnelsRequest: zmqencdec.MsgDelTunnelsRequest{
+			FlowId: 1234,
+			Teids:  []uint32{1001, 1002},
+		},
+	}
+
+	jsonMsg, err := jsonEncoder.DecodeRequest(msg)
+	if err != nil {
+		t.Fatalf("Decode failed:%s", err)
+	}
+	assert.Equal(t, expJson, jsonMsg, "\nThe two jsons string should be the same.")
+}
+
+func TestJsonEncoderLogger(t *testing.T) {
+	output := new(bytes.Buffer)
+	encoder := JsonEncoder{
+		Logger: slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{Level: slog.LevelInfo})),
+	}
+
+	if _, err := encoder.Encode(`{"Header":{"Command": 2}}`); err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	assert.Equal(t, "", output.String(), "\nPayloads must be logged at debug level only.")
+
+	_, err := encoder.Encode(`{"Header":`)
+	assert.Assert(t, err != nil)
+	assert.Assert(t, strings.Contains(output.String(), "level=ERROR msg=\"json message decode failed\""), output.String())
+}
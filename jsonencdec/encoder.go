@@ -2,19 +2,23 @@ package jsonencdec
 
 import (
 	"encoding/json"
+	"fmt"
+	"reflect"
 	"zmqclient/zmqencdec"
-
-	"github.com/golang/glog"
+	"zmqclient/zmqlog"
 )
 
 type JsonEncoder struct {
+	// Logger - nil for zmqlog.Default(); payloads are logged at debug level only
+	Logger zmqlog.Logger
 }
 
 // Encode - encode Messages
 func (enc *JsonEncoder) Encode(jsonData string) (*zmqencdec.Message, error) {
 	msg := &zmqencdec.Message{}
 
-	glog.Infof("Encode Json message:%v", jsonData)
+	logger := zmqlog.Or(enc.Logger)
+	logger.Debug("encode json message", "json", jsonData)
 
 	//decoding the json data and storing in the message map
 	err := json.Unmarshal([]byte(jsonData), msg)
@@ -22,7 +26,7 @@ func (enc *JsonEncoder) Encode(jsonData string) (*zmqencdec.Message, error) {
 	//Checks whether the error is nil or not
 	if err != nil {
 		//Prints the error if not nil
-		glog.Errorf("Error while decoding the data", err.Error())
+		logger.Error("json message decode failed", "error", err)
 		return nil, err
 	}
 	return msg, nil
@@ -32,7 +36,40 @@ func (enc *JsonEncoder) Decode(msg *zmqencdec.Message) (string, error) {
 
 	j, err := json.Marshal(msg)
 	if err != nil {
-		glog.Errorf("Error: %s", err)
+		zmqlog.Or(enc.Logger).Error("json message encode failed", "error", err)
+		return "", err
+	}
+
+	return string(j), nil
+}
+
+// DecodeRequest - json of the header and the request section of msg only
+func (enc *JsonEncoder) DecodeRequest(msg *zmqencdec.Message) (string, error) {
+	section := zmqencdec.RequestSection(msg.Header.Command)
+	if section == "" {
+		return "", fmt.Errorf("no request for command %s", msg.Header.Command)
+	}
+	return enc.decodeSection(msg, section)
+}
+
+// DecodeResponse - json of the header and the response section of msg only
+func (enc *JsonEncoder) DecodeResponse(msg *zmqencdec.Message) (string, error) {
+	section := zmqencdec.ResponseSection(msg.Header.Command)
+	if section == "" {
+		return "", fmt.Errorf("no response for command %s", msg.Header.Command)
+	}
+	return enc.decodeSection(msg, section)
+}
+
+func (enc *JsonEncoder) decodeSection(msg *zmqencdec.Message, section string) (string, error) {
+	value := reflect.ValueOf(msg).Elem().FieldByName(section)
+
+	j, err := json.Marshal(map[string]interface{}{
+		"Header": msg.Header,
+		section:  value.Interface(),
+	})
+	if err != nil {
+		zmqlog.Or(enc.Logger).Error("json message encode failed", "error", err)
 		return "", err
 	}
 
@@ -0,0 +1,56 @@
+package jsonencdec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"zmqclient/internal/golden"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+func TestJsonGoldenCorpus(t *testing.T) {
+	encoder := &zmqencdec.ZmqEncoder{}
+	for _, vector := range golden.Load(t) {
+		vector := vector
+		t.Run(vector.Name(), func(t *testing.T) {
+			if vector.Request != nil {
+				checkJsonGoldenFrame(t, vector.Request, encoder.Encode, jsonEncoder.DecodeRequest)
+			}
+			if vector.Response != nil {
+				checkJsonGoldenFrame(t, vector.Response, encoder.EncodeResponse, jsonEncoder.DecodeResponse)
+			}
+		})
+	}
+}
+
+// checkJsonGoldenFrame - the JSON form of frame encodes to its wire bytes and
+// the message decodes back to the same JSON form
+func checkJsonGoldenFrame(t *testing.T, frame *golden.Frame,
+	encode func(*zmqencdec.Message) ([]byte, error), decode func(*zmqencdec.Message) (string, error)) {
+	msg, err := jsonEncoder.Encode(string(frame.Json))
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	bytes, err := encode(msg)
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	assert.Equal(t, frame.Hex, hex.EncodeToString(bytes), "\nThe two array should be the same.")
+
+	jsonMsg, err := decode(msg)
+	if err != nil {
+		t.Fatalf("Decode failed:%s", err)
+	}
+	assert.Equal(t, compactJson(t, frame.Json), jsonMsg, "\nThe two jsons string should be the same.")
+}
+
+func compactJson(t *testing.T, data []byte) string {
+	compact := new(bytes.Buffer)
+	if err := json.Compact(compact, data); err != nil {
+		t.Fatalf("Compact failed. Err:%v", err)
+	}
+	return compact.String()
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"zmqclient/zmqmetrics"
+)
+
+// metrics - metrics command: list the runs of a metrics store, query run
+// files, convert them to another format or export them to InfluxDB/StatsD
+func metrics(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("metrics: runs, query, convert or export expected")
+	}
+	switch args[0] {
+	case "runs":
+		return metricsRuns(args[1:])
+	case "query":
+		return metricsQuery(args[1:])
+	case "convert":
+		return metricsConvert(args[1:])
+	case "export":
+		return metricsExport(args[1:])
+	}
+	return fmt.Errorf("metrics: unknown command %q", args[0])
+}
+
+// metricsRuns - run id, format, record count and time span of every run of a store
+func metricsRuns(args []string) error {
+	flags := flag.NewFlagSet("metrics runs", flag.ContinueOnError)
+	dir := flags.String("dir", "metrics", "metrics store directory")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	runs, err := zmqmetrics.Runs(*dir)
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		records, err := zmqmetrics.ReadFile(run.Path)
+		if err != nil {
+			return fmt.Errorf("%s: %v", run.Path, err)
+		}
+		first, last := "-", "-"
+		if len(records) > 0 {
+			first = records[0].Time.Format(time.RFC3339)
+			last = records[len(records)-1].Time.Format(time.RFC3339)
+		}
+		fmt.Printf("%s\t%s\t%d\t%s\t%s\n", run.RunId, run.Format, len(records), first, last)
+	}
+	return nil
+}
+
+// metricsQuery - records of run files matching the filters, to stdout
+func metricsQuery(args []string) error {
+	flags := flag.NewFlagSet("metrics query", flag.ContinueOnError)
+	format := flags.String("format", "csv", "output format: csv, ndjson or col")
+	runs := flags.String("run", "", "comma separated run ids")
+	flowIds := flags.String("flow", "", "comma separated flow ids")
+	protocols := flags.String("protocol", "", "comma separated protocols")
+	from := flags.String("from", "", "first time, RFC 3339")
+	to := flags.String("to", "", "end time (excluded), RFC 3339")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	output, err := zmqmetrics.ParseStorageFormat(*format)
+	if err != nil {
+		return err
+	}
+	query := zmqmetrics.Query{}
+	if *runs != "" {
+		query.RunIds = strings.Split(*runs, ",")
+	}
+	if query.FlowIds, err = parseUint32List(*flowIds); err != nil {
+		return fmt.Errorf("-flow: %v", err)
+	}
+	if query.Protocols, err = parseUint32List(*protocols); err != nil {
+		return fmt.Errorf("-protocol: %v", err)
+	}
+	if query.From, err = parseTime(*from); err != nil {
+		return fmt.Errorf("-from: %v", err)
+	}
+	if query.To, err = parseTime(*to); err != nil {
+		return fmt.Errorf("-to: %v", err)
+	}
+
+	records, err := readRunFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+	return zmqmetrics.WriteRecords(os.Stdout, output, query.Filter(records))
+}
+
+// metricsConvert - records of the input run files to one file, format from
+// its extension
+func metricsConvert(args []string) error {
+	flags := flag.NewFlagSet("metrics convert", flag.ContinueOnError)
+	out := flags.String("out", "", "output file (.csv, .ndjson or .col)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	format, err := zmqmetrics.ParseStorageFormat(filepath.Ext(*out))
+	if err != nil {
+		return err
+	}
+
+	records, err := readRunFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := zmqmetrics.WriteRecords(file, format, records); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// metricsExport - samples of the input run files as Influx line protocol or
+// StatsD gauges
+func metricsExport(args []string) error {
+	flags := flag.NewFlagSet("metrics export", flag.ContinueOnError)
+	influx := flags.String("influx", "", "Influx line protocol to - (stdout), a file, udp://host:port or the http(s) write URL")
+	token := flags.String("token", "", "InfluxDB API token")
+	measurement := flags.String("measurement", "dfxp", "Influx measurement")
+	statsd := flags.String("statsd", "", "StatsD gauges to - (stdout) or the host:port of a StatsD server")
+	prefix := flags.String("prefix", "dfxp", "StatsD metric name prefix")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if (*influx == "") == (*statsd == "") {
+		return fmt.Errorf("metrics export: one of -influx or -statsd expected")
+	}
+
+	records, err := readRunFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+	samples := make([]zmqmetrics.Sample, len(records))
+	for i := range records {
+		samples[i] = records[i].Sample
+	}
+
+	var writer zmqmetrics.SampleWriter
+	switch {
+	case *influx == "-":
+		writer = zmqmetrics.NewInfluxWriter(os.Stdout, &zmqmetrics.InfluxOptions{Measurement: *measurement})
+	case strings.Contains(*influx, "://"):
+		writer, err = zmqmetrics.DialInflux(*influx, &zmqmetrics.InfluxOptions{Measurement: *measurement, Token: *token})
+	case *influx != "":
+		file, err := os.Create(*influx)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer = zmqmetrics.NewInfluxWriter(file, &zmqmetrics.InfluxOptions{Measurement: *measurement})
+	case *statsd == "-":
+		writer = zmqmetrics.NewStatsdWriter(os.Stdout, &zmqmetrics.StatsdOptions{Prefix: *prefix})
+	default:
+		writer, err = zmqmetrics.DialStatsd(*statsd, &zmqmetrics.StatsdOptions{Prefix: *prefix})
+	}
+	if err != nil {
+		return err
+	}
+	if err := writer.Write(samples...); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+func readRunFiles(paths []string) ([]zmqmetrics.Record, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no run file given")
+	}
+	var records []zmqmetrics.Record
+	for _, path := range paths {
+		read, err := zmqmetrics.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		records = append(records, read...)
+	}
+	return records, nil
+}
+
+func parseUint32List(s string) ([]uint32, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var values []uint32
+	for _, field := range strings.Split(s, ",") {
+		value, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, uint32(value))
+	}
+	return values, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"zmqclient/zmqclient"
+	"zmqclient/zmqencdec"
+	"zmqclient/zmqtunnel"
+
+	"github.com/golang/glog"
+)
+
+// addTunnels - add-tunnels command: generate tunnels or read them from a CSV
+// or YAML table and send them to dfxp in ADD_TUNNELS batches
+func addTunnels(args []string) error {
+	flags := flag.NewFlagSet("add-tunnels", flag.ContinueOnError)
+	endpoint := flags.String("endpoint", "tcp://127.0.0.1:5555", "dfxp control endpoint")
+	to := flags.Int("to", 1, "request timeout in seconds")
+	flowId := uint32(1)
+	flags.Var((*uint32Value)(&flowId), "flow", "flow id")
+	batch := flags.Int("batch", 1000, "tunnels per ADD_TUNNELS request")
+	dryRun := flags.Bool("dry-run", false, "print the tunnels as CSV instead of sending them")
+	table := flags.String("table", "", "CSV or YAML tunnel table to send instead of generated tunnels")
+
+	spec := zmqtunnel.GeneratorSpec{TeidStart: 1, TeidStep: 1}
+	var servers string
+	flags.IntVar(&spec.Count, "count", 1, "number of tunnels")
+	flags.Var((*uint32Value)(&spec.TeidStart), "teid-start", "first TEID")
+	flags.Var((*uint32Value)(&spec.TeidStep), "teid-step", "gap between TEIDs")
+	flags.BoolVar(&spec.TeidRandom, "teid-random", false, "unique random TEIDs")
+	flags.Var((*uint32Value)(&spec.TeidOutOffset), "teid-out-offset", "TeidOut = TeidIn + offset")
+	flags.StringVar(&spec.UePrefix, "ue-prefix", "10.0.0.0/16", "UE addresses CIDR")
+	flags.BoolVar(&spec.UeRandom, "ue-random", false, "unique random UE addresses")
+	flags.StringVar(&servers, "servers", "192.168.0.1", "comma separated server addresses")
+	flags.Int64Var(&spec.Seed, "seed", 1, "seed of the random TEIDs and UE addresses")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	spec.ServerIps = strings.Split(servers, ",")
+
+	var rows []zmqtunnel.Row
+	if *table != "" {
+		var err error
+		if rows, err = readTable(*table); err != nil {
+			return err
+		}
+		for i := range rows {
+			if rows[i].FlowId == 0 {
+				rows[i].FlowId = flowId
+			}
+		}
+	} else {
+		tunnels, err := zmqtunnel.Generate(&spec)
+		if err != nil {
+			return err
+		}
+		rows = zmqtunnel.FlowRows(flowId, tunnels)
+	}
+	if *dryRun {
+		return zmqtunnel.WriteCSV(os.Stdout, rows)
+	}
+
+	client := zmqclient.NewZmqClient(&zmqclient.ClientOptions{Endpoint: *endpoint, To: *to})
+	if err := client.Connect(*to); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	flows := zmqtunnel.ByFlow(rows)
+	ids := make([]uint32, 0, len(flows))
+	for id := range flows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		for _, tunnels := range zmqtunnel.Batches(flows[id], *batch) {
+			msg := &zmqencdec.Message{
+				Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_ADD_TUNNELS},
+				AddTunnelRequest: zmqencdec.MsgAddTunnelsRequest{
+					FlowId:  id,
+					Tunnels: tunnels,
+				},
+			}
+			response, err := client.Request(context.Background(), msg)
+			if err != nil {
+				return err
+			}
+			glog.Infof("flow %d: %d tunnels added, %d on dfxp", id, len(tunnels), response.TunnelResponse.Tunnels)
+		}
+	}
+	return nil
+}
+
+// uint32Value - flag of a TEID or flow id, values above math.MaxUint32 are
+// rejected as flag errors
+type uint32Value uint32
+
+func (v *uint32Value) Set(s string) error {
+	value, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return err
+	}
+	*v = uint32Value(value)
+	return nil
+}
+
+func (v *uint32Value) String() string {
+	return strconv.FormatUint(uint64(*v), 10)
+}
+
+// readTable - rows of a tunnel table file, format from its extension
+func readTable(path string) ([]zmqtunnel.Row, error) {
+	format, err := zmqtunnel.ParseTableFormat(filepath.Ext(path))
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if format == zmqtunnel.FORMAT_YAML {
+		return zmqtunnel.ReadYAML(file)
+	}
+	return zmqtunnel.ReadCSV(file)
+}
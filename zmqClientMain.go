@@ -21,6 +21,21 @@ func init() {
 }
 
 func main() {
+	if flag.Arg(0) == "add-tunnels" {
+		if err := addTunnels(flag.Args()[1:]); err != nil {
+			glog.Errorf("add-tunnels failed.Err:%s", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.Arg(0) == "metrics" {
+		if err := metrics(flag.Args()[1:]); err != nil {
+			glog.Errorf("metrics failed.Err:%s", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	glog.Infoln("Start dfxp Client")
 	option := zmqclient.ClientOptions{}
 	client := zmqclient.NewZmqClient(&option)
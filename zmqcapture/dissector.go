@@ -0,0 +1,237 @@
+package zmqcapture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"zmqclient/zmqencdec"
+)
+
+// commandLayout - request/response payload structs of a dfxp command, after the header
+type commandLayout struct {
+	command  zmqencdec.ZmqMessageType
+	request  interface{}
+	response interface{}
+}
+
+var commandLayouts = []commandLayout{
+	{zmqencdec.ZMQ_CMD_START, zmqencdec.MsgStartRequest{}, zmqencdec.MsgStartResponse{}},
+	{zmqencdec.ZMQ_CMD_STOP, zmqencdec.MsgStopRequest{}, zmqencdec.MsgResponse{}},
+	{zmqencdec.ZMQ_CMD_SHUTDOWN, nil, nil},
+	{zmqencdec.ZMQ_CMD_ADD_TUNNELS, zmqencdec.MsgAddTunnelsRequest{}, zmqencdec.MsgTunnelResponse{}},
+	{zmqencdec.ZMQ_CMD_DEL_TUNNELS, zmqencdec.MsgDelTunnelsRequest{}, zmqencdec.MsgTunnelResponse{}},
+	{zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS, zmqencdec.MsgDelAllTunnelsRequest{}, zmqencdec.MsgTunnelResponse{}},
+	{zmqencdec.ZMQ_CMD_GET_INFO, zmqencdec.MsgGetInfoRequest{}, zmqencdec.MsgGetInfoResponse{}},
+	{zmqencdec.ZMQ_CMD_ERROR, nil, zmqencdec.ErrorResponse{}},
+	{zmqencdec.ZMQ_CMD_MSG_ERROR, nil, zmqencdec.MsgErrorResponse{}},
+	{zmqencdec.ZMQ_CMD_METRICS, nil, zmqencdec.MsgMetrics{}},
+}
+
+// luaScalars - ProtoField kind and lua reader helper of the unsigned kinds
+var luaScalars = map[reflect.Kind]struct {
+	kind   string
+	reader string
+}{
+	reflect.Uint16: {"uint16", "u16"},
+	reflect.Uint32: {"uint32", "u32"},
+	reflect.Uint64: {"uint64", "u64"},
+}
+
+// luaGenerator - accumulates ProtoField declarations and per command parsers
+type luaGenerator struct {
+	fields     strings.Builder
+	parsers    strings.Builder
+	fieldNames map[string]bool
+}
+
+// LuaDissector - generate a Wireshark Lua dissector for the dfxp payloads
+// written by WritePcapng. Field layouts come from the zmqencdec message structs.
+func LuaDissector(options PcapngOptions) string {
+	// the header fields are declared below
+	gen := &luaGenerator{fieldNames: map[string]bool{"length": true, "command": true}}
+
+	for _, layout := range commandLayouts {
+		if layout.request != nil {
+			gen.addParser("requests", layout.command, reflect.TypeOf(layout.request))
+		}
+		if layout.response != nil {
+			gen.addParser("responses", layout.command, reflect.TypeOf(layout.response))
+		}
+	}
+
+	var lua strings.Builder
+	lua.WriteString("-- dfxp Wireshark dissector, generated by zmqcapture.LuaDissector. DO NOT EDIT.\n")
+	lua.WriteString("local dfxp = Proto(\"dfxp\", \"Dflux Performance\")\n")
+	lua.WriteString("local f = dfxp.fields\n\n")
+	fmt.Fprintf(&lua, "local CONTROL_PORT = %d\n", options.ControlPort)
+	fmt.Fprintf(&lua, "local METRICS_PORT = %d\n\n", options.MetricsPort)
+
+	lua.WriteString("local commands = {\n")
+	for _, layout := range commandLayouts {
+		fmt.Fprintf(&lua, "  [%d] = %q,\n", layout.command, layout.command.String())
+	}
+	lua.WriteString("}\n\n")
+
+	lua.WriteString("f.length = ProtoField.uint16(\"dfxp.length\", \"Length\", base.DEC)\n")
+	lua.WriteString("f.command = ProtoField.uint16(\"dfxp.command\", \"Command\", base.DEC, commands)\n")
+	lua.WriteString(gen.fields.String())
+	lua.WriteString(luaHelpers)
+	lua.WriteString("local requests = {}\nlocal responses = {}\n\n")
+	lua.WriteString(gen.parsers.String())
+	lua.WriteString(luaDissectorBody)
+	return lua.String()
+}
+
+func (gen *luaGenerator) addParser(table string, command zmqencdec.ZmqMessageType, t reflect.Type) {
+	fmt.Fprintf(&gen.parsers, "%s[%d] = function(buf, tree, offset)\n", table, command)
+	gen.addStruct(t, "  ")
+	gen.parsers.WriteString("  return offset\nend\n\n")
+}
+
+func (gen *luaGenerator) addStruct(t reflect.Type, indent string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := luaFieldName(field.Name)
+
+		switch kind := field.Type.Kind(); kind {
+		case reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			scalar := luaScalars[kind]
+			gen.declareField(name, field.Name, scalar.kind, strings.HasSuffix(field.Name, "IpV4"))
+			fmt.Fprintf(&gen.parsers, "%soffset = %s(buf, tree, f.%s, offset)\n", indent, scalar.reader, name)
+		case reflect.Struct:
+			// nested struct: its fields follow inline
+			gen.addStruct(field.Type, indent)
+		case reflect.String:
+			gen.declareField(name, field.Name, "string", false)
+			fmt.Fprintf(&gen.parsers, "%soffset = rest(buf, tree, f.%s, offset)\n", indent, name)
+		case reflect.Slice:
+			gen.declareField(name, field.Name, "uint32", false)
+			fmt.Fprintf(&gen.parsers, "%slocal %s_count\n", indent, name)
+			fmt.Fprintf(&gen.parsers, "%soffset, %s_count = u32(buf, tree, f.%s, offset)\n", indent, name, name)
+			fmt.Fprintf(&gen.parsers, "%sfor i = 1, (%s_count or 0) do\n", indent, name)
+			gen.addElement(field, indent+"  ")
+			fmt.Fprintf(&gen.parsers, "%send\n", indent)
+		}
+	}
+}
+
+func (gen *luaGenerator) addElement(field reflect.StructField, indent string) {
+	elem := field.Type.Elem()
+	if elem.Kind() == reflect.Struct {
+		size := binary.Size(reflect.New(elem).Elem().Interface())
+		fmt.Fprintf(&gen.parsers, "%sif buf:len() < offset + %d then break end\n", indent, size)
+		fmt.Fprintf(&gen.parsers, "%slocal parent = tree\n", indent)
+		fmt.Fprintf(&gen.parsers, "%slocal tree = parent:add(dfxp, buf(offset, %d), \"%s \" .. i)\n", indent, size, elem.Name())
+		gen.addStruct(elem, indent)
+		return
+	}
+
+	// slice of scalars: one field per item, named after the singular
+	itemName := strings.TrimSuffix(field.Name, "s")
+	name := luaFieldName(itemName)
+	gen.declareField(name, itemName, "uint32", false)
+	fmt.Fprintf(&gen.parsers, "%sif buf:len() < offset + 4 then break end\n", indent)
+	fmt.Fprintf(&gen.parsers, "%soffset = u32(buf, tree, f.%s, offset)\n", indent, name)
+}
+
+func (gen *luaGenerator) declareField(name, label, kind string, ipv4 bool) {
+	if gen.fieldNames[name] {
+		return
+	}
+	gen.fieldNames[name] = true
+
+	switch {
+	case ipv4:
+		fmt.Fprintf(&gen.fields, "f.%s = ProtoField.ipv4(\"dfxp.%s\", %q)\n", name, name, label)
+	case kind == "string":
+		fmt.Fprintf(&gen.fields, "f.%s = ProtoField.string(\"dfxp.%s\", %q)\n", name, name, label)
+	default:
+		fmt.Fprintf(&gen.fields, "f.%s = ProtoField.%s(\"dfxp.%s\", %q, base.DEC)\n", name, kind, name, label)
+	}
+}
+
+// luaFieldName - FlowId -> flow_id
+func luaFieldName(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && !unicode.IsUpper(runes[i-1]) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+const luaHelpers = `
+local function u16(buf, tree, field, offset)
+  if buf:len() < offset + 2 then
+    return offset, nil
+  end
+  local value = buf(offset, 2)
+  tree:add(field, value)
+  return offset + 2, value:uint()
+end
+
+local function u32(buf, tree, field, offset)
+  if buf:len() < offset + 4 then
+    return offset, nil
+  end
+  local value = buf(offset, 4)
+  tree:add(field, value)
+  return offset + 4, value:uint()
+end
+
+local function u64(buf, tree, field, offset)
+  if buf:len() < offset + 8 then
+    return offset, nil
+  end
+  local value = buf(offset, 8)
+  tree:add(field, value)
+  return offset + 8, value:uint64()
+end
+
+local function rest(buf, tree, field, offset)
+  if buf:len() > offset then
+    tree:add(field, buf(offset))
+  end
+  return buf:len()
+end
+
+`
+
+const luaDissectorBody = `function dfxp.dissector(buf, pinfo, tree)
+  if buf:len() < 4 then
+    return 0
+  end
+  pinfo.cols.protocol = "DFXP"
+
+  local subtree = tree:add(dfxp, buf(), "Dflux Performance")
+  subtree:add(f.length, buf(0, 2))
+  subtree:add(f.command, buf(2, 2))
+
+  local command = buf(2, 2):uint()
+  local name = commands[command] or "UNKNOWN"
+  local parsers = responses
+  local kind = "response"
+  if pinfo.dst_port == CONTROL_PORT then
+    parsers = requests
+    kind = "request"
+  elseif pinfo.src_port == METRICS_PORT then
+    kind = "publish"
+  end
+  pinfo.cols.info = name .. " " .. kind
+
+  local parser = parsers[command]
+  if parser then
+    parser(buf, subtree, 4)
+  end
+  return buf:len()
+end
+
+local tcp_port = DissectorTable.get("tcp.port")
+tcp_port:add(CONTROL_PORT, dfxp)
+tcp_port:add(METRICS_PORT, dfxp)
+`
@@ -0,0 +1,191 @@
+package zmqcapture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+const (
+	pcapngSectionHeaderBlock   = 0x0a0d0d0a
+	pcapngInterfaceDescBlock   = 0x00000001
+	pcapngEnhancedPacketBlock  = 0x00000006
+	pcapngByteOrderMagic       = 0x1a2b3c4d
+	pcapngLinkTypeEthernet     = 1
+	pcapngOptionEnd            = 0
+	pcapngOptionIfTsResolution = 9
+
+	ethernetHeaderLen = 14
+	ipv4HeaderLen     = 20
+	tcpHeaderLen      = 20
+	maxPayloadLen     = 0xffff - ipv4HeaderLen - tcpHeaderLen
+)
+
+// PcapngOptions - addresses and ports used for the synthetic TCP framing
+type PcapngOptions struct {
+	ClientIp          net.IP
+	ServerIp          net.IP
+	ControlPort       uint16
+	MetricsPort       uint16
+	ClientControlPort uint16
+	ClientMetricsPort uint16
+}
+
+// DefaultPcapngOptions - dfxp default ports on loopback addresses
+func DefaultPcapngOptions() PcapngOptions {
+	return PcapngOptions{
+		ClientIp:          net.IPv4(127, 0, 0, 1),
+		ServerIp:          net.IPv4(127, 0, 0, 2),
+		ControlPort:       5555,
+		MetricsPort:       5557,
+		ClientControlPort: 40000,
+		ClientMetricsPort: 40001,
+	}
+}
+
+// WritePcapng - write frames as a pcapng capture.
+// Every frame becomes one Ethernet/IPv4/TCP packet carrying the raw dfxp
+// payload without ZMTP framing, so the generated Lua dissector can decode it.
+func WritePcapng(w io.Writer, frames []Frame, options PcapngOptions) error {
+	clientIp := options.ClientIp.To4()
+	serverIp := options.ServerIp.To4()
+	if clientIp == nil || serverIp == nil {
+		return fmt.Errorf("pcapng export needs IPv4 client and server addresses")
+	}
+
+	if err := writeSectionHeader(w); err != nil {
+		return err
+	}
+	if err := writeInterfaceDescription(w); err != nil {
+		return err
+	}
+
+	// one sequence number per socket and direction to keep TCP streams sane
+	seq := make(map[[2]uint8]uint32)
+	for idx, frame := range frames {
+		if len(frame.Data) > maxPayloadLen {
+			return fmt.Errorf("frame %d too long for pcapng export: %d bytes", idx, len(frame.Data))
+		}
+
+		srcIp, dstIp := clientIp, serverIp
+		srcPort, dstPort := options.ClientControlPort, options.ControlPort
+		if frame.Socket == SOCKET_METRICS {
+			srcPort, dstPort = options.ClientMetricsPort, options.MetricsPort
+		}
+		if frame.Direction == DIRECTION_RECEIVED {
+			srcIp, dstIp = dstIp, srcIp
+			srcPort, dstPort = dstPort, srcPort
+		}
+
+		key := [2]uint8{uint8(frame.Socket), uint8(frame.Direction)}
+		ackKey := [2]uint8{uint8(frame.Socket), uint8(frame.Direction ^ 1)}
+		packet := buildPacket(srcIp, dstIp, srcPort, dstPort, seq[key]+1, seq[ackKey]+1, frame.Data)
+		seq[key] += uint32(len(frame.Data))
+
+		if err := writeEnhancedPacket(w, frame, packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeBlock(w io.Writer, blockType uint32, body []byte) error {
+	padded := (len(body) + 3) &^ 3
+	total := uint32(12 + padded)
+
+	block := make([]byte, total)
+	binary.LittleEndian.PutUint32(block[0:], blockType)
+	binary.LittleEndian.PutUint32(block[4:], total)
+	copy(block[8:], body)
+	binary.LittleEndian.PutUint32(block[total-4:], total)
+
+	_, err := w.Write(block)
+	return err
+}
+
+func writeSectionHeader(w io.Writer) error {
+	body := make([]byte, 16)
+	binary.LittleEndian.PutUint32(body[0:], pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(body[4:], 1) // major version
+	binary.LittleEndian.PutUint16(body[6:], 0) // minor version
+	binary.LittleEndian.PutUint64(body[8:], 0xffffffffffffffff)
+	return writeBlock(w, pcapngSectionHeaderBlock, body)
+}
+
+func writeInterfaceDescription(w io.Writer) error {
+	body := make([]byte, 20)
+	binary.LittleEndian.PutUint16(body[0:], pcapngLinkTypeEthernet)
+	binary.LittleEndian.PutUint32(body[4:], 0) // no snap length limit
+	// if_tsresol: timestamps in nanoseconds
+	binary.LittleEndian.PutUint16(body[8:], pcapngOptionIfTsResolution)
+	binary.LittleEndian.PutUint16(body[10:], 1)
+	body[12] = 9
+	binary.LittleEndian.PutUint16(body[16:], pcapngOptionEnd)
+	return writeBlock(w, pcapngInterfaceDescBlock, body)
+}
+
+func writeEnhancedPacket(w io.Writer, frame Frame, packet []byte) error {
+	ts := uint64(frame.Time.UnixNano())
+
+	body := make([]byte, 20+len(packet))
+	binary.LittleEndian.PutUint32(body[0:], 0) // interface id
+	binary.LittleEndian.PutUint32(body[4:], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(body[8:], uint32(ts))
+	binary.LittleEndian.PutUint32(body[12:], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(body[16:], uint32(len(packet)))
+	copy(body[20:], packet)
+	return writeBlock(w, pcapngEnhancedPacketBlock, body)
+}
+
+func buildPacket(srcIp, dstIp net.IP, srcPort, dstPort uint16, seq, ack uint32, payload []byte) []byte {
+	packet := make([]byte, ethernetHeaderLen+ipv4HeaderLen+tcpHeaderLen+len(payload))
+
+	// Ethernet: locally administered MACs derived from the last IP byte
+	eth := packet[:ethernetHeaderLen]
+	copy(eth[0:6], []byte{0x02, 0, 0, 0, 0, dstIp[3]})
+	copy(eth[6:12], []byte{0x02, 0, 0, 0, 0, srcIp[3]})
+	binary.BigEndian.PutUint16(eth[12:], 0x0800)
+
+	ip := packet[ethernetHeaderLen : ethernetHeaderLen+ipv4HeaderLen]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(ipv4HeaderLen+tcpHeaderLen+len(payload)))
+	binary.BigEndian.PutUint16(ip[6:], 0x4000) // don't fragment
+	ip[8] = 64
+	ip[9] = 6 // TCP
+	copy(ip[12:16], srcIp)
+	copy(ip[16:20], dstIp)
+	binary.BigEndian.PutUint16(ip[10:], checksum(ip, 0))
+
+	tcp := packet[ethernetHeaderLen+ipv4HeaderLen:]
+	binary.BigEndian.PutUint16(tcp[0:], srcPort)
+	binary.BigEndian.PutUint16(tcp[2:], dstPort)
+	binary.BigEndian.PutUint32(tcp[4:], seq)
+	binary.BigEndian.PutUint32(tcp[8:], ack)
+	tcp[12] = (tcpHeaderLen / 4) << 4
+	tcp[13] = 0x18 // PSH, ACK
+	binary.BigEndian.PutUint16(tcp[14:], 0xffff)
+	copy(tcp[tcpHeaderLen:], payload)
+
+	// pseudo header for the TCP checksum
+	var pseudo uint32
+	pseudo += uint32(binary.BigEndian.Uint16(srcIp[0:])) + uint32(binary.BigEndian.Uint16(srcIp[2:]))
+	pseudo += uint32(binary.BigEndian.Uint16(dstIp[0:])) + uint32(binary.BigEndian.Uint16(dstIp[2:]))
+	pseudo += 6 + uint32(len(tcp))
+	binary.BigEndian.PutUint16(tcp[16:], checksum(tcp, pseudo))
+
+	return packet
+}
+
+func checksum(data []byte, sum uint32) uint16 {
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i:]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,67 @@
+package zmqcapture
+
+import (
+	"sync"
+	"time"
+)
+
+type Direction uint8
+
+const (
+	DIRECTION_SENT Direction = iota
+	DIRECTION_RECEIVED
+)
+
+type SocketKind uint8
+
+const (
+	SOCKET_CONTROL SocketKind = iota
+	SOCKET_METRICS
+)
+
+// Frame - one dfxp payload seen on a zmq socket
+type Frame struct {
+	Time      time.Time
+	Socket    SocketKind
+	Direction Direction
+	Data      []byte
+}
+
+// Recorder - collects control and metrics frames for later export
+type Recorder struct {
+	mu     sync.Mutex
+	frames []Frame
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Record - store a copy of data as a frame captured now
+func (r *Recorder) Record(socket SocketKind, direction Direction, data []byte) {
+	frame := Frame{
+		Time:      time.Now(),
+		Socket:    socket,
+		Direction: direction,
+		Data:      append([]byte(nil), data...),
+	}
+
+	r.mu.Lock()
+	r.frames = append(r.frames, frame)
+	r.mu.Unlock()
+}
+
+// Frames - return the recorded frames in capture order
+func (r *Recorder) Frames() []Frame {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	frames := make([]Frame, len(r.frames))
+	copy(frames, r.frames)
+	return frames
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.frames = nil
+	r.mu.Unlock()
+}
@@ -0,0 +1,89 @@
+package zmqcapture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestWritePcapng(t *testing.T) {
+	request, _ := hex.DecodeString("000a0001000004d10000000a")
+	response, _ := hex.DecodeString("00110001000004d16c6f63616c3a3539303031")
+
+	recorder := NewRecorder()
+	recorder.Record(SOCKET_CONTROL, DIRECTION_SENT, request)
+	recorder.Record(SOCKET_CONTROL, DIRECTION_RECEIVED, response)
+
+	var out bytes.Buffer
+	options := DefaultPcapngOptions()
+	if err := WritePcapng(&out, recorder.Frames(), options); err != nil {
+		t.Fatalf("WritePcapng failed. Err:%v", err)
+	}
+
+	blocks := splitBlocks(t, out.Bytes())
+	assert.Equal(t, 4, len(blocks), "\nSHB + IDB + 2 EPB expected.")
+	assert.Equal(t, uint32(pcapngSectionHeaderBlock), binary.LittleEndian.Uint32(blocks[0]))
+	assert.Equal(t, uint32(pcapngByteOrderMagic), binary.LittleEndian.Uint32(blocks[0][8:]))
+	assert.Equal(t, uint32(pcapngInterfaceDescBlock), binary.LittleEndian.Uint32(blocks[1]))
+
+	for idx, payload := range [][]byte{request, response} {
+		block := blocks[idx+2]
+		assert.Equal(t, uint32(pcapngEnhancedPacketBlock), binary.LittleEndian.Uint32(block))
+		capLen := binary.LittleEndian.Uint32(block[20:])
+		packet := block[28 : 28+capLen]
+
+		ip := packet[ethernetHeaderLen : ethernetHeaderLen+ipv4HeaderLen]
+		assert.Equal(t, uint16(0), checksum(ip, 0), "\nIPv4 header checksum should verify.")
+
+		tcp := packet[ethernetHeaderLen+ipv4HeaderLen:]
+		srcPort := binary.BigEndian.Uint16(tcp[0:])
+		dstPort := binary.BigEndian.Uint16(tcp[2:])
+		if idx == 0 {
+			assert.Equal(t, options.ControlPort, dstPort, "\nRequest goes to the control port.")
+		} else {
+			assert.Equal(t, options.ControlPort, srcPort, "\nResponse comes from the control port.")
+		}
+		assert.DeepEqual(t, payload, tcp[tcpHeaderLen:])
+	}
+}
+
+func TestWritePcapngFrameTooLong(t *testing.T) {
+	frames := []Frame{{Data: make([]byte, maxPayloadLen+1)}}
+
+	var out bytes.Buffer
+	err := WritePcapng(&out, frames, DefaultPcapngOptions())
+	assert.ErrorContains(t, err, "too long")
+}
+
+func TestLuaDissector(t *testing.T) {
+	lua := LuaDissector(DefaultPcapngOptions())
+
+	for _, expect := range []string{
+		"local CONTROL_PORT = 5555",
+		"[4] = \"ADD_TUNNELS\"",
+		"f.flow_id = ProtoField.uint32(\"dfxp.flow_id\"",
+		"f.ue_ip_v4 = ProtoField.ipv4(\"dfxp.ue_ip_v4\"",
+		"f.publisher = ProtoField.string(\"dfxp.publisher\"",
+		"requests[4] = function(buf, tree, offset)",
+		"responses[9] = function(buf, tree, offset)",
+		"offset = u32(buf, tree, f.teid, offset)",
+	} {
+		assert.Assert(t, strings.Contains(lua, expect), "missing %q in dissector", expect)
+	}
+	assert.Equal(t, 1, strings.Count(lua, "f.flow_id = "), "\nFields must be declared once.")
+}
+
+func splitBlocks(t *testing.T, data []byte) [][]byte {
+	var blocks [][]byte
+	for len(data) > 0 {
+		total := binary.LittleEndian.Uint32(data[4:])
+		assert.Equal(t, total, binary.LittleEndian.Uint32(data[total-4:]), "\nBlock trailer length mismatch.")
+		blocks = append(blocks, data[:total])
+		data = data[total:]
+	}
+	return blocks
+}
@@ -0,0 +1,115 @@
+// Package zmqcbor - zmqencdec.Codec with self-describing CBOR payloads, for
+// dfxp builds that accept them. Frames keep the dfxp header, Length and
+// Command, followed by the section of the command as a CBOR map keyed by
+// field name.
+package zmqcbor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"zmqclient/zmqencdec"
+	"zmqclient/zmqlog"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Codec - CBOR zmqencdec.Codec
+type Codec struct {
+	// Logger - nil for zmqlog.Default(); frames are dumped at debug level only
+	Logger zmqlog.Logger
+}
+
+var _ zmqencdec.Codec = (*Codec)(nil)
+
+// Encode - encode the request of msg
+func (codec *Codec) Encode(msg *zmqencdec.Message) ([]byte, error) {
+	frame, err := codec.AppendEncode(nil, msg)
+	if err != nil {
+		return nil, err
+	}
+	zmqlog.Or(codec.Logger).Debug("encode cbor message",
+		"command", msg.Header.Command,
+		"flow_id", msg.RequestFlowId(),
+		"frame", zmqlog.Hex(frame))
+	return frame, nil
+}
+
+// AppendEncode - append the request frame of msg to dst. The header Length
+// is the size of the CBOR payload, msg.Header.Length is ignored.
+func (codec *Codec) AppendEncode(dst []byte, msg *zmqencdec.Message) ([]byte, error) {
+	if msg == nil {
+		return dst, fmt.Errorf("Message nil")
+	}
+	return appendFrame(dst, msg, zmqencdec.RequestSection(msg.Header.Command))
+}
+
+// RequestLength - 0: AppendEncode writes the size of the CBOR payload, known
+// once it is marshalled
+func (codec *Codec) RequestLength(msg *zmqencdec.Message) (uint16, error) {
+	return 0, nil
+}
+
+// EncodeResponse - encode the response of msg, as dfxp would send it
+func (codec *Codec) EncodeResponse(msg *zmqencdec.Message) ([]byte, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("Message nil")
+	}
+	return appendFrame(nil, msg, zmqencdec.ResponseSection(msg.Header.Command))
+}
+
+// Decode - decode a response or metrics frame
+func (codec *Codec) Decode(frame []byte) (*zmqencdec.Message, error) {
+	msg, err := decodeFrame(frame, zmqencdec.ResponseSection)
+	if err != nil {
+		return nil, err
+	}
+	zmqlog.Or(codec.Logger).Debug("decode cbor message",
+		"command", msg.Header.Command,
+		"length", msg.Header.Length,
+		"frame", zmqlog.Hex(frame))
+	return msg, nil
+}
+
+// DecodeRequest - decode a request frame, as dfxp would
+func (codec *Codec) DecodeRequest(frame []byte) (*zmqencdec.Message, error) {
+	return decodeFrame(frame, zmqencdec.RequestSection)
+}
+
+func appendFrame(dst []byte, msg *zmqencdec.Message, section string) ([]byte, error) {
+	value := msg.Section(section)
+	if value == nil {
+		return dst, fmt.Errorf("Wrong message command [%d]", msg.Header.Command)
+	}
+	payload, err := cbor.Marshal(value)
+	if err != nil {
+		return dst, fmt.Errorf("cbor encode failed. Error: %v", err)
+	}
+	if 2+len(payload) > math.MaxUint16 {
+		return dst, fmt.Errorf("message too long [%d]", 2+len(payload))
+	}
+	dst = binary.BigEndian.AppendUint16(dst, uint16(2+len(payload)))
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	return append(dst, payload...), nil
+}
+
+func decodeFrame(frame []byte, sectionOf func(zmqencdec.ZmqMessageType) string) (*zmqencdec.Message, error) {
+	if frame == nil {
+		return nil, fmt.Errorf("bytesArray nil")
+	}
+	if len(frame) < 4 {
+		return nil, fmt.Errorf("frame too short [%d]", len(frame))
+	}
+	msg := &zmqencdec.Message{Header: zmqencdec.MsgHeader{
+		Length:  binary.BigEndian.Uint16(frame),
+		Command: zmqencdec.ZmqMessageType(binary.BigEndian.Uint16(frame[2:])),
+	}}
+	value := msg.Section(sectionOf(msg.Header.Command))
+	if value == nil {
+		return nil, fmt.Errorf("Wrong message command [%d]", msg.Header.Command)
+	}
+	if err := cbor.Unmarshal(frame[4:], value); err != nil {
+		return nil, fmt.Errorf("cbor decode failed. Error: %v", err)
+	}
+	return msg, nil
+}
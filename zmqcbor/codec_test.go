@@ -0,0 +1,78 @@
+package zmqcbor
+
+import (
+	"testing"
+	"zmqclient/internal/golden"
+	"zmqclient/jsonencdec"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+func TestCodecGoldenCorpus(t *testing.T) {
+	codec := &Codec{}
+	jsonEncoder := &jsonencdec.JsonEncoder{}
+	for _, vector := range golden.Load(t) {
+		vector := vector
+		t.Run(vector.Name(), func(t *testing.T) {
+			if vector.Request != nil {
+				msg, err := jsonEncoder.Encode(string(vector.Request.Json))
+				if err != nil {
+					t.Fatalf("Encode failed. Err:%v", err)
+				}
+				frame, err := codec.Encode(msg)
+				if err != nil {
+					t.Fatalf("Encode failed. Err:%v", err)
+				}
+				decoded, err := codec.DecodeRequest(frame)
+				if err != nil {
+					t.Fatalf("DecodeRequest failed. Err:%v", err)
+				}
+				assert.Equal(t, uint16(len(frame)-2), decoded.Header.Length, "\nThe two length should be the same.")
+				msg.Header.Length = decoded.Header.Length
+				assert.DeepEqual(t, msg, decoded)
+			}
+			if vector.Response != nil {
+				msg, err := jsonEncoder.Encode(string(vector.Response.Json))
+				if err != nil {
+					t.Fatalf("Encode failed. Err:%v", err)
+				}
+				frame, err := codec.EncodeResponse(msg)
+				if err != nil {
+					t.Fatalf("EncodeResponse failed. Err:%v", err)
+				}
+				decoded, err := codec.Decode(frame)
+				if err != nil {
+					t.Fatalf("Decode failed. Err:%v", err)
+				}
+				msg.Header.Length = decoded.Header.Length
+				assert.DeepEqual(t, msg, decoded)
+			}
+		})
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	codec := &Codec{}
+
+	_, err := codec.Encode(&zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_METRICS}})
+	assert.ErrorContains(t, err, "Wrong message command [11]")
+	_, err = codec.Encode(nil)
+	assert.ErrorContains(t, err, "Message nil")
+
+	_, err = codec.Decode([]byte{0, 2})
+	assert.ErrorContains(t, err, "frame too short [2]")
+	_, err = codec.Decode([]byte{0, 2, 0, 99})
+	assert.ErrorContains(t, err, "Wrong message command [99]")
+	_, err = codec.Decode([]byte{0, 3, 0, 2, 0xff})
+	assert.ErrorContains(t, err, "cbor decode failed")
+
+	// a self-describing payload carries its own field names
+	msg := &zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_STOP}}
+	msg.Response.FlowId = 7
+	frame, err := codec.EncodeResponse(msg)
+	if err != nil {
+		t.Fatalf("EncodeResponse failed. Err:%v", err)
+	}
+	assert.Equal(t, "\xa1fFlowId\x07", string(frame[4:]))
+}
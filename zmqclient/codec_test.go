@@ -0,0 +1,114 @@
+package zmqclient
+
+import (
+	"context"
+	"testing"
+	"time"
+	"zmqclient/zmqcbor"
+	"zmqclient/zmqencdec"
+
+	"github.com/go-zeromq/zmq4"
+	"gotest.tools/assert"
+)
+
+// cborHandler - answers START and ADD_TUNNELS requests in CBOR
+func cborHandler(t *testing.T) func(request []byte) []byte {
+	codec := &zmqcbor.Codec{}
+	return func(request []byte) []byte {
+		msg, err := codec.DecodeRequest(request)
+		if err != nil {
+			msg = &zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_ERROR}}
+			msg.ErrorResponse.Error = "invalid message: " + err.Error()
+		}
+		switch msg.Header.Command {
+		case zmqencdec.ZMQ_CMD_START:
+			msg.StartResponse = zmqencdec.MsgStartResponse{FlowId: msg.StartRequest.FlowId, Publisher: "inproc://cbor-metrics"}
+		case zmqencdec.ZMQ_CMD_ADD_TUNNELS:
+			msg.TunnelResponse = zmqencdec.MsgTunnelResponse{
+				FlowId:  msg.AddTunnelRequest.FlowId,
+				Tunnels: uint32(len(msg.AddTunnelRequest.Tunnels)),
+			}
+		}
+		response, err := codec.EncodeResponse(msg)
+		if err != nil {
+			t.Errorf("EncodeResponse failed. Err:%v", err)
+		}
+		return response
+	}
+}
+
+func TestCodecOption(t *testing.T) {
+	server := startFakeDfxpAt(t, "inproc://cbor-codec", cborHandler(t))
+	options := server.options()
+	options.Codec = &zmqcbor.Codec{}
+	client := NewZmqClient(options)
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	start := &zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_START}}
+	start.StartRequest = zmqencdec.MsgStartRequest{FlowId: 7, MetricsInterval: 5}
+	response, err := client.Request(ctx, start)
+	if err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+	assert.Equal(t, zmqencdec.MsgStartResponse{FlowId: 7, Publisher: "inproc://cbor-metrics"}, response.StartResponse)
+	// Header.Length is filled by the codec, for tracing and instrumentation
+	frame, err := options.Codec.Encode(start)
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	assert.Equal(t, uint16(len(frame)-2), start.Header.Length, "\nThe two length should be the same.")
+
+	response, err = client.Request(ctx, tunnelsRequest(zmqencdec.ZMQ_CMD_ADD_TUNNELS, 7, 10, 11))
+	if err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+	assert.Equal(t, zmqencdec.MsgTunnelResponse{FlowId: 7, Tunnels: 2}, response.TunnelResponse)
+	assert.DeepEqual(t, []uint32{10, 11}, client.KnownTunnels(7))
+}
+
+func TestCodecOptionMetrics(t *testing.T) {
+	publisher := zmq4.NewPub(context.Background())
+	if err := publisher.Listen("inproc://cbor-metrics"); err != nil {
+		t.Fatalf("Listen failed. Err:%v", err)
+	}
+	defer publisher.Close()
+
+	codec := &zmqcbor.Codec{}
+	client := NewZmqClient(&ClientOptions{To: 1, Codec: codec})
+	received := make(chan *zmqencdec.Message, 16)
+	client.WithMetricsHandler(func(ctx context.Context, msg *zmqencdec.Message) error {
+		received <- msg
+		return nil
+	})
+	if err := client.ConnectMetrics("inproc://cbor-metrics"); err != nil {
+		t.Fatalf("ConnectMetrics failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	metrics := &zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_METRICS}}
+	metrics.Metrics = zmqencdec.MsgMetrics{FlowId: 7, Metrics: []zmqencdec.ProtocolMetrics{
+		{Command: uint16(zmqencdec.ZMQ_CMD_METRICS), FlowId: 7, Protocol: zmqencdec.METRICS_PROTOCOL_TCP, Metric: zmqencdec.Metric{PktRx: 10, ByteRx: 1500}},
+	}}
+	frame, err := codec.EncodeResponse(metrics)
+	if err != nil {
+		t.Fatalf("EncodeResponse failed. Err:%v", err)
+	}
+
+	// the subscription reaches the publisher asynchronously, publish until seen
+	timeout := time.After(5 * time.Second)
+	for {
+		publisher.Send(zmq4.NewMsg(frame))
+		select {
+		case msg := <-received:
+			assert.DeepEqual(t, metrics.Metrics, msg.Metrics)
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("no metrics received")
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package zmqclient
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ValidateEndpoint - check a zmq endpoint is tcp://host:port, ipc://path or inproc://name
+func ValidateEndpoint(endpoint string) error {
+	scheme, addr, ok := strings.Cut(endpoint, "://")
+	if !ok || addr == "" {
+		return fmt.Errorf("invalid endpoint %q, expected scheme://address", endpoint)
+	}
+
+	switch scheme {
+	case "tcp":
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid tcp endpoint %q: %v", endpoint, err)
+		}
+	case "ipc", "inproc":
+	default:
+		return fmt.Errorf("unsupported endpoint scheme %q in %q", scheme, endpoint)
+	}
+	return nil
+}
+
+// endpoints - Endpoint, Endpoints and Host/Port as one validated list
+func (options *ClientOptions) endpoints() ([]string, error) {
+	var endpoints []string
+	if options.Endpoint != "" {
+		endpoints = append(endpoints, options.Endpoint)
+	}
+	endpoints = append(endpoints, options.Endpoints...)
+	if options.Host != "" {
+		endpoints = append(endpoints, fmt.Sprintf("tcp://%s", net.JoinHostPort(options.Host, fmt.Sprint(options.Port))))
+	}
+
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoint configured")
+	}
+	for _, endpoint := range endpoints {
+		if err := ValidateEndpoint(endpoint); err != nil {
+			return nil, err
+		}
+	}
+	return endpoints, nil
+}
@@ -0,0 +1,73 @@
+package zmqclient
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+func TestValidateEndpoint(t *testing.T) {
+	for _, endpoint := range []string{
+		"tcp://127.0.0.1:5555",
+		"tcp://[::1]:5555",
+		"ipc:///tmp/dfxp.sock",
+		"inproc://dfxp",
+	} {
+		assert.NilError(t, ValidateEndpoint(endpoint))
+	}
+
+	assert.ErrorContains(t, ValidateEndpoint("127.0.0.1:5555"), "expected scheme://address")
+	assert.ErrorContains(t, ValidateEndpoint("tcp://127.0.0.1"), "invalid tcp endpoint")
+	assert.ErrorContains(t, ValidateEndpoint("udp://127.0.0.1:5555"), "unsupported endpoint scheme")
+	assert.ErrorContains(t, ValidateEndpoint("inproc://"), "expected scheme://address")
+}
+
+func TestClientOptionsEndpoints(t *testing.T) {
+	options := ClientOptions{
+		Host:      "10.0.0.4",
+		Port:      5555,
+		Endpoint:  "ipc:///run/dfxp.sock",
+		Endpoints: []string{"inproc://dfxp"},
+	}
+	endpoints, err := options.endpoints()
+	if err != nil {
+		t.Fatalf("endpoints failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, []string{"ipc:///run/dfxp.sock", "inproc://dfxp", "tcp://10.0.0.4:5555"}, endpoints)
+
+	_, err = (&ClientOptions{}).endpoints()
+	assert.ErrorContains(t, err, "no endpoint configured")
+}
+
+func TestTransports(t *testing.T) {
+	tcp := startFakeDfxp(t, dfxpHandler("1.0.0"))
+	_, port, _ := net.SplitHostPort(tcp.endpoint[len("tcp://"):])
+	portNumber, _ := strconv.Atoi(port)
+
+	for name, options := range map[string]*ClientOptions{
+		"inproc":    startFakeDfxpAt(t, "inproc://dfxp-transports", dfxpHandler("1.0.0")).options(),
+		"ipc":       startFakeDfxpAt(t, "ipc://"+filepath.Join(t.TempDir(), "dfxp.sock"), dfxpHandler("1.0.0")).options(),
+		"host/port": {Host: "127.0.0.1", Port: portNumber, To: 1},
+	} {
+		client := NewZmqClient(options)
+		if err := client.Connect(options.To); err != nil {
+			t.Fatalf("%s: Connect failed. Err:%v", name, err)
+		}
+
+		msg := &zmqencdec.Message{
+			Header:      zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_STOP},
+			StopRequest: zmqencdec.MsgStopRequest{FlowId: 1234},
+		}
+		response, err := client.Request(context.Background(), msg)
+		if err != nil {
+			t.Fatalf("%s: Request failed. Err:%v", name, err)
+		}
+		assert.Equal(t, uint32(1234), response.Response.FlowId, "\n%s", name)
+		client.Close()
+	}
+}
@@ -0,0 +1,219 @@
+package zmqclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"zmqclient/zmqencdec"
+
+	zmq "github.com/go-zeromq/zmq4"
+)
+
+// FailoverOptions - how the client moves between the configured endpoints.
+// The client talks to one endpoint at a time; a request that fails or times
+// out reopens the control socket on the next endpoint.
+type FailoverOptions struct {
+	// ProbeInterval - period of the GET_INFO health probe, 0 disables probing
+	ProbeInterval time.Duration
+	// PreferPrimary - go back to the first endpoint as soon as it answers probes
+	PreferPrimary bool
+	// DialRetries - dial attempts per endpoint after the first one
+	DialRetries int
+	// OnSwitchover - called after the active endpoint changed
+	OnSwitchover func(from, to string)
+}
+
+type switchover struct {
+	from string
+	to   string
+}
+
+func (options *FailoverOptions) dialRetries() int {
+	if options == nil {
+		return 0
+	}
+	return options.DialRetries
+}
+
+// Endpoint - endpoint the control socket is connected to
+func (client *ZmqClient) Endpoint() string {
+	client.reqMu.Lock()
+	defer client.reqMu.Unlock()
+	if client.socket == nil {
+		return ""
+	}
+	return client.endpoints[client.active]
+}
+
+// recover - reopen the control socket after a failed exchange, starting with
+// the endpoint after the active one; caller holds reqMu.
+// The client is DEGRADED until a request succeeds, DISCONNECTED when no
+// endpoint could be reopened.
+func (client *ZmqClient) recover(cause error) *switchover {
+	if len(client.endpoints) == 0 {
+		return nil
+	}
+	from := client.endpoints[client.active]
+	client.log().Error("dfxp request failed", "endpoint", from, "error", cause)
+
+	if client.socket != nil {
+		client.socket.Close()
+		client.socket = nil
+	}
+
+	for i := 1; i <= len(client.endpoints); i++ {
+		idx := (client.active + i) % len(client.endpoints)
+		err := client.connectTo(idx, client.options.To)
+		client.observeReconnect(client.endpoints[idx], err)
+		if err != nil {
+			client.log().Error("connect failed", "endpoint", client.endpoints[idx], "error", err)
+			continue
+		}
+		to := client.endpoints[idx]
+		client.setState(STATE_DEGRADED, to, cause)
+		if to != from {
+			client.tunnels.reset()
+			return &switchover{from: from, to: to}
+		}
+		return nil
+	}
+	client.log().Error("no dfxp endpoint reachable")
+	client.setState(STATE_DISCONNECTED, from, cause)
+	return nil
+}
+
+func (client *ZmqClient) notifySwitchover(switched *switchover) {
+	if switched == nil {
+		return
+	}
+	client.log().Info("dfxp switchover", "from", switched.from, "to", switched.to)
+	if failover := client.options.Failover; failover != nil && failover.OnSwitchover != nil {
+		failover.OnSwitchover(switched.from, switched.to)
+	}
+}
+
+func (client *ZmqClient) startProbe() {
+	interval := client.options.probeInterval()
+	if interval <= 0 || client.probeExit != nil {
+		return
+	}
+	client.probeExit = make(chan bool)
+	go client.probe(interval, client.probeExit)
+}
+
+func (client *ZmqClient) stopProbe() {
+	if client.probeExit != nil {
+		close(client.probeExit)
+		client.probeExit = nil
+	}
+}
+
+// probe - heartbeat of the active endpoint, and health check of the primary
+// one when PreferPrimary is set and a standby is active
+func (client *ZmqClient) probe(interval time.Duration, exit chan bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failover := client.options.Failover
+	preferPrimary := failover != nil && failover.PreferPrimary
+	maxMissed := client.options.Heartbeat.maxMissed()
+	missed := 0
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-ticker.C:
+		}
+
+		client.reqMu.Lock()
+		var switched *switchover
+		switch {
+		case client.socket == nil:
+			switched = client.recover(fmt.Errorf("not connected"))
+		case preferPrimary && client.active != 0 && client.probeEndpoint(client.endpoints[0]) == nil:
+			switched = client.switchTo(0)
+		default:
+			if _, err := client.roundTrip(context.Background(), infoRequest()); err != nil {
+				missed++
+				switched = client.recover(err)
+				if client.socket != nil {
+					// reopened: DEGRADED until a heartbeat or request gets through
+					missed = 0
+				} else if missed >= maxMissed {
+					client.setState(STATE_DISCONNECTED, client.activeEndpoint(),
+						fmt.Errorf("%d heartbeats missed. Error: %w", missed, err))
+				}
+			} else {
+				missed = 0
+				client.setState(STATE_READY, client.activeEndpoint(), nil)
+			}
+		}
+		client.reqMu.Unlock()
+
+		client.notifySwitchover(switched)
+	}
+}
+
+// switchTo - move the control socket to endpoints[idx]; caller holds reqMu
+func (client *ZmqClient) switchTo(idx int) *switchover {
+	from := client.endpoints[client.active]
+	previous := client.socket
+	previousIdx := client.active
+
+	err := client.connectTo(idx, client.options.To)
+	client.observeReconnect(client.endpoints[idx], err)
+	if err != nil {
+		client.log().Error("connect failed", "endpoint", client.endpoints[idx], "error", err)
+		client.socket = previous
+		client.active = previousIdx
+		return nil
+	}
+	if previous != nil {
+		previous.Close()
+	}
+	client.tunnels.reset()
+	return &switchover{from: from, to: client.endpoints[idx]}
+}
+
+// probeEndpoint - GET_INFO round trip on a throw away socket
+func (client *ZmqClient) probeEndpoint(endpoint string) error {
+	opts, err := client.socketOptions(client.options.To)
+	if err != nil {
+		return err
+	}
+	socket := zmq.NewReq(context.Background(), opts...)
+	defer socket.Close()
+
+	if err := socket.Dial(endpoint); err != nil {
+		return err
+	}
+
+	request, err := client.appendRequest(nil, infoRequest())
+	if err != nil {
+		return err
+	}
+	if err := socket.Send(zmq.NewMsg(request)); err != nil {
+		return err
+	}
+
+	result := make(chan recvResult, 1)
+	go func() {
+		msg, err := socket.Recv()
+		result <- recvResult{msg, err}
+	}()
+	select {
+	case <-time.After(time.Duration(client.options.To) * time.Second):
+		return ErrTimeout
+	case r := <-result:
+		return r.err
+	}
+}
+
+func infoRequest() *zmqencdec.Message {
+	return &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{
+			Command: zmqencdec.ZMQ_CMD_GET_INFO,
+		},
+	}
+}
@@ -0,0 +1,159 @@
+package zmqclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+func getInfo(t *testing.T, client *ZmqClient) (string, error) {
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_GET_INFO},
+	}
+	response, err := client.Request(context.Background(), msg)
+	if err != nil {
+		return "", err
+	}
+	return response.GetInfoResponse.Version, nil
+}
+
+func TestFailoverOnTimeout(t *testing.T) {
+	stuck := make(chan bool)
+	t.Cleanup(func() { close(stuck) })
+	primary := startFakeDfxpAt(t, "inproc://failover-timeout-primary", func(request []byte) []byte {
+		<-stuck
+		return nil
+	})
+	standby := startFakeDfxpAt(t, "inproc://failover-timeout-standby", dfxpHandler("standby"))
+
+	switched := make(chan [2]string, 1)
+	options := &ClientOptions{
+		Endpoints: []string{primary.endpoint, standby.endpoint},
+		To:        1,
+		Failover: &FailoverOptions{
+			OnSwitchover: func(from, to string) {
+				switched <- [2]string{from, to}
+			},
+		},
+	}
+	client := NewZmqClient(options)
+	if err := client.Connect(options.To); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+	assert.Equal(t, primary.endpoint, client.Endpoint())
+
+	_, err := getInfo(t, client)
+	assert.Assert(t, errors.Is(err, ErrTimeout), "unexpected error %v", err)
+	assert.Equal(t, [2]string{primary.endpoint, standby.endpoint}, <-switched)
+	assert.Equal(t, standby.endpoint, client.Endpoint())
+
+	version, err := getInfo(t, client)
+	if err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+	assert.Equal(t, "standby", version)
+}
+
+func TestReceiveTimeoutReopens(t *testing.T) {
+	healthy := dfxpHandler("dfxp v1.0")
+	server := startFakeDfxpAt(t, "inproc://receive-timeout", func(request []byte) []byte {
+		if _, flowId := requestCommand(request); flowId == 1 {
+			time.Sleep(1500 * time.Millisecond)
+		}
+		return healthy(request)
+	})
+	client := NewZmqClient(server.options())
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	encoder := &zmqencdec.ZmqEncoder{}
+	infoFrame := func(flowId uint32) []byte {
+		msg := &zmqencdec.Message{
+			Header:         zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_GET_INFO, Length: 6},
+			GetInfoRequest: zmqencdec.MsgGetInfoRequest{FlowId: flowId},
+		}
+		frame, err := encoder.Encode(msg)
+		if err != nil {
+			t.Fatalf("Encode failed. Err:%v", err)
+		}
+		return frame
+	}
+
+	if err := client.Send(infoFrame(1)); err != nil {
+		t.Fatalf("Send failed. Err:%v", err)
+	}
+	_, err := client.ReceiveWithTimeout(1)
+	assert.Assert(t, errors.Is(err, ErrTimeout), "unexpected error %v", err)
+	// the socket still receiving the late response is replaced
+	assert.Equal(t, STATE_DEGRADED, client.State())
+
+	// the late response of flow 1 must not be taken for the one of flow 2
+	response, err := client.SendAndReceiveWithTimeout(infoFrame(2), 2)
+	if err != nil {
+		t.Fatalf("SendAndReceiveWithTimeout failed. Err:%v", err)
+	}
+	_, flowId := requestCommand(response)
+	assert.Equal(t, uint32(2), flowId, "\nThe two flow ids should be the same.")
+}
+
+func TestFailoverDeadPrimaryOnConnect(t *testing.T) {
+	standby := startFakeDfxpAt(t, "inproc://failover-connect-standby", dfxpHandler("standby"))
+
+	options := &ClientOptions{
+		Endpoints: []string{"inproc://failover-connect-primary", standby.endpoint},
+		To:        1,
+	}
+	client := NewZmqClient(options)
+	if err := client.Connect(options.To); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+	assert.Equal(t, standby.endpoint, client.Endpoint())
+
+	dead := NewZmqClient(&ClientOptions{Endpoints: []string{"inproc://nobody-1", "inproc://nobody-2"}, To: 1})
+	assert.ErrorContains(t, dead.Connect(1), "could not dial")
+}
+
+func TestFailoverPreferPrimary(t *testing.T) {
+	standby := startFakeDfxpAt(t, "inproc://prefer-primary-standby", dfxpHandler("standby"))
+
+	switched := make(chan [2]string, 4)
+	options := &ClientOptions{
+		Endpoints: []string{"inproc://prefer-primary-primary", standby.endpoint},
+		To:        1,
+		Failover: &FailoverOptions{
+			ProbeInterval: 20 * time.Millisecond,
+			PreferPrimary: true,
+			OnSwitchover: func(from, to string) {
+				switched <- [2]string{from, to}
+			},
+		},
+	}
+	client := NewZmqClient(options)
+	if err := client.Connect(options.To); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+	assert.Equal(t, standby.endpoint, client.Endpoint())
+
+	primary := startFakeDfxpAt(t, "inproc://prefer-primary-primary", dfxpHandler("primary"))
+	select {
+	case s := <-switched:
+		assert.Equal(t, [2]string{standby.endpoint, primary.endpoint}, s)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no switch back to the primary")
+	}
+
+	version, err := getInfo(t, client)
+	if err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+	assert.Equal(t, "primary", version)
+}
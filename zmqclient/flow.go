@@ -0,0 +1,357 @@
+package zmqclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"zmqclient/zmqencdec"
+)
+
+// FlowOptions - parameters of a flow opened with NewFlow
+type FlowOptions struct {
+	// FlowId - 0 allocates an id not used by another open flow of the client
+	FlowId          uint32
+	MetricsInterval uint32
+	// Metrics - receives the metrics publishes of the flow once started,
+	// after the metrics interceptors; nil for none
+	Metrics MetricsHandler
+}
+
+// Flow - a dfxp flow of the client: its id, started state and tunnels.
+// Close removes the tunnels and stops the flow on dfxp.
+type Flow struct {
+	client  *ZmqClient
+	id      uint32
+	options FlowOptions
+
+	mu        sync.Mutex
+	started   bool
+	closed    bool
+	publisher string
+	tunnels   map[uint32]zmqencdec.Tunnel // by TeidIn
+}
+
+// flowTable - open flows of a client by id
+type flowTable struct {
+	mu    sync.Mutex
+	next  uint32
+	flows map[uint32]*Flow
+
+	subscribeMu sync.Mutex // one metrics subscription at a time
+}
+
+// NewFlow - open a flow on the client; nothing is sent until Start or AddTunnels
+func (client *ZmqClient) NewFlow(options *FlowOptions) (*Flow, error) {
+	if options == nil {
+		options = &FlowOptions{}
+	}
+
+	client.flows.mu.Lock()
+	defer client.flows.mu.Unlock()
+	if client.flows.flows == nil {
+		client.flows.flows = make(map[uint32]*Flow)
+	}
+
+	id := options.FlowId
+	if id == 0 {
+		if uint64(len(client.flows.flows)) == math.MaxUint32 {
+			return nil, fmt.Errorf("new flow failed. Error: no free flow id")
+		}
+		for id = client.flows.next + 1; id == 0 || client.flows.flows[id] != nil; id++ {
+		}
+		client.flows.next = id
+	} else if client.flows.flows[id] != nil {
+		return nil, fmt.Errorf("new flow failed. Error: flow %d already open", id)
+	}
+
+	flow := &Flow{
+		client:  client,
+		id:      id,
+		options: *options,
+		tunnels: make(map[uint32]zmqencdec.Tunnel),
+	}
+	flow.options.FlowId = id
+	client.flows.flows[id] = flow
+	return flow, nil
+}
+
+// Flows - ids of the open flows
+func (client *ZmqClient) Flows() []uint32 {
+	client.flows.mu.Lock()
+	defer client.flows.mu.Unlock()
+	ids := make([]uint32, 0, len(client.flows.flows))
+	for id := range client.flows.flows {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (client *ZmqClient) flow(id uint32) *Flow {
+	client.flows.mu.Lock()
+	defer client.flows.mu.Unlock()
+	return client.flows.flows[id]
+}
+
+// flowMetrics - an open flow has a metrics handler
+func (client *ZmqClient) flowMetrics() bool {
+	client.flows.mu.Lock()
+	defer client.flows.mu.Unlock()
+	for _, flow := range client.flows.flows {
+		if flow.options.Metrics != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Id - dfxp flow id
+func (flow *Flow) Id() uint32 {
+	return flow.id
+}
+
+// Started - START was answered and STOP not sent yet
+func (flow *Flow) Started() bool {
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+	return flow.started
+}
+
+// Publisher - metrics endpoint returned by START, empty before
+func (flow *Flow) Publisher() string {
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+	return flow.publisher
+}
+
+// Tunnels - tunnels added and not deleted yet, ordered by TeidIn
+func (flow *Flow) Tunnels() []zmqencdec.Tunnel {
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+	tunnels := make([]zmqencdec.Tunnel, 0, len(flow.tunnels))
+	for _, tunnel := range flow.tunnels {
+		tunnels = append(tunnels, tunnel)
+	}
+	sort.Slice(tunnels, func(i, j int) bool { return tunnels[i].TeidIn < tunnels[j].TeidIn })
+	return tunnels
+}
+
+// Start - START the flow and, when it has a metrics handler, subscribe to
+// its publisher
+func (flow *Flow) Start(ctx context.Context) error {
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+	if err := flow.usable(); err != nil {
+		return err
+	}
+	if flow.started {
+		return fmt.Errorf("flow %d start failed. Error: already started", flow.id)
+	}
+
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_START},
+		StartRequest: zmqencdec.MsgStartRequest{
+			FlowId:          flow.id,
+			MetricsInterval: flow.options.MetricsInterval,
+		},
+	}
+	response, err := flow.request(ctx, msg)
+	if err != nil {
+		return err
+	}
+	flow.started = true
+	flow.publisher = response.StartResponse.Publisher
+
+	if flow.options.Metrics != nil {
+		if err := flow.client.subscribeMetrics(flow.publisher); err != nil {
+			return fmt.Errorf("flow %d metrics failed. Error: %w", flow.id, err)
+		}
+	}
+	return nil
+}
+
+// Stop - STOP the flow; its tunnels are kept
+func (flow *Flow) Stop(ctx context.Context) error {
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+	if err := flow.usable(); err != nil {
+		return err
+	}
+	return flow.stop(ctx)
+}
+
+// AddTunnels - ADD_TUNNELS and remember the tunnels
+func (flow *Flow) AddTunnels(ctx context.Context, tunnels ...zmqencdec.Tunnel) error {
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+	if err := flow.usable(); err != nil {
+		return err
+	}
+
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_ADD_TUNNELS},
+		AddTunnelRequest: zmqencdec.MsgAddTunnelsRequest{
+			FlowId:  flow.id,
+			Tunnels: tunnels,
+		},
+	}
+	if _, err := flow.request(ctx, msg); err != nil {
+		return err
+	}
+	for _, tunnel := range tunnels {
+		flow.tunnels[tunnel.TeidIn] = tunnel
+	}
+	return nil
+}
+
+// DelTunnels - DEL_TUNNELS of the given TEIDs
+func (flow *Flow) DelTunnels(ctx context.Context, teids ...uint32) error {
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+	if err := flow.usable(); err != nil {
+		return err
+	}
+
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_DEL_TUNNELS},
+		DelTunnelsRequest: zmqencdec.MsgDelTunnelsRequest{
+			FlowId: flow.id,
+			Teids:  teids,
+		},
+	}
+	if _, err := flow.request(ctx, msg); err != nil {
+		return err
+	}
+	for _, teid := range teids {
+		delete(flow.tunnels, teid)
+	}
+	return nil
+}
+
+// DelAllTunnels - DEL_ALL_TUNNELS of the flow
+func (flow *Flow) DelAllTunnels(ctx context.Context) error {
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+	if err := flow.usable(); err != nil {
+		return err
+	}
+	return flow.delAll(ctx)
+}
+
+// Close - send DEL_ALL_TUNNELS and STOP, whatever the state of the flow and
+// even when ctx is done or the first request fails, then release the flow id.
+// The errors of both requests are joined; a never started flow ignores the
+// unknown flow and not started answers. Close of a closed flow does nothing.
+func (flow *Flow) Close(ctx context.Context) error {
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+	if flow.closed {
+		return nil
+	}
+	flow.closed = true
+	defer flow.client.releaseFlow(flow)
+
+	// the requests must go out even when the caller gave up
+	ctx = context.WithoutCancel(ctx)
+	started := flow.started
+	delErr := flow.delAll(ctx)
+	stopErr := flow.stop(ctx)
+	if !started {
+		delErr = ignoreUnknownFlow(delErr)
+		stopErr = ignoreUnknownFlow(stopErr)
+	}
+	return errors.Join(delErr, stopErr)
+}
+
+func (flow *Flow) usable() error {
+	if flow.closed {
+		return fmt.Errorf("flow %d failed. Error: closed", flow.id)
+	}
+	return nil
+}
+
+// stop - caller holds mu
+func (flow *Flow) stop(ctx context.Context) error {
+	msg := &zmqencdec.Message{
+		Header:      zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_STOP},
+		StopRequest: zmqencdec.MsgStopRequest{FlowId: flow.id},
+	}
+	_, err := flow.request(ctx, msg)
+	var dfxpErr *zmqencdec.DfxpError
+	if err == nil || errors.As(err, &dfxpErr) {
+		// dfxp answered: the flow is not running anymore either way
+		flow.started = false
+	}
+	return err
+}
+
+// delAll - caller holds mu
+func (flow *Flow) delAll(ctx context.Context) error {
+	msg := &zmqencdec.Message{
+		Header:               zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS},
+		DelAllTunnelsRequest: zmqencdec.MsgDelAllTunnelsRequest{FlowId: flow.id},
+	}
+	if _, err := flow.request(ctx, msg); err != nil {
+		return err
+	}
+	flow.tunnels = make(map[uint32]zmqencdec.Tunnel)
+	return nil
+}
+
+// request - send msg, a response of another command is an error
+func (flow *Flow) request(ctx context.Context, msg *zmqencdec.Message) (*zmqencdec.Message, error) {
+	command := msg.Header.Command
+	response, err := flow.client.Request(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("flow %d: %w", flow.id, err)
+	}
+	if response.Header.Command != command {
+		return nil, fmt.Errorf("flow %d: %s failed. Error: unexpected %s response", flow.id, command, response.Header.Command)
+	}
+	return response, nil
+}
+
+func ignoreUnknownFlow(err error) error {
+	if errors.Is(err, zmqencdec.ErrUnknownFlow) || errors.Is(err, zmqencdec.ErrFlowNotStarted) {
+		return nil
+	}
+	return err
+}
+
+// releaseFlow - forget a closed flow, its id can be allocated again
+func (client *ZmqClient) releaseFlow(flow *Flow) {
+	client.flows.mu.Lock()
+	defer client.flows.mu.Unlock()
+	if client.flows.flows[flow.id] == flow {
+		delete(client.flows.flows, flow.id)
+	}
+}
+
+// subscribeMetrics - connect the metrics socket to publisher unless already
+// connected; every flow of a dfxp publishes on the same socket
+func (client *ZmqClient) subscribeMetrics(publisher string) error {
+	client.flows.subscribeMu.Lock()
+	defer client.flows.subscribeMu.Unlock()
+	if client.metrics != nil {
+		client.startListener()
+		return nil
+	}
+	return client.ConnectMetrics(publisher)
+}
+
+// dispatchMetrics - last handler of the metrics chain: the flow handler, then
+// the client one
+func (client *ZmqClient) dispatchMetrics(ctx context.Context, msg *zmqencdec.Message) error {
+	if flow := client.flow(msg.ResponseFlowId()); flow != nil && flow.options.Metrics != nil {
+		if err := flow.options.Metrics(ctx, msg); err != nil {
+			return err
+		}
+	}
+	if client.metricsHandler != nil {
+		return client.metricsHandler(ctx, msg)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package zmqclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"zmqclient/zmqencdec"
+
+	"github.com/go-zeromq/zmq4"
+	"gotest.tools/assert"
+)
+
+// commandLog - commands and flow ids received by a fake dfxp
+type commandLog struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+func (log *commandLog) handler(next func(request []byte) []byte) func(request []byte) []byte {
+	return func(request []byte) []byte {
+		cmd, flowId := requestCommand(request)
+		log.mu.Lock()
+		log.commands = append(log.commands, fmt.Sprintf("%s %d", cmd, flowId))
+		log.mu.Unlock()
+		return next(request)
+	}
+}
+
+func (log *commandLog) take() []string {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	commands := log.commands
+	log.commands = nil
+	return commands
+}
+
+func TestFlowLifecycle(t *testing.T) {
+	received := &commandLog{}
+	server := startFakeDfxpAt(t, "inproc://flow-lifecycle", received.handler(dfxpHandler("dfxp v1.0")))
+	client := NewZmqClient(server.options())
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	flow, err := client.NewFlow(nil)
+	if err != nil {
+		t.Fatalf("NewFlow failed. Err:%v", err)
+	}
+	pinned, err := client.NewFlow(&FlowOptions{FlowId: 3})
+	if err != nil {
+		t.Fatalf("NewFlow failed. Err:%v", err)
+	}
+	next, err := client.NewFlow(nil)
+	if err != nil {
+		t.Fatalf("NewFlow failed. Err:%v", err)
+	}
+	_, err = client.NewFlow(&FlowOptions{FlowId: 3})
+	assert.ErrorContains(t, err, "flow 3 already open")
+	assert.Equal(t, uint32(1), flow.Id())
+	assert.Equal(t, uint32(3), pinned.Id())
+	assert.Equal(t, uint32(2), next.Id())
+	assert.DeepEqual(t, []uint32{1, 2, 3}, client.Flows())
+	received.take()
+
+	ctx := context.Background()
+	if err := flow.Start(ctx); err != nil {
+		t.Fatalf("Start failed. Err:%v", err)
+	}
+	assert.Assert(t, flow.Started())
+	assert.Equal(t, "tcp://127.0.0.1:5557", flow.Publisher())
+	assert.ErrorContains(t, flow.Start(ctx), "already started")
+
+	tunnels := []zmqencdec.Tunnel{
+		{TeidIn: 20, TeidOut: 21, UeIpV4: 0x0a000002, SrvIpV4: 0x0a000001},
+		{TeidIn: 10, TeidOut: 11, UeIpV4: 0x0a000003, SrvIpV4: 0x0a000001},
+	}
+	if err := flow.AddTunnels(ctx, tunnels...); err != nil {
+		t.Fatalf("AddTunnels failed. Err:%v", err)
+	}
+	if err := flow.DelTunnels(ctx, 20); err != nil {
+		t.Fatalf("DelTunnels failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, tunnels[1:], flow.Tunnels())
+
+	if err := flow.Close(ctx); err != nil {
+		t.Fatalf("Close failed. Err:%v", err)
+	}
+	assert.Assert(t, !flow.Started())
+	assert.Equal(t, 0, len(flow.Tunnels()))
+	assert.NilError(t, flow.Close(ctx), "\nA second Close must do nothing.")
+	assert.ErrorContains(t, flow.AddTunnels(ctx, tunnels...), "closed")
+	assert.DeepEqual(t, []uint32{2, 3}, client.Flows())
+
+	assert.DeepEqual(t, []string{
+		"START 1",
+		"ADD_TUNNELS 1",
+		"DEL_TUNNELS 1",
+		"DEL_ALL_TUNNELS 1",
+		"STOP 1",
+	}, received.take())
+
+	// the id of a closed flow is free again
+	reopened, err := client.NewFlow(&FlowOptions{FlowId: 1})
+	if err != nil {
+		t.Fatalf("NewFlow failed. Err:%v", err)
+	}
+	assert.Equal(t, uint32(1), reopened.Id())
+}
+
+func TestFlowCloseOnErrors(t *testing.T) {
+	received := &commandLog{}
+	healthy := dfxpHandler("dfxp v1.0")
+	server := startFakeDfxpAt(t, "inproc://flow-close-errors", received.handler(func(request []byte) []byte {
+		cmd, flowId := requestCommand(request)
+		switch cmd {
+		case zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS:
+			return responseFrame(zmqencdec.ZMQ_CMD_MSG_ERROR, flowId, "out of memory")
+		case zmqencdec.ZMQ_CMD_STOP:
+			if flowId == 2 {
+				return responseFrame(zmqencdec.ZMQ_CMD_MSG_ERROR, flowId, "flow not started")
+			}
+		}
+		return healthy(request)
+	}))
+	client := NewZmqClient(server.options())
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	flow, err := client.NewFlow(nil)
+	if err != nil {
+		t.Fatalf("NewFlow failed. Err:%v", err)
+	}
+	if err := flow.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed. Err:%v", err)
+	}
+	received.take()
+
+	// a cancelled context must not keep the cleanup from being sent
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = flow.Close(ctx)
+	assert.Assert(t, errors.Is(err, zmqencdec.ErrNoResources), "\nThe DEL_ALL_TUNNELS error must be returned. Err:%v", err)
+	assert.DeepEqual(t, []string{"DEL_ALL_TUNNELS 1", "STOP 1"}, received.take())
+	assert.Assert(t, !flow.Started())
+
+	// never started: the not started answer of STOP is expected
+	idle, err := client.NewFlow(nil)
+	if err != nil {
+		t.Fatalf("NewFlow failed. Err:%v", err)
+	}
+	err = idle.Close(context.Background())
+	assert.Assert(t, errors.Is(err, zmqencdec.ErrNoResources))
+	assert.Assert(t, !errors.Is(err, zmqencdec.ErrFlowNotStarted), "\nThe not started answer must be ignored. Err:%v", err)
+	assert.DeepEqual(t, []string{"DEL_ALL_TUNNELS 2", "STOP 2"}, received.take())
+	assert.Equal(t, 0, len(client.Flows()))
+}
+
+func TestFlowMetrics(t *testing.T) {
+	publisher := zmq4.NewPub(context.Background())
+	if err := publisher.Listen("inproc://flow-metrics-publisher"); err != nil {
+		t.Fatalf("Listen failed. Err:%v", err)
+	}
+	defer publisher.Close()
+
+	healthy := dfxpHandler("dfxp v1.0")
+	server := startFakeDfxpAt(t, "inproc://flow-metrics", func(request []byte) []byte {
+		cmd, flowId := requestCommand(request)
+		if cmd == zmqencdec.ZMQ_CMD_START {
+			return responseFrame(cmd, flowId, "inproc://flow-metrics-publisher")
+		}
+		return healthy(request)
+	})
+	client := NewZmqClient(server.options())
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	received := make(chan uint32, 16)
+	flows := make([]*Flow, 2)
+	for i := range flows {
+		flowId := uint32(i + 1)
+		flow, err := client.NewFlow(&FlowOptions{
+			FlowId: flowId,
+			Metrics: func(ctx context.Context, msg *zmqencdec.Message) error {
+				if msg.Metrics.FlowId != flowId {
+					t.Errorf("flow %d received the metrics of flow %d", flowId, msg.Metrics.FlowId)
+				}
+				received <- msg.Metrics.FlowId
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("NewFlow failed. Err:%v", err)
+		}
+		if err := flow.Start(context.Background()); err != nil {
+			t.Fatalf("Start failed. Err:%v", err)
+		}
+		flows[i] = flow
+	}
+	if err := flows[1].Close(context.Background()); err != nil {
+		t.Fatalf("Close failed. Err:%v", err)
+	}
+
+	// the subscription reaches the publisher asynchronously, publish until seen
+	timeout := time.After(5 * time.Second)
+	for {
+		publisher.Send(zmq4.NewMsg(responseFrame(zmqencdec.ZMQ_CMD_METRICS, uint32(2), uint32(0))))
+		publisher.Send(zmq4.NewMsg(responseFrame(zmqencdec.ZMQ_CMD_METRICS, uint32(1), uint32(0))))
+		select {
+		case flowId := <-received:
+			assert.Equal(t, uint32(1), flowId, "\nOnly the open flow may receive metrics.")
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("no metrics received")
+		}
+	}
+}
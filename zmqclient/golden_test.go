@@ -0,0 +1,56 @@
+package zmqclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"zmqclient/internal/golden"
+	"zmqclient/jsonencdec"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+func TestGoldenRequests(t *testing.T) {
+	vectors := golden.Load(t)
+	healthy := dfxpHandler("dfxp v1.0")
+	server := startFakeDfxpAt(t, "inproc://golden-requests", func(request []byte) []byte {
+		for _, vector := range vectors {
+			if vector.Request != nil && vector.Response != nil && bytes.Equal(request, vector.Request.Bytes(t)) {
+				return vector.Response.Bytes(t)
+			}
+		}
+		return healthy(request)
+	})
+	options := server.options()
+	// the corpus TEIDs are not added before they are deleted
+	options.SkipTunnelValidation = true
+	client := NewZmqClient(options)
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	jsonEncoder := &jsonencdec.JsonEncoder{}
+	for _, vector := range vectors {
+		if vector.Request == nil || vector.Response == nil {
+			continue
+		}
+		vector := vector
+		t.Run(vector.Name(), func(t *testing.T) {
+			msg, err := jsonEncoder.Encode(string(vector.Request.Json))
+			if err != nil {
+				t.Fatalf("Encode failed. Err:%v", err)
+			}
+			response, err := client.Request(context.Background(), msg)
+			if err != nil {
+				t.Fatalf("Request failed. Err:%v", err)
+			}
+			section := zmqencdec.ResponseSection(response.Header.Command)
+			value := reflect.ValueOf(response).Elem().FieldByName(section).Interface()
+			assert.Equal(t, vector.Response.Section, fmt.Sprintf("%+v", value), "\nThe two sections should be the same.")
+		})
+	}
+}
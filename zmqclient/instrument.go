@@ -0,0 +1,114 @@
+package zmqclient
+
+import (
+	"context"
+	"errors"
+	"time"
+	"zmqclient/zmqencdec"
+)
+
+// Instrumentation - sink of the client own measurements, e.g. zmqprom
+type Instrumentation interface {
+	// ObserveRequest - every control request, heartbeats and negotiation included
+	ObserveRequest(observation RequestObservation)
+	// ObserveReconnect - every attempt to reopen the control socket
+	ObserveReconnect(endpoint string, err error)
+	// ObserveListenerError - receive or decode failure on the metrics socket
+	ObserveListenerError(err error)
+}
+
+// request outcomes
+const (
+	OUTCOME_OK             = "ok"
+	OUTCOME_TIMEOUT        = "timeout"
+	OUTCOME_TRANSPORT      = "transport"
+	OUTCOME_ERROR_RESPONSE = "error_response"
+	OUTCOME_ERROR          = "error"
+)
+
+// RequestObservation - one request/response exchange
+type RequestObservation struct {
+	Command zmqencdec.ZmqMessageType
+	// Response - command of the response, ERROR/MSG_ERROR for server failures
+	Response      zmqencdec.ZmqMessageType
+	Latency       time.Duration
+	BytesSent     int
+	BytesReceived int
+	// Retry - the request is resent by RetryInterceptor
+	Retry bool
+	Err   error
+}
+
+// Outcome - OUTCOME_* class of the exchange
+func (observation *RequestObservation) Outcome() string {
+	var transportErr *transportError
+	switch {
+	case errors.Is(observation.Err, ErrTimeout) || errors.Is(observation.Err, context.DeadlineExceeded):
+		return OUTCOME_TIMEOUT
+	case errors.As(observation.Err, &transportErr):
+		return OUTCOME_TRANSPORT
+	case observation.Err != nil:
+		return OUTCOME_ERROR
+	case observation.Response == zmqencdec.ZMQ_CMD_ERROR || observation.Response == zmqencdec.ZMQ_CMD_MSG_ERROR:
+		return OUTCOME_ERROR_RESPONSE
+	}
+	return OUTCOME_OK
+}
+
+// WithInstrumentation - report requests, reconnects and listener errors to sink
+func (c *ZmqClient) WithInstrumentation(sink Instrumentation) {
+	c.instrumentation = sink
+}
+
+func (c *ZmqClient) observeRequest(observation RequestObservation) {
+	if c.instrumentation != nil {
+		c.instrumentation.ObserveRequest(observation)
+	}
+}
+
+func (c *ZmqClient) observeReconnect(endpoint string, err error) {
+	if c.instrumentation != nil {
+		c.instrumentation.ObserveReconnect(endpoint, err)
+	}
+}
+
+func (c *ZmqClient) observeListenerError(err error) {
+	if c.instrumentation != nil {
+		c.instrumentation.ObserveListenerError(err)
+	}
+}
+
+// FrameSizes - sizes of the encoded request and response frames of a Request,
+// whatever the codec
+type FrameSizes struct {
+	Sent     int
+	Received int
+}
+
+type frameSizesKey struct{}
+
+// WithFrameSizes - ctx in which Request records its frame sizes into the
+// returned FrameSizes, the last try when it is retried. For interceptors
+// reporting the bytes of a request, e.g. zmqtrace.
+func WithFrameSizes(ctx context.Context) (context.Context, *FrameSizes) {
+	sizes := new(FrameSizes)
+	return context.WithValue(ctx, frameSizesKey{}, sizes), sizes
+}
+
+// FrameSizesFrom - FrameSizes set in ctx by WithFrameSizes, nil if none
+func FrameSizesFrom(ctx context.Context) *FrameSizes {
+	sizes, _ := ctx.Value(frameSizesKey{}).(*FrameSizes)
+	return sizes
+}
+
+type attemptKey struct{}
+
+// withAttempt - number the tries of a request in its context
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+func isRetry(ctx context.Context) bool {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt > 1
+}
@@ -0,0 +1,91 @@
+package zmqclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+// recordingSink - Instrumentation keeping every observation
+type recordingSink struct {
+	mu             sync.Mutex
+	requests       []RequestObservation
+	reconnects     []string
+	listenerErrors int
+}
+
+func (sink *recordingSink) ObserveRequest(observation RequestObservation) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.requests = append(sink.requests, observation)
+}
+
+func (sink *recordingSink) ObserveReconnect(endpoint string, err error) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.reconnects = append(sink.reconnects, endpoint)
+}
+
+func (sink *recordingSink) ObserveListenerError(err error) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.listenerErrors++
+}
+
+func TestInstrumentationRequests(t *testing.T) {
+	healthy := dfxpHandler("dfxp v1.0")
+	var stall sync.Once
+	server := startFakeDfxpAt(t, "inproc://instrument-requests", func(request []byte) []byte {
+		cmd, flowId := requestCommand(request)
+		switch cmd {
+		case zmqencdec.ZMQ_CMD_STOP:
+			return responseFrame(zmqencdec.ZMQ_CMD_MSG_ERROR, flowId, "flow not started")
+		case zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS:
+			stall.Do(func() { time.Sleep(300 * time.Millisecond) })
+		}
+		return healthy(request)
+	})
+
+	sink := &recordingSink{}
+	client := NewZmqClient(server.options())
+	client.WithInstrumentation(sink)
+	client.WithInterceptors(RetryInterceptor(2, 400*time.Millisecond), TimeoutInterceptor(100*time.Millisecond))
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	if _, err := getInfo(t, client); err != nil {
+		t.Fatalf("getInfo failed. Err:%v", err)
+	}
+	_, err := client.Request(context.Background(), &zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_STOP}})
+	assert.Assert(t, errors.Is(err, zmqencdec.ErrFlowNotStarted), "\nThe MSG_ERROR must be returned as error. Err:%v", err)
+	if _, err := client.Request(context.Background(), &zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS}}); err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	var outcomes []string
+	for _, observation := range sink.requests {
+		outcomes = append(outcomes, fmt.Sprintf("%s %s retry=%v", observation.Command, observation.Outcome(), observation.Retry))
+	}
+	assert.DeepEqual(t, []string{
+		"GET_INFO ok retry=false",
+		"STOP error_response retry=false",
+		"DEL_ALL_TUNNELS timeout retry=false",
+		"DEL_ALL_TUNNELS ok retry=true",
+	}, outcomes)
+
+	info := sink.requests[0]
+	assert.Equal(t, 8, info.BytesSent)
+	assert.Equal(t, 4+4+len("dfxp v1.0"), info.BytesReceived)
+	assert.Equal(t, zmqencdec.ZMQ_CMD_GET_INFO, info.Response)
+	assert.DeepEqual(t, []string{server.endpoint}, sink.reconnects)
+}
@@ -0,0 +1,186 @@
+package zmqclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"zmqclient/zmqencdec"
+	"zmqclient/zmqlog"
+)
+
+// RequestHandler - sends msg on the control socket and returns the response
+type RequestHandler func(ctx context.Context, msg *zmqencdec.Message) (*zmqencdec.Message, error)
+
+// Interceptor - wraps every Request; it calls next to go on with the chain
+// or answers by itself
+type Interceptor func(ctx context.Context, msg *zmqencdec.Message, next RequestHandler) (*zmqencdec.Message, error)
+
+// MetricsHandler - receives a decoded metrics publish
+type MetricsHandler func(ctx context.Context, msg *zmqencdec.Message) error
+
+// MetricsInterceptor - wraps every received metrics publish; it calls next
+// to go on with the chain or drops the message
+type MetricsInterceptor func(ctx context.Context, msg *zmqencdec.Message, next MetricsHandler) error
+
+// WithInterceptors - add interceptors around Request, the first one is the outermost
+func (c *ZmqClient) WithInterceptors(interceptors ...Interceptor) {
+	c.interceptors = append(c.interceptors, interceptors...)
+}
+
+// WithMetricsInterceptors - add interceptors in front of the metrics handler,
+// the first one is the outermost
+func (c *ZmqClient) WithMetricsInterceptors(interceptors ...MetricsInterceptor) {
+	c.metricsInterceptors = append(c.metricsInterceptors, interceptors...)
+}
+
+// WithMetricsHandler - handler receives the decoded metrics published once
+// ConnectMetrics is done, through the metrics interceptors
+func (c *ZmqClient) WithMetricsHandler(handler MetricsHandler) {
+	c.metricsHandler = handler
+	c.startListener()
+}
+
+func chainRequest(interceptors []Interceptor, handler RequestHandler) RequestHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, msg *zmqencdec.Message) (*zmqencdec.Message, error) {
+			return interceptor(ctx, msg, next)
+		}
+	}
+	return handler
+}
+
+func chainMetrics(interceptors []MetricsInterceptor, handler MetricsHandler) MetricsHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, msg *zmqencdec.Message) error {
+			return interceptor(ctx, msg, next)
+		}
+	}
+	return handler
+}
+
+// handleMetrics - decode a metrics frame and run it through the chain, with
+// the zmqencdec.MetricsDecoder of the codec when it has one. Frames of a dfxp
+// whose layout is unknown are dropped.
+func (c *ZmqClient) handleMetrics(data []byte) {
+	if capabilities := c.Capabilities(); capabilities != nil && !capabilities.Metrics {
+		c.observeListenerError(fmt.Errorf("%w for dfxp %s", ErrUnknownMetricsLayout, capabilities.Version))
+		return
+	}
+	msg, err := c.decodeMetrics(data)
+	if err != nil {
+		c.log().Error("metrics decode failed", "error", err)
+		c.observeListenerError(err)
+		return
+	}
+	handler := chainMetrics(c.metricsInterceptors, c.dispatchMetrics)
+	if err := handler(zmqlog.NewContext(context.Background(), c.log()), msg); err != nil {
+		c.log().Error("metrics handler failed", "command", msg.Header.Command, "flow_id", msg.ResponseFlowId(), "error", err)
+	}
+}
+
+func (c *ZmqClient) decodeMetrics(data []byte) (*zmqencdec.Message, error) {
+	if decoder, ok := c.codec.(zmqencdec.MetricsDecoder); ok {
+		return decoder.DecodeMetrics(data)
+	}
+	return c.codec.Decode(data)
+}
+
+// ///////////////////////////////////////////////////////////
+// Built-in interceptors
+// ///////////////////////////////////////////////////////////
+
+// LoggingInterceptor - log command, flow id, latency and error of every request
+func LoggingInterceptor() Interceptor {
+	return func(ctx context.Context, msg *zmqencdec.Message, next RequestHandler) (*zmqencdec.Message, error) {
+		start := time.Now()
+		response, err := next(ctx, msg)
+		logger := zmqlog.FromContext(ctx)
+		if err != nil {
+			logger.Error("request failed", "command", msg.Header.Command, "flow_id", msg.RequestFlowId(),
+				"latency", time.Since(start), "error", err)
+		} else {
+			logger.Info("request answered", "command", msg.Header.Command, "flow_id", msg.RequestFlowId(),
+				"response", response.Header.Command, "latency", time.Since(start))
+		}
+		return response, err
+	}
+}
+
+// IdempotentCommands - commands RetryInterceptor resends: dfxp ends in the same
+// state whether the lost attempt was applied or not
+var IdempotentCommands = []zmqencdec.ZmqMessageType{
+	zmqencdec.ZMQ_CMD_GET_INFO,
+	zmqencdec.ZMQ_CMD_STOP,
+	zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS,
+}
+
+// RetryInterceptor - resend an IdempotentCommands request that failed on the
+// transport, up to attempts times in total, waiting backoff between tries
+func RetryInterceptor(attempts int, backoff time.Duration) Interceptor {
+	return RetryCommandsInterceptor(attempts, backoff, IdempotentCommands...)
+}
+
+// RetryCommandsInterceptor - RetryInterceptor for the given commands.
+// Warning: a timed out START, ADD_TUNNELS or DEL_TUNNELS may have been applied
+// by dfxp; resending it can start a flow or provision its tunnels twice, and
+// the known tunnels table does not catch it since the lost attempt was never
+// recorded.
+func RetryCommandsInterceptor(attempts int, backoff time.Duration, commands ...zmqencdec.ZmqMessageType) Interceptor {
+	return func(ctx context.Context, msg *zmqencdec.Message, next RequestHandler) (*zmqencdec.Message, error) {
+		if !slices.Contains(commands, msg.Header.Command) {
+			return next(ctx, msg)
+		}
+		var response *zmqencdec.Message
+		var err error
+		for attempt := 1; ; attempt++ {
+			response, err = next(withAttempt(ctx, attempt), msg)
+			var transportErr *transportError
+			if err == nil || attempt >= attempts || !errors.As(err, &transportErr) {
+				return response, err
+			}
+			zmqlog.FromContext(ctx).Warn("request failed, retrying", "command", msg.Header.Command, "attempt", attempt, "error", err)
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+	}
+}
+
+// TimeoutInterceptor - fail requests whose context is still running after timeout.
+// The control socket is reopened like for any receive timeout.
+func TimeoutInterceptor(timeout time.Duration) Interceptor {
+	return func(ctx context.Context, msg *zmqencdec.Message, next RequestHandler) (*zmqencdec.Message, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return next(ctx, msg)
+	}
+}
+
+// MetricsLoggingInterceptor - log flow id and protocol count of every metrics publish
+func MetricsLoggingInterceptor() MetricsInterceptor {
+	return func(ctx context.Context, msg *zmqencdec.Message, next MetricsHandler) error {
+		zmqlog.FromContext(ctx).Info("metrics received", "flow_id", msg.Metrics.FlowId, "protocols", len(msg.Metrics.Metrics))
+		return next(ctx, msg)
+	}
+}
+
+// MetricsFlowFilter - pass only the metrics of the given flows
+func MetricsFlowFilter(flows ...uint32) MetricsInterceptor {
+	accepted := make(map[uint32]bool, len(flows))
+	for _, flowId := range flows {
+		accepted[flowId] = true
+	}
+	return func(ctx context.Context, msg *zmqencdec.Message, next MetricsHandler) error {
+		if !accepted[msg.ResponseFlowId()] {
+			return nil
+		}
+		return next(ctx, msg)
+	}
+}
@@ -0,0 +1,181 @@
+package zmqclient
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+	"zmqclient/zmqencdec"
+
+	"github.com/go-zeromq/zmq4"
+	"gotest.tools/assert"
+)
+
+func TestInterceptorOrder(t *testing.T) {
+	server := startFakeDfxpAt(t, "inproc://interceptor-order", dfxpHandler("dfxp v1.0"))
+	client := NewZmqClient(server.options())
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	var calls []string
+	trace := func(name string) Interceptor {
+		return func(ctx context.Context, msg *zmqencdec.Message, next RequestHandler) (*zmqencdec.Message, error) {
+			calls = append(calls, name+" "+msg.Header.Command.String())
+			response, err := next(ctx, msg)
+			calls = append(calls, name+" "+response.Header.Command.String())
+			return response, err
+		}
+	}
+	client.WithInterceptors(trace("a"), trace("b"), LoggingInterceptor())
+
+	version, err := getInfo(t, client)
+	if err != nil {
+		t.Fatalf("getInfo failed. Err:%v", err)
+	}
+	assert.Equal(t, "dfxp v1.0", version)
+	assert.DeepEqual(t, []string{"a GET_INFO", "b GET_INFO", "b GET_INFO", "a GET_INFO"}, calls)
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	client := NewZmqClient(&ClientOptions{Endpoint: "inproc://interceptor-nobody", To: 1})
+	client.WithInterceptors(func(ctx context.Context, msg *zmqencdec.Message, next RequestHandler) (*zmqencdec.Message, error) {
+		if msg.Header.Command == zmqencdec.ZMQ_CMD_GET_INFO {
+			return &zmqencdec.Message{
+				Header:          msg.Header,
+				GetInfoResponse: zmqencdec.MsgGetInfoResponse{Version: "cached"},
+			}, nil
+		}
+		return next(ctx, msg)
+	})
+
+	version, err := getInfo(t, client)
+	if err != nil {
+		t.Fatalf("getInfo failed. Err:%v", err)
+	}
+	assert.Equal(t, "cached", version)
+
+	stop := &zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_STOP}}
+	_, err = client.Request(context.Background(), stop)
+	assert.ErrorContains(t, err, "not connected")
+}
+
+func TestTimeoutInterceptor(t *testing.T) {
+	healthy := dfxpHandler("dfxp v1.0")
+	server := startFakeDfxpAt(t, "inproc://interceptor-timeout", func(request []byte) []byte {
+		time.Sleep(300 * time.Millisecond)
+		return healthy(request)
+	})
+	client := NewZmqClient(server.options())
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	client.WithInterceptors(TimeoutInterceptor(100 * time.Millisecond))
+	_, err := getInfo(t, client)
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), "unexpected error %v", err)
+}
+
+func TestRetryInterceptor(t *testing.T) {
+	var calls atomic.Int32
+	healthy := dfxpHandler("dfxp v1.0")
+	server := startFakeDfxpAt(t, "inproc://interceptor-retry", func(request []byte) []byte {
+		if calls.Add(1) == 1 {
+			time.Sleep(300 * time.Millisecond)
+		}
+		return healthy(request)
+	})
+	client := NewZmqClient(server.options())
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	// the fake answers one request at a time: back off until it is free
+	client.WithInterceptors(RetryInterceptor(2, 400*time.Millisecond), TimeoutInterceptor(100*time.Millisecond))
+	version, err := getInfo(t, client)
+	if err != nil {
+		t.Fatalf("getInfo failed. Err:%v", err)
+	}
+	assert.Equal(t, "dfxp v1.0", version)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	var calls atomic.Int32
+	healthy := dfxpHandler("dfxp v1.0")
+	server := startFakeDfxpAt(t, "inproc://interceptor-retry-tunnels", func(request []byte) []byte {
+		if calls.Add(1) == 1 {
+			time.Sleep(300 * time.Millisecond)
+		}
+		return healthy(request)
+	})
+	options := server.options()
+	options.SkipTunnelValidation = true
+	client := NewZmqClient(options)
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	// a timed out ADD_TUNNELS may be applied: not resent by default
+	client.WithInterceptors(RetryInterceptor(2, 400*time.Millisecond), TimeoutInterceptor(100*time.Millisecond))
+	_, err := client.Request(context.Background(), tunnelsRequest(zmqencdec.ZMQ_CMD_ADD_TUNNELS, 1, 10))
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), "unexpected error %v", err)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// the fake answers one request at a time: let it finish the stalled one
+	time.Sleep(300 * time.Millisecond)
+	calls.Store(0)
+	optIn := NewZmqClient(options)
+	if err := optIn.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer optIn.Close()
+	optIn.WithInterceptors(RetryCommandsInterceptor(2, 400*time.Millisecond, zmqencdec.ZMQ_CMD_ADD_TUNNELS), TimeoutInterceptor(100*time.Millisecond))
+	response, err := optIn.Request(context.Background(), tunnelsRequest(zmqencdec.ZMQ_CMD_ADD_TUNNELS, 1, 10))
+	if err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+	assert.Equal(t, uint32(1), response.TunnelResponse.Tunnels)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestMetricsInterceptors(t *testing.T) {
+	publisher := zmq4.NewPub(context.Background())
+	if err := publisher.Listen("inproc://interceptor-metrics"); err != nil {
+		t.Fatalf("Listen failed. Err:%v", err)
+	}
+	defer publisher.Close()
+
+	client := NewZmqClient(&ClientOptions{To: 1})
+	received := make(chan *zmqencdec.Message, 16)
+	client.WithMetricsInterceptors(MetricsLoggingInterceptor(), MetricsFlowFilter(1))
+	client.WithMetricsHandler(func(ctx context.Context, msg *zmqencdec.Message) error {
+		received <- msg
+		return nil
+	})
+	if err := client.ConnectMetrics("inproc://interceptor-metrics"); err != nil {
+		t.Fatalf("ConnectMetrics failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	// the subscription reaches the publisher asynchronously, publish until seen
+	timeout := time.After(5 * time.Second)
+	for {
+		publisher.Send(zmq4.NewMsg(responseFrame(zmqencdec.ZMQ_CMD_METRICS, uint32(2), uint32(0))))
+		publisher.Send(zmq4.NewMsg(responseFrame(zmqencdec.ZMQ_CMD_METRICS, uint32(1), uint32(0))))
+		select {
+		case msg := <-received:
+			assert.Equal(t, zmqencdec.ZMQ_CMD_METRICS, msg.Header.Command)
+			assert.Equal(t, uint32(1), msg.Metrics.FlowId, "\nOnly flow 1 may pass the filter.")
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("no metrics received")
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package zmqclient
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+// syncBuffer - log output written from the client goroutines
+type syncBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buffer.String()
+}
+
+func TestClientLogger(t *testing.T) {
+	server := startFakeDfxpAt(t, "inproc://logger", dfxpHandler("dfxp v1.0"))
+
+	for _, test := range []struct {
+		level  slog.Level
+		frames bool
+	}{
+		{slog.LevelInfo, false},
+		{slog.LevelDebug, true},
+	} {
+		output := &syncBuffer{}
+		options := server.options()
+		options.Logger = slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{Level: test.level}))
+		client := NewZmqClient(options)
+		client.WithInterceptors(LoggingInterceptor())
+		if err := client.Connect(1); err != nil {
+			t.Fatalf("Connect failed. Err:%v", err)
+		}
+		if _, err := getInfo(t, client); err != nil {
+			t.Fatalf("getInfo failed. Err:%v", err)
+		}
+		client.Close()
+
+		logs := output.String()
+		assert.Assert(t, strings.Contains(logs, "msg=\"connecting perf zmq\" endpoint=inproc://logger"), logs)
+		assert.Assert(t, strings.Contains(logs, "msg=\"request answered\" command=GET_INFO flow_id=0 response=GET_INFO"), logs)
+		assert.Equal(t, test.frames, strings.Contains(logs, "frame=00060007"), "\nFrames must be dumped at debug level only.\n%s", logs)
+	}
+}
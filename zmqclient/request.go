@@ -0,0 +1,143 @@
+package zmqclient
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"zmqclient/zmqencdec"
+	"zmqclient/zmqlog"
+)
+
+// Request - encode msg, send it on the control socket and decode the response.
+// Header.Length is computed when left zero. The interceptors run around it.
+// An ERROR or MSG_ERROR response is returned together with a *zmqencdec.DfxpError.
+func (client *ZmqClient) Request(ctx context.Context, msg *zmqencdec.Message) (*zmqencdec.Message, error) {
+	ctx = zmqlog.ContextOr(ctx, client.log())
+	return chainRequest(client.interceptors, client.request)(ctx, msg)
+}
+
+func (client *ZmqClient) request(ctx context.Context, msg *zmqencdec.Message) (*zmqencdec.Message, error) {
+	if err := client.validate(msg); err != nil {
+		return nil, err
+	}
+	if !client.isConnected() {
+		return nil, fmt.Errorf("request %s failed. Error: not connected", msg.Header.Command)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	client.reqMu.Lock()
+	response, err := client.roundTrip(ctx, msg)
+	var switched *switchover
+	var transportErr *transportError
+	if errors.As(err, &transportErr) {
+		switched = client.recover(err)
+	} else if err == nil {
+		client.setState(STATE_READY, client.activeEndpoint(), nil)
+		err = zmqencdec.ResponseError(msg.Header.Command, response)
+		if err == nil {
+			client.tunnels.update(msg)
+		}
+	}
+	client.reqMu.Unlock()
+
+	client.notifySwitchover(switched)
+	return response, err
+}
+
+// roundTrip - encode msg, exchange it and decode the response; caller holds reqMu
+func (client *ZmqClient) roundTrip(ctx context.Context, msg *zmqencdec.Message) (*zmqencdec.Message, error) {
+	start := time.Now()
+	observation := RequestObservation{
+		Command: msg.Header.Command,
+		Retry:   isRetry(ctx),
+	}
+
+	response, err := client.sendRequest(ctx, msg, &observation)
+
+	observation.Latency = time.Since(start)
+	observation.Err = err
+	if response != nil {
+		observation.Response = response.Header.Command
+	}
+	client.observeRequest(observation)
+	return response, err
+}
+
+// requestBuffers - encoded request frames, reused across requests. A frame
+// goes back to the pool once its response is read only: the inproc transport
+// keeps the slice after Send returned, until the peer has read it, and a
+// timed out request may still be unread.
+var requestBuffers = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, 0, 512)
+		return &buffer
+	},
+}
+
+func (client *ZmqClient) sendRequest(ctx context.Context, msg *zmqencdec.Message, observation *RequestObservation) (*zmqencdec.Message, error) {
+	buffer := requestBuffers.Get().(*[]byte)
+	request, err := client.appendRequest((*buffer)[:0], msg)
+	if err != nil {
+		requestBuffers.Put(buffer)
+		return nil, err
+	}
+	client.log().Debug("encode dfxp message",
+		"command", msg.Header.Command,
+		"flow_id", msg.RequestFlowId(),
+		"length", msg.Header.Length,
+		"frame", zmqlog.Hex(request))
+
+	response, err := client.exchange(ctx, request, client.options.To)
+	if err != nil {
+		// the peer may not have read the frame yet, leave it to the GC
+		return nil, &transportError{err}
+	}
+	// answered: the peer is done with the frame
+	*buffer = request[:0]
+	requestBuffers.Put(buffer)
+	observation.BytesSent = len(request)
+	observation.BytesReceived = len(response)
+	if sizes := FrameSizesFrom(ctx); sizes != nil {
+		sizes.Sent, sizes.Received = len(request), len(response)
+	}
+	return client.codec.Decode(response)
+}
+
+// appendRequest - append the request frame of msg to dst. Header.Length, when
+// left zero, is set by the codec, or read back from the frame when the codec
+// writes the length itself.
+func (client *ZmqClient) appendRequest(dst []byte, msg *zmqencdec.Message) ([]byte, error) {
+	if msg.Header.Length == 0 {
+		l, err := client.codec.RequestLength(msg)
+		if err != nil {
+			return dst, err
+		}
+		msg.Header.Length = l
+	}
+	frame, err := client.codec.AppendEncode(dst, msg)
+	if err != nil {
+		return frame, err
+	}
+	if msg.Header.Length == 0 && len(frame) >= len(dst)+2 {
+		msg.Header.Length = binary.BigEndian.Uint16(frame[len(dst):])
+	}
+	return frame, nil
+}
+
+// transportError - send/receive failure, the control socket must be reopened
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
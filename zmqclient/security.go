@@ -0,0 +1,44 @@
+package zmqclient
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-zeromq/zmq4"
+	"github.com/go-zeromq/zmq4/security/plain"
+)
+
+// SecurityOptions - ZeroMQ security used on both the control and metrics sockets
+type SecurityOptions struct {
+	// Mechanism - zmq4.NullSecurity (default) or zmq4.PlainSecurity
+	Mechanism zmq4.SecurityType
+
+	// PLAIN credentials
+	User     string
+	Password string
+}
+
+// ErrCurveUnsupported - zmq4 writes the frame headers around the Security
+// output, it cannot carry the CurveZMQ MESSAGE commands libzmq expects
+var ErrCurveUnsupported = errors.New("CURVE security is not supported by the zmq4 transport")
+
+// security - zmq4 security mechanism for the options, nil for NULL security
+func (options *SecurityOptions) security() (zmq4.Security, error) {
+	if options == nil {
+		return nil, nil
+	}
+
+	switch options.Mechanism {
+	case "", zmq4.NullSecurity:
+		return nil, nil
+	case zmq4.PlainSecurity:
+		if options.User == "" {
+			return nil, fmt.Errorf("PLAIN security needs a user name")
+		}
+		return plain.Security(options.User, options.Password), nil
+	case zmq4.CurveSecurity:
+		return nil, ErrCurveUnsupported
+	default:
+		return nil, fmt.Errorf("unknown security mechanism %q", options.Mechanism)
+	}
+}
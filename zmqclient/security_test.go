@@ -0,0 +1,113 @@
+package zmqclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"zmqclient/zmqencdec"
+
+	"github.com/go-zeromq/zmq4"
+	"github.com/go-zeromq/zmq4/security/plain"
+	"gotest.tools/assert"
+)
+
+func TestPlainSecurity(t *testing.T) {
+	server := startFakeDfxp(t, dfxpHandler("1.0.0"), zmq4.WithSecurity(plain.Security("", "")))
+
+	options := server.options()
+	options.Security = &SecurityOptions{
+		Mechanism: zmq4.PlainSecurity,
+		User:      "dfxp",
+		Password:  "secret",
+	}
+	client := NewZmqClient(options)
+	if err := client.Connect(options.To); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	msg := &zmqencdec.Message{
+		Header:         zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_GET_INFO},
+		GetInfoRequest: zmqencdec.MsgGetInfoRequest{FlowId: 1234},
+	}
+	response, err := client.Request(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+	assert.Equal(t, "1.0.0", response.GetInfoResponse.Version)
+}
+
+func TestSecurityMismatch(t *testing.T) {
+	server := startFakeDfxp(t, dfxpHandler("1.0.0"))
+
+	options := server.options()
+	options.Security = &SecurityOptions{
+		Mechanism: zmq4.PlainSecurity,
+		User:      "dfxp",
+	}
+	client := NewZmqClient(options)
+	err := client.Connect(options.To)
+	assert.ErrorContains(t, err, "PLAIN handshake failed")
+
+	options.Security.User = ""
+	err = client.Connect(options.To)
+	assert.ErrorContains(t, err, "PLAIN security needs a user name")
+
+	// nothing listening, two endpoints so that the dialer does not retry:
+	// a transport failure, not a handshake one
+	options.Security.User = "dfxp"
+	options.Endpoint = ""
+	options.Endpoints = []string{"inproc://security-mismatch-none", "inproc://security-mismatch-none-2"}
+	err = client.Connect(options.To)
+	assert.ErrorContains(t, err, "could not dial to")
+	assert.Assert(t, !strings.Contains(err.Error(), "handshake"), "unexpected error %v", err)
+}
+
+func TestCurveUnsupported(t *testing.T) {
+	options := &ClientOptions{
+		Host:     "127.0.0.1",
+		Port:     1,
+		To:       1,
+		Security: &SecurityOptions{Mechanism: zmq4.CurveSecurity},
+	}
+	client := NewZmqClient(options)
+	err := client.Connect(options.To)
+	assert.Assert(t, errors.Is(err, ErrCurveUnsupported), "unexpected error %v", err)
+}
+
+func TestMetricsSubscriber(t *testing.T) {
+	publisher := zmq4.NewPub(context.Background())
+	if err := publisher.Listen("tcp://127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen failed. Err:%v", err)
+	}
+	defer publisher.Close()
+
+	client := NewZmqClient(&ClientOptions{To: 1})
+	received := make(chan []byte, 16)
+	client.WithHandler(func(msg *zmq4.Msg) {
+		received <- msg.Bytes()
+	})
+
+	endpoint := "tcp://" + publisher.Addr().(*net.TCPAddr).String()
+	if err := client.ConnectMetrics(endpoint); err != nil {
+		t.Fatalf("ConnectMetrics failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	// the subscription reaches the publisher asynchronously, publish until seen
+	timeout := time.After(5 * time.Second)
+	for {
+		publisher.Send(zmq4.NewMsgString("metrics"))
+		select {
+		case data := <-received:
+			assert.Equal(t, "metrics", string(data))
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("no metrics received")
+		}
+	}
+}
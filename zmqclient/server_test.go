@@ -0,0 +1,107 @@
+package zmqclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"zmqclient/zmqencdec"
+
+	"github.com/go-zeromq/zmq4"
+)
+
+// fakeDfxp - in process dfxp control socket answering with handler
+type fakeDfxp struct {
+	socket   zmq4.Socket
+	endpoint string
+}
+
+func startFakeDfxp(t *testing.T, handler func(request []byte) []byte, opts ...zmq4.Option) *fakeDfxp {
+	return startFakeDfxpAt(t, "tcp://127.0.0.1:0", handler, opts...)
+}
+
+func startFakeDfxpAt(t *testing.T, endpoint string, handler func(request []byte) []byte, opts ...zmq4.Option) *fakeDfxp {
+	socket := zmq4.NewRep(context.Background(), opts...)
+	if err := socket.Listen(endpoint); err != nil {
+		t.Fatalf("Listen failed. Err:%v", err)
+	}
+	t.Cleanup(func() {
+		socket.Close()
+	})
+
+	go func() {
+		for {
+			msg, err := socket.Recv()
+			if err != nil {
+				return
+			}
+			if err := socket.Send(zmq4.NewMsg(handler(msg.Bytes()))); err != nil {
+				return
+			}
+		}
+	}()
+
+	if addr, ok := socket.Addr().(*net.TCPAddr); ok {
+		endpoint = "tcp://" + addr.String()
+	}
+	return &fakeDfxp{
+		socket:   socket,
+		endpoint: endpoint,
+	}
+}
+
+func (server *fakeDfxp) options() *ClientOptions {
+	return &ClientOptions{
+		Endpoint: server.endpoint,
+		To:       1,
+	}
+}
+
+// responseFrame - header followed by the big endian payload fields
+func responseFrame(cmd zmqencdec.ZmqMessageType, fields ...interface{}) []byte {
+	payload := new(bytes.Buffer)
+	for _, field := range fields {
+		switch v := field.(type) {
+		case string:
+			payload.WriteString(v)
+		default:
+			binary.Write(payload, binary.BigEndian, v)
+		}
+	}
+
+	frame := new(bytes.Buffer)
+	binary.Write(frame, binary.BigEndian, uint16(payload.Len()+2))
+	binary.Write(frame, binary.BigEndian, cmd)
+	frame.Write(payload.Bytes())
+	return frame.Bytes()
+}
+
+// requestCommand - command and flow id of a request frame
+func requestCommand(request []byte) (zmqencdec.ZmqMessageType, uint32) {
+	if len(request) < 8 {
+		return zmqencdec.ZMQ_CMD_NONE, 0
+	}
+	return zmqencdec.ZmqMessageType(binary.BigEndian.Uint16(request[2:])), binary.BigEndian.Uint32(request[4:])
+}
+
+// dfxpHandler - answers every command like a healthy dfxp of the given version
+func dfxpHandler(version string) func(request []byte) []byte {
+	return func(request []byte) []byte {
+		cmd, flowId := requestCommand(request)
+		switch cmd {
+		case zmqencdec.ZMQ_CMD_START:
+			return responseFrame(cmd, flowId, "tcp://127.0.0.1:5557")
+		case zmqencdec.ZMQ_CMD_STOP:
+			return responseFrame(cmd, flowId)
+		case zmqencdec.ZMQ_CMD_ADD_TUNNELS, zmqencdec.ZMQ_CMD_DEL_TUNNELS:
+			return responseFrame(cmd, flowId, binary.BigEndian.Uint32(request[8:]))
+		case zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS:
+			return responseFrame(cmd, flowId, uint32(0))
+		case zmqencdec.ZMQ_CMD_GET_INFO:
+			return responseFrame(cmd, flowId, version)
+		default:
+			return responseFrame(zmqencdec.ZMQ_CMD_ERROR, "unknown command")
+		}
+	}
+}
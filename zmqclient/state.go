@@ -0,0 +1,168 @@
+package zmqclient
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ConnState - state of the control connection.
+// zmq4 has no socket monitor: transport errors of the requests and the
+// heartbeat results stand in for the socket events.
+type ConnState int
+
+const (
+	// STATE_DISCONNECTED - no usable control socket, requests are refused
+	STATE_DISCONNECTED ConnState = iota
+	// STATE_CONNECTING - Connect in progress
+	STATE_CONNECTING
+	// STATE_READY - the last request or heartbeat got an answer
+	STATE_READY
+	// STATE_DEGRADED - the last request or heartbeat failed, the control
+	// socket was reopened and is not confirmed yet
+	STATE_DEGRADED
+	// STATE_CLOSED - Close was called, final
+	STATE_CLOSED
+)
+
+var connStateNames = map[ConnState]string{
+	STATE_DISCONNECTED: "DISCONNECTED",
+	STATE_CONNECTING:   "CONNECTING",
+	STATE_READY:        "READY",
+	STATE_DEGRADED:     "DEGRADED",
+	STATE_CLOSED:       "CLOSED",
+}
+
+func (state ConnState) String() string {
+	if name, ok := connStateNames[state]; ok {
+		return name
+	}
+	return fmt.Sprintf("ConnState(%d)", int(state))
+}
+
+// StateEvent - a state change of the client
+type StateEvent struct {
+	From     ConnState
+	To       ConnState
+	Endpoint string
+	// Err - failure that caused the change, nil for READY, CONNECTING and CLOSED
+	Err  error
+	Time time.Time
+}
+
+// HeartbeatOptions - periodic GET_INFO on the control socket
+type HeartbeatOptions struct {
+	Interval time.Duration
+	// MaxMissed - consecutive failed heartbeats, the control socket not
+	// reopened, before DISCONNECTED, 3 when zero
+	MaxMissed int
+}
+
+func (options *HeartbeatOptions) maxMissed() int {
+	if options == nil || options.MaxMissed <= 0 {
+		return 3
+	}
+	return options.MaxMissed
+}
+
+// probeInterval - heartbeat period, Failover.ProbeInterval when no heartbeat is set
+func (options *ClientOptions) probeInterval() time.Duration {
+	if options.Heartbeat != nil && options.Heartbeat.Interval > 0 {
+		return options.Heartbeat.Interval
+	}
+	if options.Failover != nil {
+		return options.Failover.ProbeInterval
+	}
+	return 0
+}
+
+// connState - state and its subscribers, usable without reqMu
+type connState struct {
+	mu          sync.Mutex
+	state       ConnState
+	subscribers map[chan StateEvent]bool
+}
+
+// State - current state of the control connection
+func (client *ZmqClient) State() ConnState {
+	client.conn.mu.Lock()
+	defer client.conn.mu.Unlock()
+	return client.conn.state
+}
+
+// Subscribe - channel receiving the state changes until cancel is called or
+// the client is closed. Changes are dropped when the channel is full.
+func (client *ZmqClient) Subscribe(buffer int) (<-chan StateEvent, func()) {
+	events := make(chan StateEvent, buffer)
+
+	client.conn.mu.Lock()
+	defer client.conn.mu.Unlock()
+	if client.conn.state == STATE_CLOSED {
+		close(events)
+		return events, func() {}
+	}
+	if client.conn.subscribers == nil {
+		client.conn.subscribers = make(map[chan StateEvent]bool)
+	}
+	client.conn.subscribers[events] = true
+
+	cancel := func() {
+		client.conn.mu.Lock()
+		defer client.conn.mu.Unlock()
+		if client.conn.subscribers[events] {
+			delete(client.conn.subscribers, events)
+			close(events)
+		}
+	}
+	return events, cancel
+}
+
+func (client *ZmqClient) isConnected() bool {
+	state := client.State()
+	return state == STATE_READY || state == STATE_DEGRADED
+}
+
+// setState - move to state and notify the subscribers
+func (client *ZmqClient) setState(state ConnState, endpoint string, err error) {
+	client.conn.mu.Lock()
+	defer client.conn.mu.Unlock()
+
+	from := client.conn.state
+	if from == state || from == STATE_CLOSED {
+		return
+	}
+	client.conn.state = state
+	if err != nil {
+		client.log().Warn("dfxp state changed", "endpoint", endpoint, "from", from, "to", state, "error", err)
+	} else {
+		client.log().Info("dfxp state changed", "endpoint", endpoint, "from", from, "to", state)
+	}
+
+	event := StateEvent{
+		From:     from,
+		To:       state,
+		Endpoint: endpoint,
+		Err:      err,
+		Time:     time.Now(),
+	}
+	for events := range client.conn.subscribers {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+	if state == STATE_CLOSED {
+		for events := range client.conn.subscribers {
+			close(events)
+		}
+		client.conn.subscribers = nil
+	}
+}
+
+// activeEndpoint - caller holds reqMu
+func (client *ZmqClient) activeEndpoint() string {
+	if len(client.endpoints) == 0 {
+		return ""
+	}
+	return client.endpoints[client.active]
+}
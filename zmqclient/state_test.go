@@ -0,0 +1,159 @@
+package zmqclient
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+// waitState - next event moving to state, skipping the others
+func waitState(t *testing.T, events <-chan StateEvent, state ConnState) StateEvent {
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("events closed before %s", state)
+			}
+			if event.To == state {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("no move to %s", state)
+		}
+	}
+}
+
+func TestStateConnectAndClose(t *testing.T) {
+	server := startFakeDfxpAt(t, "inproc://state-connect", dfxpHandler("dfxp v1.0"))
+	client := NewZmqClient(server.options())
+	assert.Equal(t, STATE_DISCONNECTED, client.State())
+
+	events, cancel := client.Subscribe(8)
+	defer cancel()
+
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	assert.Equal(t, STATE_READY, client.State())
+	assert.Equal(t, StateEvent{From: STATE_DISCONNECTED, To: STATE_CONNECTING, Endpoint: server.endpoint}, clearTime(<-events))
+	assert.Equal(t, StateEvent{From: STATE_CONNECTING, To: STATE_READY, Endpoint: server.endpoint}, clearTime(<-events))
+
+	client.Close()
+	assert.Equal(t, STATE_CLOSED, client.State())
+	assert.Equal(t, STATE_CLOSED, (<-events).To)
+	_, ok := <-events
+	assert.Assert(t, !ok, "\nThe events must be closed with the client.")
+
+	_, err := getInfo(t, client)
+	assert.ErrorContains(t, err, "not connected")
+}
+
+func TestStateConnectFailed(t *testing.T) {
+	client := NewZmqClient(&ClientOptions{Endpoint: "inproc://state-nobody", To: 1})
+	events, cancel := client.Subscribe(8)
+	defer cancel()
+
+	assert.Assert(t, client.Connect(1) != nil)
+	assert.Equal(t, STATE_DISCONNECTED, client.State())
+	event := waitState(t, events, STATE_DISCONNECTED)
+	assert.Equal(t, STATE_CONNECTING, event.From)
+	assert.Assert(t, event.Err != nil, "\nThe dial error must be reported.")
+}
+
+func TestStateHeartbeat(t *testing.T) {
+	server := startFakeDfxpAt(t, "inproc://state-heartbeat", dfxpHandler("dfxp v1.0"))
+
+	options := server.options()
+	// a standby nobody listens on: no dial retries
+	options.Endpoints = []string{server.endpoint, "inproc://state-heartbeat-standby"}
+	options.Heartbeat = &HeartbeatOptions{
+		Interval:  50 * time.Millisecond,
+		MaxMissed: 2,
+	}
+	client := NewZmqClient(options)
+	events, cancel := client.Subscribe(16)
+	defer cancel()
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+	waitState(t, events, STATE_READY)
+
+	// no endpoint to reopen
+	server.socket.Close()
+	disconnected := waitState(t, events, STATE_DISCONNECTED)
+	assert.Assert(t, disconnected.Err != nil)
+
+	_, err := getInfo(t, client)
+	assert.ErrorContains(t, err, "not connected")
+
+	startFakeDfxpAt(t, "inproc://state-heartbeat", dfxpHandler("dfxp v1.0"))
+	waitState(t, events, STATE_READY)
+	if _, err := getInfo(t, client); err != nil {
+		t.Fatalf("getInfo failed. Err:%v", err)
+	}
+}
+
+func TestStateHeartbeatReopen(t *testing.T) {
+	var stalled atomic.Bool
+	healthy := dfxpHandler("dfxp v1.0")
+	server := startFakeDfxpAt(t, "inproc://state-heartbeat-reopen", func(request []byte) []byte {
+		if stalled.Load() {
+			return nil
+		}
+		return healthy(request)
+	})
+
+	options := server.options()
+	options.Heartbeat = &HeartbeatOptions{
+		Interval:  50 * time.Millisecond,
+		MaxMissed: 2,
+	}
+	client := NewZmqClient(options)
+	events, cancel := client.Subscribe(16)
+	defer cancel()
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+	waitState(t, events, STATE_READY)
+
+	// an empty answer does not decode: heartbeats fail, the socket reopens
+	stalled.Store(true)
+	time.Sleep(5 * options.Heartbeat.Interval)
+	assert.Equal(t, STATE_DEGRADED, client.State())
+	stalled.Store(false)
+
+	var moves []ConnState
+	for len(moves) == 0 || moves[len(moves)-1] != STATE_READY {
+		select {
+		case event := <-events:
+			moves = append(moves, event.To)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("no move to READY after %v", moves)
+		}
+	}
+	assert.DeepEqual(t, []ConnState{STATE_DEGRADED, STATE_READY}, moves)
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	client := NewZmqClient(&ClientOptions{Endpoint: "inproc://state-cancel", To: 1})
+	events, cancel := client.Subscribe(1)
+	cancel()
+	cancel()
+	_, ok := <-events
+	assert.Assert(t, !ok, "\nThe events must be closed by cancel.")
+
+	client.Close()
+	events, _ = client.Subscribe(1)
+	_, ok = <-events
+	assert.Assert(t, !ok, "\nSubscribing to a closed client gets closed events.")
+}
+
+func clearTime(event StateEvent) StateEvent {
+	event.Time = time.Time{}
+	return event
+}
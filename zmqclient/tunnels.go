@@ -0,0 +1,81 @@
+package zmqclient
+
+import (
+	"sort"
+	"sync"
+	"zmqclient/zmqencdec"
+	"zmqclient/zmqtunnel"
+)
+
+// tunnelTable - TEIDs the client added and did not delete, by flow
+type tunnelTable struct {
+	mu    sync.Mutex
+	flows map[uint32]map[uint32]bool
+}
+
+var _ zmqtunnel.KnownTunnels = (*tunnelTable)(nil)
+
+func (table *tunnelTable) HasTunnel(flowId uint32, teid uint32) bool {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	return table.flows[flowId][teid]
+}
+
+// update - apply a request answered without error
+func (table *tunnelTable) update(msg *zmqencdec.Message) {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	if table.flows == nil {
+		table.flows = make(map[uint32]map[uint32]bool)
+	}
+
+	switch msg.Header.Command {
+	case zmqencdec.ZMQ_CMD_ADD_TUNNELS:
+		flowId := msg.AddTunnelRequest.FlowId
+		teids := table.flows[flowId]
+		if teids == nil {
+			teids = make(map[uint32]bool)
+			table.flows[flowId] = teids
+		}
+		for _, tunnel := range msg.AddTunnelRequest.Tunnels {
+			teids[tunnel.TeidIn] = true
+		}
+	case zmqencdec.ZMQ_CMD_DEL_TUNNELS:
+		teids := table.flows[msg.DelTunnelsRequest.FlowId]
+		for _, teid := range msg.DelTunnelsRequest.Teids {
+			delete(teids, teid)
+		}
+	case zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS:
+		delete(table.flows, msg.DelAllTunnelsRequest.FlowId)
+	}
+}
+
+// reset - forget every flow, e.g. when the control socket moves to another
+// dfxp, which never got the tunnels
+func (table *tunnelTable) reset() {
+	table.mu.Lock()
+	defer table.mu.Unlock()
+	table.flows = nil
+}
+
+// KnownTunnels - TEIDs the client added to flowId and did not delete, the
+// table ADD_TUNNELS and DEL_TUNNELS requests are validated against
+func (client *ZmqClient) KnownTunnels(flowId uint32) []uint32 {
+	client.tunnels.mu.Lock()
+	defer client.tunnels.mu.Unlock()
+	teids := make([]uint32, 0, len(client.tunnels.flows[flowId]))
+	for teid := range client.tunnels.flows[flowId] {
+		teids = append(teids, teid)
+	}
+	sort.Slice(teids, func(i, j int) bool { return teids[i] < teids[j] })
+	return teids
+}
+
+// validate - *zmqtunnel.ValidationError of an invalid tunnel request, unless
+// ClientOptions.SkipTunnelValidation is set
+func (client *ZmqClient) validate(msg *zmqencdec.Message) error {
+	if client.options.SkipTunnelValidation {
+		return nil
+	}
+	return zmqtunnel.Validate(msg, &client.tunnels)
+}
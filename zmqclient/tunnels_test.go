@@ -0,0 +1,131 @@
+package zmqclient
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"zmqclient/zmqencdec"
+	"zmqclient/zmqtunnel"
+
+	"gotest.tools/assert"
+)
+
+func tunnelsRequest(cmd zmqencdec.ZmqMessageType, flowId uint32, teids ...uint32) *zmqencdec.Message {
+	msg := &zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: cmd}}
+	switch cmd {
+	case zmqencdec.ZMQ_CMD_ADD_TUNNELS:
+		msg.AddTunnelRequest.FlowId = flowId
+		for _, teid := range teids {
+			msg.AddTunnelRequest.Tunnels = append(msg.AddTunnelRequest.Tunnels,
+				zmqencdec.Tunnel{TeidIn: teid, TeidOut: teid, UeIpV4: 0x0a000000 + teid, SrvIpV4: 0xc0a80001})
+		}
+	case zmqencdec.ZMQ_CMD_DEL_TUNNELS:
+		msg.DelTunnelsRequest = zmqencdec.MsgDelTunnelsRequest{FlowId: flowId, Teids: teids}
+	case zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS:
+		msg.DelAllTunnelsRequest.FlowId = flowId
+	}
+	return msg
+}
+
+func TestTunnelValidation(t *testing.T) {
+	var sent atomic.Int32
+	healthy := dfxpHandler("dfxp v1.0")
+	server := startFakeDfxpAt(t, "inproc://tunnel-validation", func(request []byte) []byte {
+		sent.Add(1)
+		return healthy(request)
+	})
+	client := NewZmqClient(server.options())
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if _, err := client.Request(ctx, tunnelsRequest(zmqencdec.ZMQ_CMD_ADD_TUNNELS, 1, 10, 11, 12)); err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+	if _, err := client.Request(ctx, tunnelsRequest(zmqencdec.ZMQ_CMD_DEL_TUNNELS, 1, 11)); err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, []uint32{10, 12}, client.KnownTunnels(1))
+	assert.Equal(t, int32(2), sent.Load())
+
+	// invalid requests never reach dfxp
+	var validationErr *zmqtunnel.ValidationError
+	_, err := client.Request(ctx, tunnelsRequest(zmqencdec.ZMQ_CMD_ADD_TUNNELS, 1, 12, 13, 13))
+	assert.Assert(t, errors.As(err, &validationErr), "\nThe violations must be returned. Err:%v", err)
+	assert.Equal(t, 2, len(validationErr.Violations))
+	_, err = client.Request(ctx, tunnelsRequest(zmqencdec.ZMQ_CMD_DEL_TUNNELS, 1, 11))
+	assert.ErrorContains(t, err, "TEID 11 never added")
+	_, err = client.Request(ctx, tunnelsRequest(zmqencdec.ZMQ_CMD_DEL_TUNNELS, 2, 10))
+	assert.ErrorContains(t, err, "TEID 10 never added", "\nThe table must be kept by flow.")
+	assert.Equal(t, int32(2), sent.Load())
+	assert.Equal(t, STATE_READY, client.State())
+
+	if _, err := client.Request(ctx, tunnelsRequest(zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS, 1)); err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+	assert.Equal(t, 0, len(client.KnownTunnels(1)))
+}
+
+func TestTunnelValidationOptOut(t *testing.T) {
+	server := startFakeDfxpAt(t, "inproc://tunnel-validation-opt-out", dfxpHandler("dfxp v1.0"))
+	options := server.options()
+	options.SkipTunnelValidation = true
+	client := NewZmqClient(options)
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	// tunnels added by another client are unknown to this one
+	if _, err := client.Request(context.Background(), tunnelsRequest(zmqencdec.ZMQ_CMD_DEL_TUNNELS, 1, 11)); err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+}
+
+func TestTunnelTableSwitchover(t *testing.T) {
+	var stalled atomic.Bool
+	stuck := make(chan bool)
+	t.Cleanup(func() { close(stuck) })
+	healthy := dfxpHandler("primary")
+	primary := startFakeDfxpAt(t, "inproc://tunnel-switchover-primary", func(request []byte) []byte {
+		if stalled.Load() {
+			<-stuck
+			return nil
+		}
+		return healthy(request)
+	})
+	standby := startFakeDfxpAt(t, "inproc://tunnel-switchover-standby", dfxpHandler("standby"))
+
+	options := &ClientOptions{
+		Endpoints: []string{primary.endpoint, standby.endpoint},
+		To:        1,
+	}
+	client := NewZmqClient(options)
+	if err := client.Connect(options.To); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if _, err := client.Request(ctx, tunnelsRequest(zmqencdec.ZMQ_CMD_ADD_TUNNELS, 1, 10, 11)); err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, []uint32{10, 11}, client.KnownTunnels(1))
+
+	stalled.Store(true)
+	_, err := getInfo(t, client)
+	assert.Assert(t, errors.Is(err, ErrTimeout), "unexpected error %v", err)
+	assert.Equal(t, standby.endpoint, client.Endpoint())
+
+	// the standby never got the tunnels of the primary
+	assert.Equal(t, 0, len(client.KnownTunnels(1)))
+	_, err = client.Request(ctx, tunnelsRequest(zmqencdec.ZMQ_CMD_DEL_TUNNELS, 1, 10))
+	assert.ErrorContains(t, err, "TEID 10 never added")
+	if _, err := client.Request(ctx, tunnelsRequest(zmqencdec.ZMQ_CMD_ADD_TUNNELS, 1, 10)); err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, []uint32{10}, client.KnownTunnels(1))
+}
@@ -0,0 +1,62 @@
+package zmqclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"zmqclient/zmqencdec"
+)
+
+// dfxp versions this client speaks, used when ClientOptions leaves the range empty
+var (
+	MinDfxpVersion = zmqencdec.Version{Major: 1}
+	MaxDfxpVersion = zmqencdec.Version{Major: 1, Minor: math.MaxInt32, Patch: math.MaxInt32}
+)
+
+var ErrUnsupportedVersion = errors.New("unsupported dfxp version")
+
+// ErrUnknownMetricsLayout - the negotiated dfxp version publishes METRICS in a
+// layout this client does not know; the frames still reach WithHandler
+var ErrUnknownMetricsLayout = errors.New("unknown METRICS layout")
+
+// Capabilities - capabilities negotiated on Connect, nil when Negotiate is off
+func (client *ZmqClient) Capabilities() *zmqencdec.Capabilities {
+	return client.capabilities.Load()
+}
+
+// negotiate - ask dfxp for its version and check it is in the supported range
+func (client *ZmqClient) negotiate() error {
+	response, err := client.roundTrip(context.Background(), infoRequest())
+	if err != nil {
+		return fmt.Errorf("get dfxp info failed. Error: %v", err)
+	}
+	if response.Header.Command != zmqencdec.ZMQ_CMD_GET_INFO {
+		return fmt.Errorf("get dfxp info failed. Unexpected response %s", response.Header.Command)
+	}
+
+	info := response.GetInfoResponse.Version
+	version, err := zmqencdec.ParseVersion(info)
+	if err != nil {
+		return fmt.Errorf("get dfxp info failed. Error: %v", err)
+	}
+
+	minVersion, maxVersion := client.options.MinVersion, client.options.MaxVersion
+	if minVersion == (zmqencdec.Version{}) {
+		minVersion = MinDfxpVersion
+	}
+	if maxVersion == (zmqencdec.Version{}) {
+		maxVersion = MaxDfxpVersion
+	}
+	if !version.AtLeast(minVersion) || !maxVersion.AtLeast(version) {
+		return fmt.Errorf("%w %s (%q), supported %s - %s", ErrUnsupportedVersion, version, info, minVersion, maxVersion)
+	}
+
+	client.log().Info("dfxp version negotiated", "version", version, "info", info)
+	capabilities := zmqencdec.NewCapabilities(version, info)
+	if !capabilities.Metrics {
+		client.log().Warn("dfxp metrics layout unknown, published metrics are not decoded", "version", version)
+	}
+	client.capabilities.Store(capabilities)
+	return nil
+}
@@ -0,0 +1,108 @@
+package zmqclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"zmqclient/zmqencdec"
+
+	"github.com/go-zeromq/zmq4"
+	"gotest.tools/assert"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	server := startFakeDfxp(t, dfxpHandler("dfxp v1.1"))
+
+	options := server.options()
+	options.Negotiate = true
+	client := NewZmqClient(options)
+	if err := client.Connect(options.To); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	caps := client.Capabilities()
+	assert.Assert(t, caps != nil, "capabilities should be negotiated")
+	assert.Equal(t, zmqencdec.Version{Major: 1, Minor: 1}, caps.Version)
+	assert.Equal(t, "dfxp v1.1", caps.Info)
+	assert.Assert(t, caps.Metrics, "dfxp 1.x metrics layout should be known")
+}
+
+func TestNegotiateUnsupportedVersion(t *testing.T) {
+	server := startFakeDfxp(t, dfxpHandler("dfxp v2.0"))
+
+	options := server.options()
+	options.Negotiate = true
+	client := NewZmqClient(options)
+	err := client.Connect(options.To)
+	assert.Assert(t, errors.Is(err, ErrUnsupportedVersion), "unexpected error %v", err)
+	assert.Assert(t, !client.isConnected())
+
+	options.MaxVersion = zmqencdec.Version{Major: 2, Minor: 9}
+	if err := client.Connect(options.To); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+	assert.Equal(t, 2, client.Capabilities().Version.Major)
+	assert.Assert(t, !client.Capabilities().Metrics, "dfxp 2.x metrics layout should be unknown")
+}
+
+func TestNegotiatedMetricsLayout(t *testing.T) {
+	server := startFakeDfxp(t, dfxpHandler("dfxp v2.0"))
+	publisher := zmq4.NewPub(context.Background())
+	if err := publisher.Listen("inproc://negotiated-metrics"); err != nil {
+		t.Fatalf("Listen failed. Err:%v", err)
+	}
+	defer publisher.Close()
+
+	options := server.options()
+	options.Negotiate = true
+	options.MaxVersion = zmqencdec.Version{Major: 2, Minor: 9}
+	client := NewZmqClient(options)
+	if err := client.Connect(options.To); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	raw := make(chan []byte, 16)
+	decoded := make(chan *zmqencdec.Message, 16)
+	client.WithMetricsHandler(func(ctx context.Context, msg *zmqencdec.Message) error {
+		decoded <- msg
+		return nil
+	})
+	client.WithHandler(func(msg *zmq4.Msg) {
+		raw <- msg.Bytes()
+	})
+	if err := client.ConnectMetrics("inproc://negotiated-metrics"); err != nil {
+		t.Fatalf("ConnectMetrics failed. Err:%v", err)
+	}
+
+	metrics := &zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_METRICS}}
+	metrics.Metrics = zmqencdec.MsgMetrics{FlowId: 7, Metrics: []zmqencdec.ProtocolMetrics{
+		{Length: 76, Command: uint16(zmqencdec.ZMQ_CMD_METRICS), FlowId: 7, Protocol: zmqencdec.METRICS_PROTOCOL_TCP},
+	}}
+	frame, err := (&zmqencdec.ZmqEncoder{}).EncodeResponse(metrics)
+	if err != nil {
+		t.Fatalf("EncodeResponse failed. Err:%v", err)
+	}
+
+	// the subscription reaches the publisher asynchronously, publish until seen
+	timeout := time.After(5 * time.Second)
+	for received := false; !received; {
+		publisher.Send(zmq4.NewMsg(frame))
+		select {
+		case data := <-raw:
+			assert.DeepEqual(t, frame, data)
+			received = true
+		case <-time.After(50 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("no metrics received")
+		}
+	}
+	select {
+	case msg := <-decoded:
+		t.Fatalf("metrics decoded with an unknown layout: %+v", msg.Metrics)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
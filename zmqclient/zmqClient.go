@@ -2,65 +2,223 @@ package zmqclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"zmqclient/zmqcapture"
+	"zmqclient/zmqencdec"
+	"zmqclient/zmqlog"
 
 	"github.com/go-zeromq/zmq4"
 	zmq "github.com/go-zeromq/zmq4"
-	"github.com/golang/glog"
 )
 
 type ZmqPacketHandler func(msg *zmq4.Msg)
 
 type ClientOptions struct {
+	// Host/Port - shortcut for Endpoint "tcp://Host:Port"
 	Host string
 	Port int
 	To   int
+
+	// Endpoint/Endpoints - full zmq endpoints (tcp://, ipc://, inproc://)
+	Endpoint  string
+	Endpoints []string
+
+	// Negotiate - send GET_INFO after connect and check the dfxp version
+	// against [MinVersion, MaxVersion], MinDfxpVersion/MaxDfxpVersion when zero
+	Negotiate  bool
+	MinVersion zmqencdec.Version
+	MaxVersion zmqencdec.Version
+
+	// Security - PLAIN settings for the control and metrics sockets, nil for none
+	Security *SecurityOptions
+
+	// Failover - health probing and switchover policy across the endpoints
+	Failover *FailoverOptions
+
+	// Heartbeat - periodic GET_INFO driving State(), nil for none
+	Heartbeat *HeartbeatOptions
+
+	// Logger - client, codec and interceptor logs, nil for zmqlog.Default()
+	Logger zmqlog.Logger
+
+	// SkipTunnelValidation - send ADD_TUNNELS and DEL_TUNNELS without checking
+	// them with zmqtunnel.Validate against the tunnels added by this client
+	SkipTunnelValidation bool
+
+	// Codec - wire form of the messages, nil for the dfxp binary layout of
+	// zmqencdec.ZmqEncoder; e.g. zmqcbor.Codec for dfxp builds taking CBOR
+	Codec zmqencdec.Codec
 }
 
 type ZmqClient struct {
-	connected    bool
+	conn         connState
 	options      *ClientOptions
+	reqMu        sync.Mutex // one request/response exchange at a time on socket
 	socket       zmq.Socket
+	endpoints    []string
+	active       int
+	probeExit    chan bool
+	metrics      zmq.Socket
 	handler      ZmqPacketHandler
 	listenerExit chan bool
+
+	interceptors        []Interceptor
+	metricsInterceptors []MetricsInterceptor
+	metricsHandler      MetricsHandler
+	flows               flowTable
+	tunnels             tunnelTable
+
+	recorder        *zmqcapture.Recorder
+	instrumentation Instrumentation
+	codec           zmqencdec.Codec
+	capabilities    atomic.Pointer[zmqencdec.Capabilities]
 }
 
 func NewZmqClient(options *ClientOptions) *ZmqClient {
+	codec := options.Codec
+	if codec == nil {
+		codec = &zmqencdec.ZmqEncoder{Logger: options.Logger}
+	}
 	return &ZmqClient{
 		options: options,
+		codec:   codec,
 	}
 }
+
+// WithHandler - handler receives the metrics published once ConnectMetrics is done
 func (c *ZmqClient) WithHandler(handler ZmqPacketHandler) error {
 	c.handler = handler
-	c.listenerExit = make(chan bool)
-
-	if c.handler != nil {
-		go func() {
-			c.listen()
-		}()
-	}
+	c.startListener()
 	return nil
 }
 
+// WithRecorder - record every control and metrics frame, e.g. for pcapng export
+func (c *ZmqClient) WithRecorder(recorder *zmqcapture.Recorder) {
+	c.recorder = recorder
+}
+
+// Connect - connect the control socket to the first endpoint that answers
 func (client *ZmqClient) Connect(to int) error {
-	ctx := context.Background()
-	socket := zmq.NewReq(ctx, zmq.WithDialerRetry(time.Second),zmq.WithDialerTimeout(time.Second*time.Duration(to)))
+	endpoints, err := client.options.endpoints()
+	if err != nil {
+		return err
+	}
+
+	client.reqMu.Lock()
+	client.endpoints = endpoints
+	for idx := range endpoints {
+		client.setState(STATE_CONNECTING, endpoints[idx], nil)
+		if err = client.connectTo(idx, to); err == nil {
+			client.setState(STATE_READY, endpoints[idx], nil)
+			break
+		}
+		client.log().Error("connect failed", "endpoint", endpoints[idx], "error", err)
+	}
+	if err != nil {
+		client.setState(STATE_DISCONNECTED, endpoints[len(endpoints)-1], err)
+	}
+	client.reqMu.Unlock()
 
-	// todo after connect send info command to get dfxp version & information
-	glog.Infof("connecting perf zmq %s:%d", client.options.Host, client.options.Port)
+	if err != nil {
+		return err
+	}
+	client.startProbe()
+	return nil
+}
 
-	if err := socket.Dial(fmt.Sprintf("tcp://%s:%d", client.options.Host, client.options.Port)); err != nil {
+// connectTo - open the control socket on endpoints[idx]; caller holds reqMu
+func (client *ZmqClient) connectTo(idx int, to int) error {
+	opts, err := client.socketOptions(to)
+	if err != nil {
 		return err
 	}
+	socket := zmq.NewReq(context.Background(), opts...)
+
+	endpoint := client.endpoints[idx]
+	client.log().Info("connecting perf zmq", "endpoint", endpoint)
+
+	if err := socket.Dial(endpoint); err != nil {
+		socket.Close()
+		return client.dialError(err)
+	}
 
 	client.socket = socket
-	client.connected = true
+	client.active = idx
+
+	if client.options.Negotiate {
+		if err := client.negotiate(); err != nil {
+			socket.Close()
+			client.socket = nil
+			return err
+		}
+	}
 	return nil
 }
 
-func (client *ZmqClient) isConnected() bool {
-	return client.connected
+// ConnectMetrics - subscribe to the metrics publisher returned by the START response
+func (client *ZmqClient) ConnectMetrics(publisher string) error {
+	if err := ValidateEndpoint(publisher); err != nil {
+		return err
+	}
+	opts, err := client.socketOptions(client.options.To)
+	if err != nil {
+		return err
+	}
+	socket := zmq.NewSub(context.Background(), opts...)
+
+	client.log().Info("connecting perf metrics", "endpoint", publisher)
+
+	if err := socket.Dial(publisher); err != nil {
+		socket.Close()
+		return client.dialError(err)
+	}
+	if err := socket.SetOption(zmq.OptionSubscribe, ""); err != nil {
+		socket.Close()
+		return err
+	}
+
+	client.metrics = socket
+	client.startListener()
+	return nil
+}
+
+func (client *ZmqClient) socketOptions(to int) ([]zmq.Option, error) {
+	opts := []zmq.Option{
+		zmq.WithDialerRetry(time.Second),
+		zmq.WithDialerTimeout(time.Second * time.Duration(to)),
+	}
+	if len(client.endpoints) > 1 {
+		// do not insist on a dead endpoint when another one can take over
+		opts = append(opts, zmq.WithDialerMaxRetries(client.options.Failover.dialRetries()))
+	}
+
+	security, err := client.options.Security.security()
+	if err != nil {
+		return nil, err
+	}
+	if security != nil {
+		opts = append(opts, zmq.WithSecurity(security))
+	}
+	return opts, nil
+}
+
+// dialError - label failures past the transport connect as handshake failures
+// when a security mechanism is set. zmq4 has no typed dial error, transport
+// failures (refused, unknown inproc endpoint) are all "could not dial to".
+func (client *ZmqClient) dialError(err error) error {
+	security := client.options.Security
+	if security == nil || security.Mechanism == "" || security.Mechanism == zmq.NullSecurity {
+		return err
+	}
+	if strings.Contains(err.Error(), "could not dial to") {
+		return err
+	}
+	return fmt.Errorf("%s handshake failed. Error: %w", security.Mechanism, err)
 }
 
 // Send - send packet to server
@@ -69,34 +227,112 @@ func (client *ZmqClient) Send(packet []byte) error {
 	if err := client.socket.Send(msg); err != nil {
 		return fmt.Errorf("send failed. Error: %v", err)
 	}
+	client.record(zmqcapture.SOCKET_CONTROL, zmqcapture.DIRECTION_SENT, packet)
 	return nil
 }
 
+// SendAndReceiveWithTimeout - send packet and wait for the response.
+// On failure the control socket is reopened, on the next endpoint if any.
 func (client *ZmqClient) SendAndReceiveWithTimeout(packet []byte, to int) ([]byte, error) {
+	client.reqMu.Lock()
+	response, err := client.exchange(context.Background(), packet, to)
+	var switched *switchover
+	if err != nil {
+		switched = client.recover(err)
+	} else {
+		client.setState(STATE_READY, client.activeEndpoint(), nil)
+	}
+	client.reqMu.Unlock()
+
+	client.notifySwitchover(switched)
+	return response, err
+}
+
+// exchange - send packet and wait for the response until to seconds or ctx
+// is done; caller holds reqMu
+func (client *ZmqClient) exchange(ctx context.Context, packet []byte, to int) ([]byte, error) {
+	if client.socket == nil {
+		return nil, fmt.Errorf("send failed. Error: not connected")
+	}
 	if err := client.Send(packet); err != nil {
 		return nil, err
 	}
-	return client.ReceiveWithTimeout(to)
+	return client.receive(ctx, to)
 }
 
+var ErrTimeout = errors.New("timeout")
+
+type recvResult struct {
+	msg zmq.Msg
+	err error
+}
+
+// ReceiveWithTimeout - wait for the response of a packet sent with Send.
+// On failure the control socket is reopened, on the next endpoint if any,
+// so that a late response is not taken for the one of the next request.
 func (client *ZmqClient) ReceiveWithTimeout(to int) ([]byte, error) {
-	select {
-	case <-time.After(time.Duration(to) * time.Second):
+	client.reqMu.Lock()
+	response, err := client.receive(context.Background(), to)
+	var switched *switchover
+	if err != nil {
+		switched = client.recover(err)
+	} else {
+		client.setState(STATE_READY, client.activeEndpoint(), nil)
+	}
+	client.reqMu.Unlock()
+
+	client.notifySwitchover(switched)
+	return response, err
+}
 
-		return nil, fmt.Errorf("receive failed. Error timeout")
-	default:
+// receive - wait for the response until to seconds or ctx is done; caller
+// holds reqMu. On failure the Recv goroutine is left on the socket, the
+// caller must close it (recover).
+func (client *ZmqClient) receive(ctx context.Context, to int) ([]byte, error) {
+	if client.socket == nil {
+		return nil, fmt.Errorf("receive failed. Error: not connected")
+	}
+	socket := client.socket
+	result := make(chan recvResult, 1)
+	go func() {
 		// Wait for reply.
-		r, err := client.socket.Recv()
-		if err != nil {
-			return nil, fmt.Errorf("receive failed. Error: %v", err)
+		msg, err := socket.Recv()
+		result <- recvResult{msg, err}
+	}()
+
+	select {
+	case <-time.After(time.Duration(to) * time.Second):
+		return nil, fmt.Errorf("receive failed. Error: %w", ErrTimeout)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("receive failed. Error: %w", ctx.Err())
+	case r := <-result:
+		if r.err != nil {
+			return nil, fmt.Errorf("receive failed. Error: %v", r.err)
 		}
-		return r.Bytes(), nil
+		client.record(zmqcapture.SOCKET_CONTROL, zmqcapture.DIRECTION_RECEIVED, r.msg.Bytes())
+		return r.msg.Bytes(), nil
 	}
 }
 
 func (client *ZmqClient) Close() error {
+	client.stopProbe()
+
+	client.reqMu.Lock()
+	defer client.reqMu.Unlock()
+
+	client.setState(STATE_CLOSED, client.activeEndpoint(), nil)
+	if client.listenerExit != nil {
+		close(client.listenerExit)
+		client.listenerExit = nil
+	}
+	if client.metrics != nil {
+		client.metrics.Close()
+		client.metrics = nil
+	}
 	if client.socket != nil {
-		if err := client.socket.Close(); err != nil {
+		socket := client.socket
+		client.socket = nil
+		if err := socket.Close(); err != nil {
 			return err
 		}
 	}
@@ -106,26 +342,54 @@ func (client *ZmqClient) Close() error {
 // ///////////////////////////////////////////////////////////
 // Local API
 // ///////////////////////////////////////////////////////////
+// startListener - start listen once a handler and the metrics socket are set
+func (c *ZmqClient) startListener() {
+	if (c.handler == nil && c.metricsHandler == nil && !c.flowMetrics()) || c.metrics == nil || c.listenerExit != nil {
+		return
+	}
+	c.listenerExit = make(chan bool)
+	go func(socket zmq.Socket, exit chan bool) {
+		c.listen(socket, exit)
+	}(c.metrics, c.listenerExit)
+}
+
 // listen - listen zmq and return received a complete message
-func (c *ZmqClient) listen() {
+func (c *ZmqClient) listen(socket zmq.Socket, exit chan bool) {
 	for {
 		select {
-		case <-c.listenerExit:
-			glog.Infoln("sctp client listen quit")
+		case <-exit:
+			c.log().Debug("zmq client listen quit")
 			return
 		default:
 			// Wait for message.
-			if msg, err := c.socket.Recv(); err == nil {
+			if msg, err := socket.Recv(); err == nil {
+				c.record(zmqcapture.SOCKET_METRICS, zmqcapture.DIRECTION_RECEIVED, msg.Bytes())
 				go func(msg *zmq4.Msg) {
-					c.handler(msg)
+					if c.handler != nil {
+						c.handler(msg)
+					}
+					if c.metricsHandler != nil || c.flowMetrics() {
+						c.handleMetrics(msg.Bytes())
+					}
 				}(&msg)
 			} else {
-				glog.Errorf("zmq client recv error:%v", err)
+				c.log().Error("zmq client recv failed", "error", err)
+				c.observeListenerError(err)
 			}
 		}
 	}
 }
 
+func (c *ZmqClient) log() zmqlog.Logger {
+	return zmqlog.Or(c.options.Logger)
+}
+
+func (c *ZmqClient) record(socket zmqcapture.SocketKind, direction zmqcapture.Direction, data []byte) {
+	if c.recorder != nil {
+		c.recorder.Record(socket, direction, data)
+	}
+}
+
 // //////////////////////////////////////////////////////////
 func ZmqClientExe() error {
 	ctx := context.Background()
@@ -0,0 +1,452 @@
+package zmqcluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"zmqclient/zmqclient"
+	"zmqclient/zmqencdec"
+	"zmqclient/zmqlog"
+)
+
+// Node - one dfxp generator of the cluster
+type Node struct {
+	Name    string
+	Options *zmqclient.ClientOptions
+	// Weight - share of the flows with PLACEMENT_WEIGHTED, 1 when zero
+	Weight int
+}
+
+// clusterNode - a Node with its client and placement state
+type clusterNode struct {
+	Node
+	client    *zmqclient.ZmqClient
+	current   int    // smooth weighted round-robin credit
+	publisher string // metrics publisher the client is subscribed to
+}
+
+// Cluster - one controller for many dfxp nodes. Each flow lives on one node,
+// its tunnels and requests follow it there.
+type Cluster struct {
+	mu        sync.Mutex
+	nodes     []*clusterNode
+	byName    map[string]*clusterNode
+	placement Placement
+	next      int
+	pins      map[uint32]string
+	flows     map[uint32]*clusterNode
+	view      *MetricsView
+	logger    zmqlog.Logger
+}
+
+func NewCluster(nodes []Node, placement Placement) (*Cluster, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("cluster needs at least one node")
+	}
+	cluster := &Cluster{
+		byName:    make(map[string]*clusterNode),
+		placement: placement,
+		pins:      make(map[uint32]string),
+		flows:     make(map[uint32]*clusterNode),
+		view:      NewMetricsView(),
+	}
+	for _, node := range nodes {
+		if node.Name == "" || node.Options == nil {
+			return nil, fmt.Errorf("cluster node needs a name and client options")
+		}
+		if _, ok := cluster.byName[node.Name]; ok {
+			return nil, fmt.Errorf("duplicate cluster node %s", node.Name)
+		}
+		if node.Weight < 0 {
+			return nil, fmt.Errorf("node %s weight %d is negative", node.Name, node.Weight)
+		}
+		if node.Weight == 0 {
+			node.Weight = 1
+		}
+		n := &clusterNode{
+			Node:   node,
+			client: zmqclient.NewZmqClient(node.Options),
+		}
+		n.client.WithMetricsHandler(cluster.metricsHandler(node.Name))
+		cluster.nodes = append(cluster.nodes, n)
+		cluster.byName[node.Name] = n
+	}
+	return cluster, nil
+}
+
+// WithLogger - logger of the cluster itself, nil for zmqlog.Default().
+// The node clients use their ClientOptions.Logger.
+func (cluster *Cluster) WithLogger(logger zmqlog.Logger) {
+	cluster.logger = logger
+}
+
+// Connect - connect the control socket of every node
+func (cluster *Cluster) Connect(to int) error {
+	return cluster.fanout(cluster.nodes, func(node *clusterNode) error {
+		return node.client.Connect(to)
+	})
+}
+
+// Client - client of the named node, nil when unknown
+func (cluster *Cluster) Client(name string) *zmqclient.ZmqClient {
+	if node, ok := cluster.byName[name]; ok {
+		return node.client
+	}
+	return nil
+}
+
+// Pin - place flowId on the named node, whatever the placement policy
+func (cluster *Cluster) Pin(flowId uint32, name string) error {
+	if _, ok := cluster.byName[name]; !ok {
+		return fmt.Errorf("unknown cluster node %s", name)
+	}
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	if node, ok := cluster.flows[flowId]; ok && node.Name != name {
+		return fmt.Errorf("flow %d already placed on %s", flowId, node.Name)
+	}
+	cluster.pins[flowId] = name
+	return nil
+}
+
+// Place - node owning flowId, chosen by the placement policy on first use
+func (cluster *Cluster) Place(flowId uint32) (string, error) {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	node, err := cluster.place(flowId)
+	if err != nil {
+		return "", err
+	}
+	return node.Name, nil
+}
+
+// Flows - flow ids per node name
+func (cluster *Cluster) Flows() map[string][]uint32 {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	flows := make(map[string][]uint32)
+	for flowId, node := range cluster.flows {
+		flows[node.Name] = append(flows[node.Name], flowId)
+	}
+	for _, ids := range flows {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	return flows
+}
+
+// Start - START flowId on its node and subscribe to the node metrics
+func (cluster *Cluster) Start(ctx context.Context, flowId uint32, metricsInterval uint32) error {
+	node, err := cluster.node(flowId, true)
+	if err != nil {
+		return err
+	}
+	return cluster.start(ctx, node, flowId, metricsInterval)
+}
+
+// Stop - STOP flowId on its node
+func (cluster *Cluster) Stop(ctx context.Context, flowId uint32) error {
+	node, err := cluster.node(flowId, false)
+	if err != nil {
+		return err
+	}
+	return stop(ctx, node, flowId)
+}
+
+// AddTunnels - ADD_TUNNELS on the node owning flowId
+func (cluster *Cluster) AddTunnels(ctx context.Context, flowId uint32, tunnels []zmqencdec.Tunnel) error {
+	node, err := cluster.node(flowId, true)
+	if err != nil {
+		return err
+	}
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_ADD_TUNNELS},
+		AddTunnelRequest: zmqencdec.MsgAddTunnelsRequest{
+			FlowId:  flowId,
+			Tunnels: tunnels,
+		},
+	}
+	_, err = request(ctx, node, msg)
+	return err
+}
+
+// DelTunnels - DEL_TUNNELS on the node owning flowId
+func (cluster *Cluster) DelTunnels(ctx context.Context, flowId uint32, teids []uint32) error {
+	node, err := cluster.node(flowId, false)
+	if err != nil {
+		return err
+	}
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_DEL_TUNNELS},
+		DelTunnelsRequest: zmqencdec.MsgDelTunnelsRequest{
+			FlowId: flowId,
+			Teids:  teids,
+		},
+	}
+	_, err = request(ctx, node, msg)
+	return err
+}
+
+// StartAll - place and START every flow, nodes are driven in parallel
+func (cluster *Cluster) StartAll(ctx context.Context, flows []uint32, metricsInterval uint32) error {
+	placed, err := cluster.group(flows, true)
+	if err != nil {
+		return err
+	}
+	return cluster.fanoutFlows(placed, func(node *clusterNode, flowId uint32) error {
+		return cluster.start(ctx, node, flowId, metricsInterval)
+	})
+}
+
+// StopAll - STOP every placed flow on every node
+func (cluster *Cluster) StopAll(ctx context.Context) error {
+	return cluster.fanoutFlows(cluster.placed(), func(node *clusterNode, flowId uint32) error {
+		return stop(ctx, node, flowId)
+	})
+}
+
+// DelAll - DEL_ALL_TUNNELS for every placed flow on every node
+func (cluster *Cluster) DelAll(ctx context.Context) error {
+	return cluster.fanoutFlows(cluster.placed(), func(node *clusterNode, flowId uint32) error {
+		msg := &zmqencdec.Message{
+			Header:               zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS},
+			DelAllTunnelsRequest: zmqencdec.MsgDelAllTunnelsRequest{FlowId: flowId},
+		}
+		_, err := request(ctx, node, msg)
+		return err
+	})
+}
+
+// Metrics - merged metrics of all nodes
+func (cluster *Cluster) Metrics() *MetricsView {
+	return cluster.view
+}
+
+// Close - close every node client
+func (cluster *Cluster) Close() error {
+	return cluster.fanout(cluster.nodes, func(node *clusterNode) error {
+		return node.client.Close()
+	})
+}
+
+// NodeErrors - failures of a fan-out, by node name
+type NodeErrors map[string]error
+
+func (errs NodeErrors) Error() string {
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var msgs []string
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", name, errs[name]))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ///////////////////////////////////////////////////////////
+// Local API
+// ///////////////////////////////////////////////////////////
+// node - node owning flowId, placing it when allowed
+func (cluster *Cluster) node(flowId uint32, place bool) (*clusterNode, error) {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	if place {
+		return cluster.place(flowId)
+	}
+	node, ok := cluster.flows[flowId]
+	if !ok {
+		return nil, fmt.Errorf("flow %d is not placed", flowId)
+	}
+	return node, nil
+}
+
+// place - caller holds mu
+func (cluster *Cluster) place(flowId uint32) (*clusterNode, error) {
+	if node, ok := cluster.flows[flowId]; ok {
+		return node, nil
+	}
+
+	var node *clusterNode
+	if name, ok := cluster.pins[flowId]; ok {
+		node = cluster.byName[name]
+	} else {
+		switch cluster.placement {
+		case PLACEMENT_ROUND_ROBIN:
+			node = cluster.nodes[cluster.next%len(cluster.nodes)]
+			cluster.next++
+		case PLACEMENT_WEIGHTED:
+			node = cluster.weighted()
+		case PLACEMENT_PINNED:
+			return nil, fmt.Errorf("flow %d is not pinned to a node", flowId)
+		default:
+			return nil, fmt.Errorf("unknown placement %d", cluster.placement)
+		}
+	}
+	cluster.flows[flowId] = node
+	cluster.log().Info("flow placed", "flow_id", flowId, "node", node.Name)
+	return node, nil
+}
+
+// weighted - smooth weighted round-robin: every node gains its weight, the
+// richest one is picked and pays the total
+func (cluster *Cluster) weighted() *clusterNode {
+	var best *clusterNode
+	total := 0
+	for _, node := range cluster.nodes {
+		node.current += node.Weight
+		total += node.Weight
+		if best == nil || node.current > best.current {
+			best = node
+		}
+	}
+	best.current -= total
+	return best
+}
+
+// group - flows per node, in the order given
+func (cluster *Cluster) group(flows []uint32, place bool) (map[*clusterNode][]uint32, error) {
+	placed := make(map[*clusterNode][]uint32)
+	for _, flowId := range flows {
+		node, err := cluster.node(flowId, place)
+		if err != nil {
+			return nil, err
+		}
+		placed[node] = append(placed[node], flowId)
+	}
+	return placed, nil
+}
+
+func (cluster *Cluster) placed() map[*clusterNode][]uint32 {
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	placed := make(map[*clusterNode][]uint32)
+	for flowId, node := range cluster.flows {
+		placed[node] = append(placed[node], flowId)
+	}
+	for _, flows := range placed {
+		sort.Slice(flows, func(i, j int) bool { return flows[i] < flows[j] })
+	}
+	return placed
+}
+
+// fanout - run fn on every node concurrently
+func (cluster *Cluster) fanout(nodes []*clusterNode, fn func(node *clusterNode) error) error {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(NodeErrors)
+	for _, node := range nodes {
+		wg.Add(1)
+		go func(node *clusterNode) {
+			defer wg.Done()
+			if err := fn(node); err != nil {
+				mu.Lock()
+				errs[node.Name] = err
+				mu.Unlock()
+			}
+		}(node)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// fanoutFlows - run fn for every flow, one goroutine per node and flows in
+// order on each node; the first failure of a node stops its remaining flows
+func (cluster *Cluster) fanoutFlows(placed map[*clusterNode][]uint32, fn func(node *clusterNode, flowId uint32) error) error {
+	nodes := make([]*clusterNode, 0, len(placed))
+	for node := range placed {
+		nodes = append(nodes, node)
+	}
+	return cluster.fanout(nodes, func(node *clusterNode) error {
+		for _, flowId := range placed[node] {
+			if err := fn(node, flowId); err != nil {
+				return fmt.Errorf("flow %d: %w", flowId, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (cluster *Cluster) start(ctx context.Context, node *clusterNode, flowId uint32, metricsInterval uint32) error {
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_START},
+		StartRequest: zmqencdec.MsgStartRequest{
+			FlowId:          flowId,
+			MetricsInterval: metricsInterval,
+		},
+	}
+	response, err := request(ctx, node, msg)
+	if err != nil {
+		return err
+	}
+
+	// every flow of a node publishes on the same socket; the publisher is
+	// claimed under the lock and dialed outside of it
+	publisher := response.StartResponse.Publisher
+	cluster.mu.Lock()
+	if publisher == "" || publisher == node.publisher {
+		cluster.mu.Unlock()
+		return nil
+	}
+	if node.publisher != "" {
+		from := node.publisher
+		cluster.mu.Unlock()
+		cluster.log().Warn("node moved its metrics publisher", "node", node.Name, "from", from, "to", publisher)
+		return nil
+	}
+	node.publisher = publisher
+	cluster.mu.Unlock()
+
+	if err := node.client.ConnectMetrics(publisher); err != nil {
+		cluster.mu.Lock()
+		if node.publisher == publisher {
+			node.publisher = ""
+		}
+		cluster.mu.Unlock()
+		return fmt.Errorf("node %s metrics failed. Error: %w", node.Name, err)
+	}
+	return nil
+}
+
+func stop(ctx context.Context, node *clusterNode, flowId uint32) error {
+	msg := &zmqencdec.Message{
+		Header:      zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_STOP},
+		StopRequest: zmqencdec.MsgStopRequest{FlowId: flowId},
+	}
+	_, err := request(ctx, node, msg)
+	return err
+}
+
+// request - send msg to node, dfxp error responses come back as *zmqencdec.DfxpError
+func request(ctx context.Context, node *clusterNode, msg *zmqencdec.Message) (*zmqencdec.Message, error) {
+	command := msg.Header.Command
+	response, err := node.client.Request(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	if response.Header.Command != command {
+		return nil, fmt.Errorf("%s failed. Error: unexpected %s response", command, response.Header.Command)
+	}
+	return response, nil
+}
+
+func (cluster *Cluster) log() zmqlog.Logger {
+	return zmqlog.Or(cluster.logger)
+}
+
+func (cluster *Cluster) metricsHandler(name string) zmqclient.MetricsHandler {
+	return func(ctx context.Context, msg *zmqencdec.Message) error {
+		if msg.Header.Command != zmqencdec.ZMQ_CMD_METRICS {
+			return fmt.Errorf("node %s published %s", name, msg.Header.Command)
+		}
+		cluster.view.Update(name, &msg.Metrics)
+		return nil
+	}
+}
@@ -0,0 +1,281 @@
+package zmqcluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"zmqclient/zmqclient"
+	"zmqclient/zmqencdec"
+
+	"github.com/go-zeromq/zmq4"
+	"gotest.tools/assert"
+)
+
+// fakeNode - in process dfxp node with control and metrics sockets
+type fakeNode struct {
+	name      string
+	control   zmq4.Socket
+	publisher zmq4.Socket
+	endpoint  string
+	metrics   string
+
+	mu       sync.Mutex
+	commands []string
+}
+
+func startFakeNode(t *testing.T, name string) *fakeNode {
+	node := &fakeNode{
+		name:      name,
+		control:   zmq4.NewRep(context.Background()),
+		publisher: zmq4.NewPub(context.Background()),
+		endpoint:  "inproc://cluster-" + t.Name() + "-" + name,
+		metrics:   "inproc://cluster-" + t.Name() + "-" + name + "-metrics",
+	}
+	if err := node.control.Listen(node.endpoint); err != nil {
+		t.Fatalf("Listen failed. Err:%v", err)
+	}
+	if err := node.publisher.Listen(node.metrics); err != nil {
+		t.Fatalf("Listen failed. Err:%v", err)
+	}
+	t.Cleanup(func() {
+		node.control.Close()
+		node.publisher.Close()
+	})
+
+	go func() {
+		for {
+			msg, err := node.control.Recv()
+			if err != nil {
+				return
+			}
+			if err := node.control.Send(zmq4.NewMsg(node.handle(msg.Bytes()))); err != nil {
+				return
+			}
+		}
+	}()
+	return node
+}
+
+func (node *fakeNode) handle(request []byte) []byte {
+	command := zmqencdec.ZmqMessageType(binary.BigEndian.Uint16(request[2:]))
+	flowId := binary.BigEndian.Uint32(request[4:])
+
+	node.mu.Lock()
+	node.commands = append(node.commands, fmt.Sprintf("%s %d", command, flowId))
+	node.mu.Unlock()
+
+	switch command {
+	case zmqencdec.ZMQ_CMD_START:
+		return frame(command, flowId, []byte(node.metrics))
+	case zmqencdec.ZMQ_CMD_ADD_TUNNELS, zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS:
+		if flowId == 666 {
+			return frame(zmqencdec.ZMQ_CMD_MSG_ERROR, flowId, []byte("no such flow"))
+		}
+		return frame(command, flowId, uint32(0))
+	default:
+		return frame(command, flowId)
+	}
+}
+
+func (node *fakeNode) received() []string {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	return append([]string{}, node.commands...)
+}
+
+func (node *fakeNode) publish(flowId uint32, protocol uint32, pktRx uint64) error {
+	record := zmqencdec.ProtocolMetrics{
+		Length:   74,
+		Command:  uint16(zmqencdec.ZMQ_CMD_METRICS),
+		FlowId:   flowId,
+		Protocol: protocol,
+		Metric:   zmqencdec.Metric{PktRx: pktRx, PktTx: pktRx * 2},
+	}
+	return node.publisher.Send(zmq4.NewMsg(frame(zmqencdec.ZMQ_CMD_METRICS, flowId, uint32(1), record)))
+}
+
+func (node *fakeNode) cluster() Node {
+	return Node{
+		Name:    node.name,
+		Options: &zmqclient.ClientOptions{Endpoint: node.endpoint, To: 1},
+	}
+}
+
+func frame(command zmqencdec.ZmqMessageType, fields ...interface{}) []byte {
+	payload := new(bytes.Buffer)
+	for _, field := range fields {
+		if data, ok := field.([]byte); ok {
+			payload.Write(data)
+			continue
+		}
+		binary.Write(payload, binary.BigEndian, field)
+	}
+	buffer := new(bytes.Buffer)
+	binary.Write(buffer, binary.BigEndian, uint16(2+payload.Len()))
+	binary.Write(buffer, binary.BigEndian, uint16(command))
+	buffer.Write(payload.Bytes())
+	return buffer.Bytes()
+}
+
+func newTestCluster(t *testing.T, placement Placement, nodes ...Node) *Cluster {
+	cluster, err := NewCluster(nodes, placement)
+	if err != nil {
+		t.Fatalf("NewCluster failed. Err:%v", err)
+	}
+	if err := cluster.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	t.Cleanup(func() {
+		cluster.Close()
+	})
+	return cluster
+}
+
+func TestClusterRoundRobin(t *testing.T) {
+	a := startFakeNode(t, "a")
+	b := startFakeNode(t, "b")
+	cluster := newTestCluster(t, PLACEMENT_ROUND_ROBIN, a.cluster(), b.cluster())
+
+	err := cluster.StartAll(context.Background(), []uint32{1, 2, 3}, 1)
+	if err != nil {
+		t.Fatalf("StartAll failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, map[string][]uint32{"a": {1, 3}, "b": {2}}, cluster.Flows())
+	assert.DeepEqual(t, []string{"START 1", "START 3"}, a.received())
+	assert.DeepEqual(t, []string{"START 2"}, b.received())
+
+	if err := cluster.DelAll(context.Background()); err != nil {
+		t.Fatalf("DelAll failed. Err:%v", err)
+	}
+	if err := cluster.StopAll(context.Background()); err != nil {
+		t.Fatalf("StopAll failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, []string{"START 1", "START 3", "DEL_ALL_TUNNELS 1", "DEL_ALL_TUNNELS 3", "STOP 1", "STOP 3"}, a.received())
+	assert.DeepEqual(t, []string{"START 2", "DEL_ALL_TUNNELS 2", "STOP 2"}, b.received())
+}
+
+func TestClusterWeighted(t *testing.T) {
+	a := startFakeNode(t, "a").cluster()
+	a.Weight = 3
+	b := startFakeNode(t, "b").cluster()
+	cluster, err := NewCluster([]Node{a, b}, PLACEMENT_WEIGHTED)
+	if err != nil {
+		t.Fatalf("NewCluster failed. Err:%v", err)
+	}
+
+	var placed []string
+	for flowId := uint32(1); flowId <= 8; flowId++ {
+		name, err := cluster.Place(flowId)
+		if err != nil {
+			t.Fatalf("Place failed. Err:%v", err)
+		}
+		placed = append(placed, name)
+	}
+	assert.DeepEqual(t, []string{"a", "a", "b", "a", "a", "a", "b", "a"}, placed)
+
+	name, _ := cluster.Place(2)
+	assert.Equal(t, "a", name, "\nA placed flow must stay on its node.")
+}
+
+func TestClusterPinned(t *testing.T) {
+	a := startFakeNode(t, "a")
+	b := startFakeNode(t, "b")
+	cluster := newTestCluster(t, PLACEMENT_PINNED, a.cluster(), b.cluster())
+
+	assert.ErrorContains(t, cluster.Start(context.Background(), 7, 1), "not pinned")
+	assert.ErrorContains(t, cluster.Pin(7, "c"), "unknown cluster node")
+
+	if err := cluster.Pin(7, "b"); err != nil {
+		t.Fatalf("Pin failed. Err:%v", err)
+	}
+	if err := cluster.Start(context.Background(), 7, 1); err != nil {
+		t.Fatalf("Start failed. Err:%v", err)
+	}
+	tunnels := []zmqencdec.Tunnel{{TeidIn: 1, TeidOut: 2, UeIpV4: 0x0a000003, SrvIpV4: 0x0a000004}}
+	if err := cluster.AddTunnels(context.Background(), 7, tunnels); err != nil {
+		t.Fatalf("AddTunnels failed. Err:%v", err)
+	}
+	if err := cluster.Stop(context.Background(), 7); err != nil {
+		t.Fatalf("Stop failed. Err:%v", err)
+	}
+	assert.Equal(t, 0, len(a.received()))
+	assert.DeepEqual(t, []string{"START 7", "ADD_TUNNELS 7", "STOP 7"}, b.received())
+	assert.ErrorContains(t, cluster.Pin(7, "a"), "already placed on b")
+}
+
+func TestClusterNodeErrors(t *testing.T) {
+	a := startFakeNode(t, "a")
+	b := startFakeNode(t, "b")
+	cluster := newTestCluster(t, PLACEMENT_ROUND_ROBIN, a.cluster(), b.cluster())
+
+	cluster.Pin(666, "b")
+	for _, flowId := range []uint32{1, 666} {
+		if _, err := cluster.Place(flowId); err != nil {
+			t.Fatalf("Place failed. Err:%v", err)
+		}
+	}
+
+	err := cluster.DelAll(context.Background())
+	var nodeErrors NodeErrors
+	assert.Assert(t, errors.As(err, &nodeErrors), "unexpected error %v", err)
+	assert.Equal(t, 1, len(nodeErrors))
+	assert.ErrorContains(t, nodeErrors["b"], "no such flow")
+	assert.Equal(t, "b: flow 666: DEL_ALL_TUNNELS failed. Error: no such flow", err.Error())
+}
+
+func TestClusterMetrics(t *testing.T) {
+	a := startFakeNode(t, "a")
+	b := startFakeNode(t, "b")
+	cluster := newTestCluster(t, PLACEMENT_ROUND_ROBIN, a.cluster(), b.cluster())
+
+	if err := cluster.StartAll(context.Background(), []uint32{1, 2}, 1); err != nil {
+		t.Fatalf("StartAll failed. Err:%v", err)
+	}
+
+	// the subscriptions are set up asynchronously, publish until both are seen
+	deadline := time.Now().Add(5 * time.Second)
+	for len(cluster.Metrics().Snapshot()) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("metrics not received. Got:%v", cluster.Metrics().Snapshot())
+		}
+		a.publish(1, zmqencdec.METRICS_PROTOCOL_UDP, 10)
+		b.publish(2, zmqencdec.METRICS_PROTOCOL_TCP, 20)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	flows := cluster.Metrics().Snapshot()
+	assert.Equal(t, FlowKey{Node: "a", FlowId: 1}, flows[0].FlowKey)
+	assert.Equal(t, uint64(10), flows[0].Protocols[zmqencdec.METRICS_PROTOCOL_UDP].PktRx)
+	assert.Equal(t, FlowKey{Node: "b", FlowId: 2}, flows[1].FlowKey)
+	assert.Equal(t, uint64(40), flows[1].Protocols[zmqencdec.METRICS_PROTOCOL_TCP].PktTx)
+
+	totals := cluster.Metrics().NodeTotals()
+	assert.Equal(t, uint64(20), totals["a"].PktTx)
+	assert.Equal(t, uint64(20), totals["b"].PktRx)
+}
+
+func TestNewClusterInvalid(t *testing.T) {
+	_, err := NewCluster(nil, PLACEMENT_ROUND_ROBIN)
+	assert.ErrorContains(t, err, "at least one node")
+
+	node := Node{Name: "a", Options: &zmqclient.ClientOptions{}}
+	_, err = NewCluster([]Node{node, node}, PLACEMENT_ROUND_ROBIN)
+	assert.ErrorContains(t, err, "duplicate cluster node a")
+}
+
+func TestParsePlacement(t *testing.T) {
+	for _, placement := range []Placement{PLACEMENT_ROUND_ROBIN, PLACEMENT_WEIGHTED, PLACEMENT_PINNED} {
+		parsed, err := ParsePlacement(placement.String())
+		if err != nil {
+			t.Fatalf("ParsePlacement failed. Err:%v", err)
+		}
+		assert.Equal(t, placement, parsed)
+	}
+	_, err := ParsePlacement("random")
+	assert.ErrorContains(t, err, "unknown placement")
+}
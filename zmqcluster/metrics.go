@@ -0,0 +1,131 @@
+package zmqcluster
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"zmqclient/zmqencdec"
+)
+
+// FlowKey - a flow of a node
+type FlowKey struct {
+	Node   string
+	FlowId uint32
+}
+
+// FlowMetrics - latest counters of a flow, by ProtocolMetrics.Protocol
+type FlowMetrics struct {
+	FlowKey
+	Protocols map[uint32]zmqencdec.Metric
+	Updated   time.Time
+}
+
+// Total - sum of the counters of all protocols
+func (flow *FlowMetrics) Total() zmqencdec.Metric {
+	var total zmqencdec.Metric
+	for _, metric := range flow.Protocols {
+		total = addMetric(total, metric)
+	}
+	return total
+}
+
+// MetricsView - merged metrics streams of the cluster nodes keyed by node and flow
+type MetricsView struct {
+	mu    sync.Mutex
+	flows map[FlowKey]*FlowMetrics
+}
+
+func NewMetricsView() *MetricsView {
+	return &MetricsView{
+		flows: make(map[FlowKey]*FlowMetrics),
+	}
+}
+
+// Update - record a METRICS publish of node
+func (view *MetricsView) Update(node string, metrics *zmqencdec.MsgMetrics) {
+	now := time.Now()
+	view.mu.Lock()
+	defer view.mu.Unlock()
+
+	for _, protocol := range metrics.Metrics {
+		flowId := protocol.FlowId
+		if flowId == 0 {
+			flowId = metrics.FlowId
+		}
+		key := FlowKey{Node: node, FlowId: flowId}
+		flow, ok := view.flows[key]
+		if !ok {
+			flow = &FlowMetrics{
+				FlowKey:   key,
+				Protocols: make(map[uint32]zmqencdec.Metric),
+			}
+			view.flows[key] = flow
+		}
+		flow.Protocols[protocol.Protocol] = protocol.Metric
+		flow.Updated = now
+	}
+}
+
+// Flow - copy of the metrics of one flow
+func (view *MetricsView) Flow(node string, flowId uint32) (FlowMetrics, bool) {
+	view.mu.Lock()
+	defer view.mu.Unlock()
+	flow, ok := view.flows[FlowKey{Node: node, FlowId: flowId}]
+	if !ok {
+		return FlowMetrics{}, false
+	}
+	return flow.copy(), true
+}
+
+// Snapshot - copy of all flows ordered by node and flow id
+func (view *MetricsView) Snapshot() []FlowMetrics {
+	view.mu.Lock()
+	defer view.mu.Unlock()
+	flows := make([]FlowMetrics, 0, len(view.flows))
+	for _, flow := range view.flows {
+		flows = append(flows, flow.copy())
+	}
+	sort.Slice(flows, func(i, j int) bool {
+		if flows[i].Node != flows[j].Node {
+			return flows[i].Node < flows[j].Node
+		}
+		return flows[i].FlowId < flows[j].FlowId
+	})
+	return flows
+}
+
+// NodeTotals - sum of the counters of all flows per node
+func (view *MetricsView) NodeTotals() map[string]zmqencdec.Metric {
+	view.mu.Lock()
+	defer view.mu.Unlock()
+	totals := make(map[string]zmqencdec.Metric)
+	for key, flow := range view.flows {
+		totals[key.Node] = addMetric(totals[key.Node], flow.Total())
+	}
+	return totals
+}
+
+func (flow *FlowMetrics) copy() FlowMetrics {
+	protocols := make(map[uint32]zmqencdec.Metric, len(flow.Protocols))
+	for protocol, metric := range flow.Protocols {
+		protocols[protocol] = metric
+	}
+	return FlowMetrics{
+		FlowKey:   flow.FlowKey,
+		Protocols: protocols,
+		Updated:   flow.Updated,
+	}
+}
+
+func addMetric(a, b zmqencdec.Metric) zmqencdec.Metric {
+	return zmqencdec.Metric{
+		PktRx:  a.PktRx + b.PktRx,
+		PktTx:  a.PktTx + b.PktTx,
+		ByteRx: a.ByteRx + b.ByteRx,
+		ByteTx: a.ByteTx + b.ByteTx,
+		BpsRx:  a.BpsRx + b.BpsRx,
+		BpsTx:  a.BpsTx + b.BpsTx,
+		ErrRx:  a.ErrRx + b.ErrRx,
+		ErrTx:  a.ErrTx + b.ErrTx,
+	}
+}
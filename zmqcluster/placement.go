@@ -0,0 +1,38 @@
+package zmqcluster
+
+import "fmt"
+
+// Placement - how flows are spread across the cluster nodes.
+// Pinned flows always go to their node.
+type Placement int
+
+const (
+	// PLACEMENT_ROUND_ROBIN - one flow per node in turn
+	PLACEMENT_ROUND_ROBIN Placement = iota
+	// PLACEMENT_WEIGHTED - flows in proportion of Node.Weight
+	PLACEMENT_WEIGHTED
+	// PLACEMENT_PINNED - only flows given to Cluster.Pin are accepted
+	PLACEMENT_PINNED
+)
+
+func (placement Placement) String() string {
+	switch placement {
+	case PLACEMENT_ROUND_ROBIN:
+		return "round-robin"
+	case PLACEMENT_WEIGHTED:
+		return "weighted"
+	case PLACEMENT_PINNED:
+		return "pinned"
+	}
+	return fmt.Sprintf("Placement(%d)", int(placement))
+}
+
+// ParsePlacement - "round-robin", "weighted" or "pinned"
+func ParsePlacement(s string) (Placement, error) {
+	for _, placement := range []Placement{PLACEMENT_ROUND_ROBIN, PLACEMENT_WEIGHTED, PLACEMENT_PINNED} {
+		if placement.String() == s {
+			return placement, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown placement %q", s)
+}
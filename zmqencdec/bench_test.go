@@ -0,0 +1,112 @@
+package zmqencdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func addTunnelsMessage(tunnels int) *Message {
+	msg := &Message{
+		Header:           MsgHeader{Command: ZMQ_CMD_ADD_TUNNELS},
+		AddTunnelRequest: MsgAddTunnelsRequest{FlowId: 1233},
+	}
+	for i := 0; i < tunnels; i++ {
+		msg.AddTunnelRequest.Tunnels = append(msg.AddTunnelRequest.Tunnels, Tunnel{
+			TeidIn:  uint32(1000 + i),
+			TeidOut: uint32(2000 + i),
+			UeIpV4:  0x0a000000 + uint32(i),
+			SrvIpV4: 0xc0a80001,
+		})
+	}
+	msg.Header.Length, _ = RequestLength(msg)
+	return msg
+}
+
+// encodeAddTunnelsReflect - the bytes.Buffer and binary.Write encoding
+// AppendEncode replaced, kept as benchmark reference
+func encodeAddTunnelsReflect(msg *Message) []byte {
+	buffer := new(bytes.Buffer)
+	binary.Write(buffer, binary.BigEndian, msg.Header.Length)
+	binary.Write(buffer, binary.BigEndian, msg.Header.Command)
+	binary.Write(buffer, binary.BigEndian, msg.AddTunnelRequest.FlowId)
+	binary.Write(buffer, binary.BigEndian, uint32(len(msg.AddTunnelRequest.Tunnels)))
+	for _, tunnel := range msg.AddTunnelRequest.Tunnels {
+		binary.Write(buffer, binary.BigEndian, tunnel.TeidIn)
+		binary.Write(buffer, binary.BigEndian, tunnel.TeidOut)
+		binary.Write(buffer, binary.BigEndian, tunnel.UeIpV4)
+		binary.Write(buffer, binary.BigEndian, tunnel.SrvIpV4)
+	}
+	return buffer.Bytes()
+}
+
+func TestAppendEncode(t *testing.T) {
+	msg := addTunnelsMessage(3)
+	encoder := &ZmqEncoder{}
+
+	prefix := []byte{0xff}
+	frame, err := encoder.AppendEncode(prefix, msg)
+	if err != nil {
+		t.Fatalf("AppendEncode failed. Err:%v", err)
+	}
+	assert.Equal(t, hex.EncodeToString(encodeAddTunnelsReflect(msg)), hex.EncodeToString(frame[1:]), "\nThe two array should be the same.")
+	assert.Equal(t, byte(0xff), frame[0])
+
+	_, err = encoder.AppendEncode(nil, &Message{Header: MsgHeader{Command: ZMQ_CMD_METRICS}})
+	assert.ErrorContains(t, err, "Wrong message command")
+	_, err = encoder.AppendEncode(nil, nil)
+	assert.ErrorContains(t, err, "Message nil")
+}
+
+func TestAppendEncodeAllocs(t *testing.T) {
+	msg := addTunnelsMessage(1000)
+	encoder := &ZmqEncoder{}
+	buffer := make([]byte, 0, 64*1024)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buffer, _ = encoder.AppendEncode(buffer[:0], msg)
+	})
+	assert.Equal(t, float64(0), allocs, "\nAppendEncode must not allocate when dst has room.")
+}
+
+func BenchmarkEncodeAddTunnels(b *testing.B) {
+	for _, tunnels := range []int{1, 100, 4000} {
+		msg := addTunnelsMessage(tunnels)
+		encoder := &ZmqEncoder{}
+
+		b.Run(fmt.Sprintf("reflect/%d", tunnels), func(b *testing.B) {
+			benchmarkPer(b, tunnels, "tunnel", func() {
+				encodeAddTunnelsReflect(msg)
+			})
+		})
+		b.Run(fmt.Sprintf("Encode/%d", tunnels), func(b *testing.B) {
+			benchmarkPer(b, tunnels, "tunnel", func() {
+				encoder.Encode(msg)
+			})
+		})
+		b.Run(fmt.Sprintf("AppendEncode/%d", tunnels), func(b *testing.B) {
+			buffer := make([]byte, 0, 64*1024)
+			benchmarkPer(b, tunnels, "tunnel", func() {
+				buffer, _ = encoder.AppendEncode(buffer[:0], msg)
+			})
+		})
+	}
+}
+
+// benchmarkPer - run fn b.N times and report the cost per unit, fn handling
+// n of them (tunnels, records)
+func benchmarkPer(b *testing.B, n int, unit string, fn func()) {
+	b.ReportAllocs()
+	allocs := testing.AllocsPerRun(10, fn)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fn()
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/"+unit)
+	b.ReportMetric(allocs/float64(n), "allocs/"+unit)
+}
@@ -0,0 +1,28 @@
+package zmqencdec
+
+// Codec - wire form of Messages: requests are encoded, responses and metrics
+// decoded. ZmqEncoder, the dfxp big endian layout, is the default codec.
+// Frames of every codec start with the dfxp header, u16 Length and u16
+// Command, big endian.
+type Codec interface {
+	// Encode - the request frame of msg
+	Encode(msg *Message) ([]byte, error)
+	// AppendEncode - append the request frame of msg to dst
+	AppendEncode(dst []byte, msg *Message) ([]byte, error)
+	// Decode - the Message of a response or metrics frame
+	Decode(frame []byte) (*Message, error)
+	// RequestLength - Header.Length to encode in the request frame of msg,
+	// 0 when the codec writes the length of each frame itself
+	RequestLength(msg *Message) (uint16, error)
+}
+
+// MetricsDecoder - optional Codec fast path for the METRICS publishes, used
+// instead of Decode when the codec has one
+type MetricsDecoder interface {
+	DecodeMetrics(frame []byte) (*Message, error)
+}
+
+var (
+	_ Codec          = (*ZmqEncoder)(nil)
+	_ MetricsDecoder = (*ZmqEncoder)(nil)
+)
@@ -1,202 +1,120 @@
 package zmqencdec
 
 import (
-	"bytes"
-	"encoding/binary"
 	"fmt"
-
-	"encoding/hex"
-
-	"github.com/golang/glog"
+	"zmqclient/zmqlog"
 )
 
 type ZmqEncoder struct {
+	// Logger - nil for zmqlog.Default(); frames are dumped at debug level only
+	Logger zmqlog.Logger
 }
 
 // Encode - encode Messages
 func (enc *ZmqEncoder) Encode(msg *Message) ([]byte, error) {
-	if msg == nil {
-		return nil, fmt.Errorf("Message nil")
+	frame, err := enc.AppendEncode(nil, msg)
+	if err != nil {
+		return nil, err
 	}
-	glog.Infof("Encode ZMQ message:%v", msg)
-
-	switch msg.Header.Command {
-	case (ZMQ_CMD_START):
-		return enc.encodeStartRequest(msg)
-	case ZMQ_CMD_STOP:
-		return enc.encodeStopRequest(msg)
-	case ZMQ_CMD_ADD_TUNNELS:
-		return enc.encodeAddTunnelRequest(msg)
-	case ZMQ_CMD_DEL_TUNNELS:
-		return enc.encodeDelTunnelRequest(msg)
-	case ZMQ_CMD_DEL_ALL_TUNNELS:
-		return enc.encodeDelAllTunnelsRequest(msg)
-	case ZMQ_CMD_GET_INFO:
-		return enc.encodeGetInfoRequest(msg)
-
-	default:
-		return nil, fmt.Errorf("Wrong message command [%d]", msg.Header.Command)
+	zmqlog.Or(enc.Logger).Debug("encode dfxp message",
+		"command", msg.Header.Command,
+		"flow_id", msg.RequestFlowId(),
+		"length", msg.Header.Length,
+		"frame", zmqlog.Hex(frame))
+	return frame, nil
+}
+
+// AppendEncode - append the request frame of msg to dst and return the
+// extended slice. dst is grown at most once, so nothing is allocated when it
+// has room for the frame; nothing is logged either.
+func (enc *ZmqEncoder) AppendEncode(dst []byte, msg *Message) ([]byte, error) {
+	if msg == nil {
+		return dst, fmt.Errorf("Message nil")
 	}
+	return enc.appendRequest(dst, msg)
 }
 
-func (enc *ZmqEncoder) encodeStartRequest(msg *Message) ([]byte, error) {
-	buffer := new(bytes.Buffer)
-	binary.Write(buffer, binary.BigEndian, msg.Header.Length)
-	binary.Write(buffer, binary.BigEndian, msg.Header.Command)
-	binary.Write(buffer, binary.BigEndian, msg.StartRequest.FlowId)
-	binary.Write(buffer, binary.BigEndian, msg.StartRequest.MetricsInterval)
-
-	return buffer.Bytes(), nil
-}
-
-func (enc *ZmqEncoder) encodeStopRequest(msg *Message) ([]byte, error) {
-	buffer := new(bytes.Buffer)
-	binary.Write(buffer, binary.BigEndian, msg.Header.Length)
-	binary.Write(buffer, binary.BigEndian, msg.Header.Command)
-	binary.Write(buffer, binary.BigEndian, msg.StopRequest.FlowId)
-
-	return buffer.Bytes(), nil
-}
-
-func (enc *ZmqEncoder) encodeAddTunnelRequest(msg *Message) ([]byte, error) {
-	buffer := new(bytes.Buffer)
-
-	binary.Write(buffer, binary.BigEndian, msg.Header.Length)
-	binary.Write(buffer, binary.BigEndian, msg.Header.Command)
-	binary.Write(buffer, binary.BigEndian, msg.AddTunnelRequest.FlowId)
-	binary.Write(buffer, binary.BigEndian, uint32(len(msg.AddTunnelRequest.Tunnels)))
-	for _, tunnel := range msg.AddTunnelRequest.Tunnels {
-		binary.Write(buffer, binary.BigEndian, tunnel.TeidIn)
-		binary.Write(buffer, binary.BigEndian, tunnel.TeidOut)
-		binary.Write(buffer, binary.BigEndian, tunnel.UeIpV4)
-		binary.Write(buffer, binary.BigEndian, tunnel.SrvIpV4)
+// RequestLength - Header.Length of the request in msg, see RequestLength
+func (enc *ZmqEncoder) RequestLength(msg *Message) (uint16, error) {
+	if msg == nil {
+		return 0, fmt.Errorf("Message nil")
 	}
-
-	return buffer.Bytes(), nil
+	return RequestLength(msg)
 }
 
-func (enc *ZmqEncoder) encodeDelTunnelRequest(msg *Message) ([]byte, error) {
-	buffer := new(bytes.Buffer)
-	binary.Write(buffer, binary.BigEndian, msg.Header.Length)
-	binary.Write(buffer, binary.BigEndian, msg.Header.Command)
-	binary.Write(buffer, binary.BigEndian, msg.DelTunnelsRequest.FlowId)
-	binary.Write(buffer, binary.BigEndian, uint32(len(msg.DelTunnelsRequest.Teids)))
-	for _, teid := range msg.DelTunnelsRequest.Teids {
-		binary.Write(buffer, binary.BigEndian, teid)
+// grow - dst with room for n more bytes
+func grow(dst []byte, n int) []byte {
+	if cap(dst)-len(dst) >= n {
+		return dst
 	}
-
-	return buffer.Bytes(), nil
+	grown := make([]byte, len(dst), len(dst)+n)
+	copy(grown, dst)
+	return grown
 }
 
-func (enc *ZmqEncoder) encodeDelAllTunnelsRequest(msg *Message) ([]byte, error) {
-	buffer := new(bytes.Buffer)
-	binary.Write(buffer, binary.BigEndian, msg.Header.Length)
-	binary.Write(buffer, binary.BigEndian, msg.Header.Command)
-	binary.Write(buffer, binary.BigEndian, msg.DelAllTunnelsRequest.FlowId)
-	binary.Write(buffer, binary.BigEndian, uint32(0))// tunnels number
-
-	return buffer.Bytes(), nil
-}
-
-func (enc *ZmqEncoder) encodeGetInfoRequest(msg *Message) ([]byte, error) {
-	buffer := new(bytes.Buffer)
-	binary.Write(buffer, binary.BigEndian, msg.Header.Length)
-	binary.Write(buffer, binary.BigEndian, msg.Header.Command)
-	binary.Write(buffer, binary.BigEndian, msg.GetInfoRequest.FlowId)
-
-	return buffer.Bytes(), nil
+// EncodeResponse - encode the response section of msg, as dfxp would send it
+func (enc *ZmqEncoder) EncodeResponse(msg *Message) ([]byte, error) {
+	if msg == nil {
+		return nil, fmt.Errorf("Message nil")
+	}
+	return enc.appendResponse(nil, msg)
 }
 
-// Decode - decode Messages
+// Decode - decode Messages. Frames too short for their fields or with bytes
+// left over are an error.
 func (enc *ZmqEncoder) Decode(bytesArray []byte) (*Message, error) {
 
 	if bytesArray == nil {
 		return nil, fmt.Errorf("bytesArray nil")
 	}
-	glog.Infof("Decode ZMQ message:%v", hex.EncodeToString(bytesArray))
-
 	msg := &Message{}
 
-	buffer := bytes.NewBuffer(bytesArray)
-	binary.Read(buffer, binary.BigEndian, &msg.Header.Length)
-	binary.Read(buffer, binary.BigEndian, &msg.Header.Command)
-
-	switch msg.Header.Command {
-	case (ZMQ_CMD_START):
-		return msg, enc.decodeStartResponse(buffer, msg)
-	case ZMQ_CMD_STOP:
-		return msg, enc.decodeStopResponse(buffer, msg)
-	case ZMQ_CMD_ADD_TUNNELS:
-		return msg, enc.decodeAddTunnelResponse(buffer, msg)
-	case ZMQ_CMD_DEL_TUNNELS:
-		return msg, enc.decodeDelTunnelResponse(buffer, msg)
-	case ZMQ_CMD_DEL_ALL_TUNNELS:
-		return msg, enc.decodeDelAllTEIDsResponse(buffer, msg)
-	case ZMQ_CMD_GET_INFO:
-		return msg, enc.decodeGetInfoResponse(buffer, msg)
-	case ZMQ_CMD_ERROR:
-		return msg, enc.decodeErrorResponse(buffer, msg)
-	case ZMQ_CMD_MSG_ERROR:
-		return msg, enc.decodeMsgErrorResponse(buffer, msg)
-	default:
-		return nil, fmt.Errorf("Wrong message command [%d]", msg.Header.Command)
+	r := &frameReader{data: bytesArray}
+	if err := r.header(&msg.Header); err != nil {
+		return nil, err
 	}
-}
-
-func (enc *ZmqEncoder) decodeStartResponse(buffer *bytes.Buffer, msg *Message) error {
-	binary.Read(buffer, binary.BigEndian, &msg.StartResponse.FlowId)
-	//read publisher
-	bytes := buffer.Bytes()
-	glog.Infof("bytes:%s", hex.EncodeToString(bytes))
-	msg.StartResponse.Publisher = string(bytes)
-	return nil
-}
-
-func (enc *ZmqEncoder) decodeStopResponse(buffer *bytes.Buffer, msg *Message) error {
-	binary.Read(buffer, binary.BigEndian, &msg.Response.FlowId)
-	return nil
-}
-func (enc *ZmqEncoder) decodeAddTunnelResponse(buffer *bytes.Buffer, msg *Message) error {
-	binary.Read(buffer, binary.BigEndian, &msg.TunnelResponse.FlowId)
-	binary.Read(buffer, binary.BigEndian, &msg.TunnelResponse.Tunnels)
-	return nil
-}
-func (enc *ZmqEncoder) decodeDelTunnelResponse(buffer *bytes.Buffer, msg *Message) error {
-	binary.Read(buffer, binary.BigEndian, &msg.TunnelResponse.FlowId)
-	binary.Read(buffer, binary.BigEndian, &msg.TunnelResponse.Tunnels)
-	return nil
-}
 
-func (enc *ZmqEncoder) decodeDelAllTEIDsResponse(buffer *bytes.Buffer, msg *Message) error {
-	binary.Read(buffer, binary.BigEndian, &msg.TunnelResponse.FlowId)
-	binary.Read(buffer, binary.BigEndian, &msg.TunnelResponse.Tunnels)
-	return nil
-
-}
-func (enc *ZmqEncoder) decodeGetInfoResponse(buffer *bytes.Buffer, msg *Message) error {
-	binary.Read(buffer, binary.BigEndian, &msg.GetInfoResponse.FlowId)
-	//read Version
-	bytes := buffer.Bytes()
-	glog.Infof("bytes:%s", hex.EncodeToString(bytes))
-	msg.GetInfoResponse.Version = string(bytes)
-	return nil
+	zmqlog.Or(enc.Logger).Debug("decode dfxp message",
+		"command", msg.Header.Command,
+		"length", msg.Header.Length,
+		"frame", zmqlog.Hex(bytesArray))
 
+	if err := enc.decodeResponse(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
 }
 
-func (enc *ZmqEncoder) decodeErrorResponse(buffer *bytes.Buffer, msg *Message) error {
-	bytes := buffer.Bytes()
-	glog.Infof("bytes:%s", hex.EncodeToString(bytes))
+// DecodeRequest - decode the request frames Encode produces, as dfxp would
+func (enc *ZmqEncoder) DecodeRequest(bytesArray []byte) (*Message, error) {
+	if bytesArray == nil {
+		return nil, fmt.Errorf("bytesArray nil")
+	}
+	msg := &Message{}
 
-	//read Error
-	msg.MsgErrorResponse.Error = string(bytes)
-	return nil
+	r := &frameReader{data: bytesArray}
+	if err := r.header(&msg.Header); err != nil {
+		return nil, err
+	}
+	if err := enc.decodeRequest(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
 }
 
-func (enc *ZmqEncoder) decodeMsgErrorResponse(buffer *bytes.Buffer, msg *Message) error {
-	binary.Read(buffer, binary.BigEndian, &msg.MsgErrorResponse.FlowId)
-	//read Error
-	bytes := buffer.Bytes()
-	glog.Infof("bytes:%s", hex.EncodeToString(bytes))
-	msg.MsgErrorResponse.Error = string(bytes)
-	return nil
+// DecodeMetrics - decode a METRICS frame through MetricsView, without the
+// per field reads of Decode
+func (enc *ZmqEncoder) DecodeMetrics(bytesArray []byte) (*Message, error) {
+	view, err := ViewMetrics(bytesArray)
+	if err != nil {
+		return nil, err
+	}
+	msg := &Message{Header: view.Header()}
+	zmqlog.Or(enc.Logger).Debug("decode dfxp message",
+		"command", msg.Header.Command,
+		"length", msg.Header.Length,
+		"frame", zmqlog.Hex(bytesArray))
+	msg.Metrics.Metrics = make([]ProtocolMetrics, 0, view.Len())
+	view.Decode(&msg.Metrics)
+	return msg, nil
 }
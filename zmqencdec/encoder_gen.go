@@ -0,0 +1,515 @@
+// Code generated by zmqgen from dfxp.schema. DO NOT EDIT.
+
+package zmqencdec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// appendRequest - append the request frame of msg to dst, grown once to the frame size
+func (enc *ZmqEncoder) appendRequest(dst []byte, msg *Message) ([]byte, error) {
+	switch msg.Header.Command {
+	case ZMQ_CMD_START:
+		return enc.appendStartRequest(grow(dst, 2+requestStartLength(msg)), msg), nil
+	case ZMQ_CMD_STOP:
+		return enc.appendStopRequest(grow(dst, 2+requestStopLength(msg)), msg), nil
+	case ZMQ_CMD_ADD_TUNNELS:
+		return enc.appendAddTunnelsRequest(grow(dst, 2+requestAddTunnelsLength(msg)), msg), nil
+	case ZMQ_CMD_DEL_TUNNELS:
+		return enc.appendDelTunnelsRequest(grow(dst, 2+requestDelTunnelsLength(msg)), msg), nil
+	case ZMQ_CMD_DEL_ALL_TUNNELS:
+		return enc.appendDelAllTunnelsRequest(grow(dst, 2+requestDelAllTunnelsLength(msg)), msg), nil
+	case ZMQ_CMD_GET_INFO:
+		return enc.appendGetInfoRequest(grow(dst, 2+requestGetInfoLength(msg)), msg), nil
+	default:
+		return nil, fmt.Errorf("Wrong message command [%d]", msg.Header.Command)
+	}
+}
+
+// appendResponse - append the response frame of msg to dst, grown once to the frame size
+func (enc *ZmqEncoder) appendResponse(dst []byte, msg *Message) ([]byte, error) {
+	switch msg.Header.Command {
+	case ZMQ_CMD_START:
+		return enc.appendStartResponse(grow(dst, 2+responseStartLength(msg)), msg), nil
+	case ZMQ_CMD_STOP:
+		return enc.appendStopResponse(grow(dst, 2+responseStopLength(msg)), msg), nil
+	case ZMQ_CMD_ADD_TUNNELS:
+		return enc.appendAddTunnelsResponse(grow(dst, 2+responseAddTunnelsLength(msg)), msg), nil
+	case ZMQ_CMD_DEL_TUNNELS:
+		return enc.appendDelTunnelsResponse(grow(dst, 2+responseDelTunnelsLength(msg)), msg), nil
+	case ZMQ_CMD_DEL_ALL_TUNNELS:
+		return enc.appendDelAllTunnelsResponse(grow(dst, 2+responseDelAllTunnelsLength(msg)), msg), nil
+	case ZMQ_CMD_GET_INFO:
+		return enc.appendGetInfoResponse(grow(dst, 2+responseGetInfoLength(msg)), msg), nil
+	case ZMQ_CMD_ERROR:
+		return enc.appendErrorResponse(grow(dst, 2+responseErrorLength(msg)), msg), nil
+	case ZMQ_CMD_MSG_ERROR:
+		return enc.appendMsgErrorResponse(grow(dst, 2+responseMsgErrorLength(msg)), msg), nil
+	case ZMQ_CMD_METRICS:
+		return enc.appendMetricsResponse(grow(dst, 2+responseMetricsLength(msg)), msg), nil
+	default:
+		return nil, fmt.Errorf("Wrong message command [%d]", msg.Header.Command)
+	}
+}
+
+func (enc *ZmqEncoder) decodeRequest(r *frameReader, msg *Message) error {
+	switch msg.Header.Command {
+	case ZMQ_CMD_START:
+		enc.decodeStartRequest(r, msg)
+	case ZMQ_CMD_STOP:
+		enc.decodeStopRequest(r, msg)
+	case ZMQ_CMD_ADD_TUNNELS:
+		enc.decodeAddTunnelsRequest(r, msg)
+	case ZMQ_CMD_DEL_TUNNELS:
+		enc.decodeDelTunnelsRequest(r, msg)
+	case ZMQ_CMD_DEL_ALL_TUNNELS:
+		enc.decodeDelAllTunnelsRequest(r, msg)
+	case ZMQ_CMD_GET_INFO:
+		enc.decodeGetInfoRequest(r, msg)
+	default:
+		return fmt.Errorf("Wrong message command [%d]", msg.Header.Command)
+	}
+	return r.end()
+}
+
+func (enc *ZmqEncoder) decodeResponse(r *frameReader, msg *Message) error {
+	switch msg.Header.Command {
+	case ZMQ_CMD_START:
+		enc.decodeStartResponse(r, msg)
+	case ZMQ_CMD_STOP:
+		enc.decodeStopResponse(r, msg)
+	case ZMQ_CMD_ADD_TUNNELS:
+		enc.decodeAddTunnelsResponse(r, msg)
+	case ZMQ_CMD_DEL_TUNNELS:
+		enc.decodeDelTunnelsResponse(r, msg)
+	case ZMQ_CMD_DEL_ALL_TUNNELS:
+		enc.decodeDelAllTunnelsResponse(r, msg)
+	case ZMQ_CMD_GET_INFO:
+		enc.decodeGetInfoResponse(r, msg)
+	case ZMQ_CMD_ERROR:
+		enc.decodeErrorResponse(r, msg)
+	case ZMQ_CMD_MSG_ERROR:
+		enc.decodeMsgErrorResponse(r, msg)
+	case ZMQ_CMD_METRICS:
+		enc.decodeMetricsResponse(r, msg)
+	default:
+		return fmt.Errorf("Wrong message command [%d]", msg.Header.Command)
+	}
+	return r.end()
+}
+
+// RequestLength - Header.Length of the request in msg: command size plus payload size
+func RequestLength(msg *Message) (uint16, error) {
+	var l int
+	switch msg.Header.Command {
+	case ZMQ_CMD_START:
+		l = requestStartLength(msg)
+	case ZMQ_CMD_STOP:
+		l = requestStopLength(msg)
+	case ZMQ_CMD_ADD_TUNNELS:
+		l = requestAddTunnelsLength(msg)
+	case ZMQ_CMD_DEL_TUNNELS:
+		l = requestDelTunnelsLength(msg)
+	case ZMQ_CMD_DEL_ALL_TUNNELS:
+		l = requestDelAllTunnelsLength(msg)
+	case ZMQ_CMD_GET_INFO:
+		l = requestGetInfoLength(msg)
+	default:
+		return 0, fmt.Errorf("Wrong message command [%d]", msg.Header.Command)
+	}
+	if l > math.MaxUint16 {
+		return 0, fmt.Errorf("message too long [%d]", l)
+	}
+	return uint16(l), nil
+}
+
+// ResponseLength - Header.Length of the response in msg: command size plus payload size
+func ResponseLength(msg *Message) (uint16, error) {
+	var l int
+	switch msg.Header.Command {
+	case ZMQ_CMD_START:
+		l = responseStartLength(msg)
+	case ZMQ_CMD_STOP:
+		l = responseStopLength(msg)
+	case ZMQ_CMD_ADD_TUNNELS:
+		l = responseAddTunnelsLength(msg)
+	case ZMQ_CMD_DEL_TUNNELS:
+		l = responseDelTunnelsLength(msg)
+	case ZMQ_CMD_DEL_ALL_TUNNELS:
+		l = responseDelAllTunnelsLength(msg)
+	case ZMQ_CMD_GET_INFO:
+		l = responseGetInfoLength(msg)
+	case ZMQ_CMD_ERROR:
+		l = responseErrorLength(msg)
+	case ZMQ_CMD_MSG_ERROR:
+		l = responseMsgErrorLength(msg)
+	case ZMQ_CMD_METRICS:
+		l = responseMetricsLength(msg)
+	default:
+		return 0, fmt.Errorf("Wrong message command [%d]", msg.Header.Command)
+	}
+	if l > math.MaxUint16 {
+		return 0, fmt.Errorf("message too long [%d]", l)
+	}
+	return uint16(l), nil
+}
+
+func (enc *ZmqEncoder) appendStartRequest(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.StartRequest.FlowId)
+	dst = binary.BigEndian.AppendUint32(dst, msg.StartRequest.MetricsInterval)
+
+	return dst
+}
+
+func requestStartLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	l += 4 // MetricsInterval
+	return l
+}
+
+func (enc *ZmqEncoder) decodeStartRequest(r *frameReader, msg *Message) {
+	msg.StartRequest.FlowId = r.uint32("FlowId")
+	msg.StartRequest.MetricsInterval = r.uint32("MetricsInterval")
+}
+
+func (enc *ZmqEncoder) appendStopRequest(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.StopRequest.FlowId)
+
+	return dst
+}
+
+func requestStopLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	return l
+}
+
+func (enc *ZmqEncoder) decodeStopRequest(r *frameReader, msg *Message) {
+	msg.StopRequest.FlowId = r.uint32("FlowId")
+}
+
+func (enc *ZmqEncoder) appendAddTunnelsRequest(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.AddTunnelRequest.FlowId)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(msg.AddTunnelRequest.Tunnels)))
+	for _, tunnel := range msg.AddTunnelRequest.Tunnels {
+		dst = binary.BigEndian.AppendUint32(dst, tunnel.TeidIn)
+		dst = binary.BigEndian.AppendUint32(dst, tunnel.TeidOut)
+		dst = binary.BigEndian.AppendUint32(dst, tunnel.UeIpV4)
+		dst = binary.BigEndian.AppendUint32(dst, tunnel.SrvIpV4)
+	}
+
+	return dst
+}
+
+func requestAddTunnelsLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	l += 4 // Tunnels number
+	l += 16 * len(msg.AddTunnelRequest.Tunnels)
+	return l
+}
+
+func (enc *ZmqEncoder) decodeAddTunnelsRequest(r *frameReader, msg *Message) {
+	msg.AddTunnelRequest.FlowId = r.uint32("FlowId")
+	if n := r.count("Tunnels", 16); n > 0 {
+		msg.AddTunnelRequest.Tunnels = make([]Tunnel, n)
+		for i := range msg.AddTunnelRequest.Tunnels {
+			msg.AddTunnelRequest.Tunnels[i].TeidIn = r.uint32("TeidIn")
+			msg.AddTunnelRequest.Tunnels[i].TeidOut = r.uint32("TeidOut")
+			msg.AddTunnelRequest.Tunnels[i].UeIpV4 = r.uint32("UeIpV4")
+			msg.AddTunnelRequest.Tunnels[i].SrvIpV4 = r.uint32("SrvIpV4")
+		}
+	}
+}
+
+func (enc *ZmqEncoder) appendDelTunnelsRequest(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.DelTunnelsRequest.FlowId)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(msg.DelTunnelsRequest.Teids)))
+	for _, teid := range msg.DelTunnelsRequest.Teids {
+		dst = binary.BigEndian.AppendUint32(dst, teid)
+	}
+
+	return dst
+}
+
+func requestDelTunnelsLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	l += 4 // Teids number
+	l += 4 * len(msg.DelTunnelsRequest.Teids)
+	return l
+}
+
+func (enc *ZmqEncoder) decodeDelTunnelsRequest(r *frameReader, msg *Message) {
+	msg.DelTunnelsRequest.FlowId = r.uint32("FlowId")
+	if n := r.count("Teids", 4); n > 0 {
+		msg.DelTunnelsRequest.Teids = make([]uint32, n)
+		for i := range msg.DelTunnelsRequest.Teids {
+			msg.DelTunnelsRequest.Teids[i] = r.uint32("Teids")
+		}
+	}
+}
+
+func (enc *ZmqEncoder) appendDelAllTunnelsRequest(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.DelAllTunnelsRequest.FlowId)
+	dst = binary.BigEndian.AppendUint32(dst, 0) // tunnels number
+
+	return dst
+}
+
+func requestDelAllTunnelsLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	l += 4 // tunnels number
+	return l
+}
+
+func (enc *ZmqEncoder) decodeDelAllTunnelsRequest(r *frameReader, msg *Message) {
+	msg.DelAllTunnelsRequest.FlowId = r.uint32("FlowId")
+	r.constant("tunnels number", 4, 0)
+}
+
+func (enc *ZmqEncoder) appendGetInfoRequest(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.GetInfoRequest.FlowId)
+
+	return dst
+}
+
+func requestGetInfoLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	return l
+}
+
+func (enc *ZmqEncoder) decodeGetInfoRequest(r *frameReader, msg *Message) {
+	msg.GetInfoRequest.FlowId = r.uint32("FlowId")
+}
+
+func (enc *ZmqEncoder) appendStartResponse(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.StartResponse.FlowId)
+	dst = append(dst, msg.StartResponse.Publisher...)
+
+	return dst
+}
+
+func responseStartLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	l += len(msg.StartResponse.Publisher)
+	return l
+}
+
+func (enc *ZmqEncoder) decodeStartResponse(r *frameReader, msg *Message) {
+	msg.StartResponse.FlowId = r.uint32("FlowId")
+	msg.StartResponse.Publisher = r.rest()
+}
+
+func (enc *ZmqEncoder) appendStopResponse(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.Response.FlowId)
+
+	return dst
+}
+
+func responseStopLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	return l
+}
+
+func (enc *ZmqEncoder) decodeStopResponse(r *frameReader, msg *Message) {
+	msg.Response.FlowId = r.uint32("FlowId")
+}
+
+func (enc *ZmqEncoder) appendAddTunnelsResponse(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.TunnelResponse.FlowId)
+	dst = binary.BigEndian.AppendUint32(dst, msg.TunnelResponse.Tunnels)
+
+	return dst
+}
+
+func responseAddTunnelsLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	l += 4 // Tunnels
+	return l
+}
+
+func (enc *ZmqEncoder) decodeAddTunnelsResponse(r *frameReader, msg *Message) {
+	msg.TunnelResponse.FlowId = r.uint32("FlowId")
+	msg.TunnelResponse.Tunnels = r.uint32("Tunnels")
+}
+
+func (enc *ZmqEncoder) appendDelTunnelsResponse(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.TunnelResponse.FlowId)
+	dst = binary.BigEndian.AppendUint32(dst, msg.TunnelResponse.Tunnels)
+
+	return dst
+}
+
+func responseDelTunnelsLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	l += 4 // Tunnels
+	return l
+}
+
+func (enc *ZmqEncoder) decodeDelTunnelsResponse(r *frameReader, msg *Message) {
+	msg.TunnelResponse.FlowId = r.uint32("FlowId")
+	msg.TunnelResponse.Tunnels = r.uint32("Tunnels")
+}
+
+func (enc *ZmqEncoder) appendDelAllTunnelsResponse(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.TunnelResponse.FlowId)
+	dst = binary.BigEndian.AppendUint32(dst, msg.TunnelResponse.Tunnels)
+
+	return dst
+}
+
+func responseDelAllTunnelsLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	l += 4 // Tunnels
+	return l
+}
+
+func (enc *ZmqEncoder) decodeDelAllTunnelsResponse(r *frameReader, msg *Message) {
+	msg.TunnelResponse.FlowId = r.uint32("FlowId")
+	msg.TunnelResponse.Tunnels = r.uint32("Tunnels")
+}
+
+func (enc *ZmqEncoder) appendGetInfoResponse(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.GetInfoResponse.FlowId)
+	dst = append(dst, msg.GetInfoResponse.Version...)
+
+	return dst
+}
+
+func responseGetInfoLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	l += len(msg.GetInfoResponse.Version)
+	return l
+}
+
+func (enc *ZmqEncoder) decodeGetInfoResponse(r *frameReader, msg *Message) {
+	msg.GetInfoResponse.FlowId = r.uint32("FlowId")
+	msg.GetInfoResponse.Version = r.rest()
+}
+
+func (enc *ZmqEncoder) appendErrorResponse(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = append(dst, msg.ErrorResponse.Error...)
+
+	return dst
+}
+
+func responseErrorLength(msg *Message) int {
+	l := 2 // command
+	l += len(msg.ErrorResponse.Error)
+	return l
+}
+
+func (enc *ZmqEncoder) decodeErrorResponse(r *frameReader, msg *Message) {
+	msg.ErrorResponse.Error = r.rest()
+}
+
+func (enc *ZmqEncoder) appendMsgErrorResponse(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.MsgErrorResponse.FlowId)
+	dst = append(dst, msg.MsgErrorResponse.Error...)
+
+	return dst
+}
+
+func responseMsgErrorLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	l += len(msg.MsgErrorResponse.Error)
+	return l
+}
+
+func (enc *ZmqEncoder) decodeMsgErrorResponse(r *frameReader, msg *Message) {
+	msg.MsgErrorResponse.FlowId = r.uint32("FlowId")
+	msg.MsgErrorResponse.Error = r.rest()
+}
+
+func (enc *ZmqEncoder) appendMetricsResponse(dst []byte, msg *Message) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)
+	dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))
+	dst = binary.BigEndian.AppendUint32(dst, msg.Metrics.FlowId)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(msg.Metrics.Metrics)))
+	for _, protocolMetrics := range msg.Metrics.Metrics {
+		dst = binary.BigEndian.AppendUint16(dst, protocolMetrics.Length)
+		dst = binary.BigEndian.AppendUint16(dst, protocolMetrics.Command)
+		dst = binary.BigEndian.AppendUint32(dst, protocolMetrics.FlowId)
+		dst = binary.BigEndian.AppendUint32(dst, protocolMetrics.Protocol)
+		dst = binary.BigEndian.AppendUint64(dst, protocolMetrics.Metric.PktRx)
+		dst = binary.BigEndian.AppendUint64(dst, protocolMetrics.Metric.PktTx)
+		dst = binary.BigEndian.AppendUint64(dst, protocolMetrics.Metric.ByteRx)
+		dst = binary.BigEndian.AppendUint64(dst, protocolMetrics.Metric.ByteTx)
+		dst = binary.BigEndian.AppendUint64(dst, protocolMetrics.Metric.BpsRx)
+		dst = binary.BigEndian.AppendUint64(dst, protocolMetrics.Metric.BpsTx)
+		dst = binary.BigEndian.AppendUint64(dst, protocolMetrics.Metric.ErrRx)
+		dst = binary.BigEndian.AppendUint64(dst, protocolMetrics.Metric.ErrTx)
+	}
+
+	return dst
+}
+
+func responseMetricsLength(msg *Message) int {
+	l := 2 // command
+	l += 4 // FlowId
+	l += 4 // Metrics number
+	l += 76 * len(msg.Metrics.Metrics)
+	return l
+}
+
+func (enc *ZmqEncoder) decodeMetricsResponse(r *frameReader, msg *Message) {
+	msg.Metrics.FlowId = r.uint32("FlowId")
+	if n := r.count("Metrics", 76); n > 0 {
+		msg.Metrics.Metrics = make([]ProtocolMetrics, n)
+		for i := range msg.Metrics.Metrics {
+			msg.Metrics.Metrics[i].Length = r.uint16("Length")
+			msg.Metrics.Metrics[i].Command = r.uint16("Command")
+			msg.Metrics.Metrics[i].FlowId = r.uint32("FlowId")
+			msg.Metrics.Metrics[i].Protocol = r.uint32("Protocol")
+			msg.Metrics.Metrics[i].Metric.PktRx = r.uint64("PktRx")
+			msg.Metrics.Metrics[i].Metric.PktTx = r.uint64("PktTx")
+			msg.Metrics.Metrics[i].Metric.ByteRx = r.uint64("ByteRx")
+			msg.Metrics.Metrics[i].Metric.ByteTx = r.uint64("ByteTx")
+			msg.Metrics.Metrics[i].Metric.BpsRx = r.uint64("BpsRx")
+			msg.Metrics.Metrics[i].Metric.BpsTx = r.uint64("BpsTx")
+			msg.Metrics.Metrics[i].Metric.ErrRx = r.uint64("ErrRx")
+			msg.Metrics.Metrics[i].Metric.ErrTx = r.uint64("ErrTx")
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Code generated by zmqgen from dfxp.schema. DO NOT EDIT.
+
+package zmqencdec
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestGoldenEncodeStartRequest(t *testing.T) {
+	expect := "000a0001000003e9000003ea"
+	msg := &Message{
+		Header:       MsgHeader{Command: ZMQ_CMD_START},
+		StartRequest: MsgStartRequest{FlowId: 1001, MetricsInterval: 1002},
+	}
+
+	l, err := RequestLength(msg)
+	if err != nil {
+		t.Fatalf("RequestLength failed. Err:%v", err)
+	}
+	assert.Equal(t, uint16(10), l, "\nThe two length should be the same.")
+	msg.Header.Length = l
+
+	encoder := &ZmqEncoder{}
+	bytes, err := encoder.Encode(msg)
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	assert.Equal(t, expect, hex.EncodeToString(bytes), "\nThe two array should be the same.")
+}
+
+func TestGoldenEncodeStopRequest(t *testing.T) {
+	expect := "00060002000003e9"
+	msg := &Message{
+		Header:      MsgHeader{Command: ZMQ_CMD_STOP},
+		StopRequest: MsgStopRequest{FlowId: 1001},
+	}
+
+	l, err := RequestLength(msg)
+	if err != nil {
+		t.Fatalf("RequestLength failed. Err:%v", err)
+	}
+	assert.Equal(t, uint16(6), l, "\nThe two length should be the same.")
+	msg.Header.Length = l
+
+	encoder := &ZmqEncoder{}
+	bytes, err := encoder.Encode(msg)
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	assert.Equal(t, expect, hex.EncodeToString(bytes), "\nThe two array should be the same.")
+}
+
+func TestGoldenEncodeAddTunnelsRequest(t *testing.T) {
+	expect := "002a0004000003e900000002000003ec000003ed000003ee000003ef000003f1000003f2000003f3000003f4"
+	msg := &Message{
+		Header:           MsgHeader{Command: ZMQ_CMD_ADD_TUNNELS},
+		AddTunnelRequest: MsgAddTunnelsRequest{FlowId: 1001, Tunnels: []Tunnel{{TeidIn: 1004, TeidOut: 1005, UeIpV4: 1006, SrvIpV4: 1007}, {TeidIn: 1009, TeidOut: 1010, UeIpV4: 1011, SrvIpV4: 1012}}},
+	}
+
+	l, err := RequestLength(msg)
+	if err != nil {
+		t.Fatalf("RequestLength failed. Err:%v", err)
+	}
+	assert.Equal(t, uint16(42), l, "\nThe two length should be the same.")
+	msg.Header.Length = l
+
+	encoder := &ZmqEncoder{}
+	bytes, err := encoder.Encode(msg)
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	assert.Equal(t, expect, hex.EncodeToString(bytes), "\nThe two array should be the same.")
+}
+
+func TestGoldenEncodeDelTunnelsRequest(t *testing.T) {
+	expect := "00120005000003e900000002000003eb000003ec"
+	msg := &Message{
+		Header:            MsgHeader{Command: ZMQ_CMD_DEL_TUNNELS},
+		DelTunnelsRequest: MsgDelTunnelsRequest{FlowId: 1001, Teids: []uint32{1003, 1004}},
+	}
+
+	l, err := RequestLength(msg)
+	if err != nil {
+		t.Fatalf("RequestLength failed. Err:%v", err)
+	}
+	assert.Equal(t, uint16(18), l, "\nThe two length should be the same.")
+	msg.Header.Length = l
+
+	encoder := &ZmqEncoder{}
+	bytes, err := encoder.Encode(msg)
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	assert.Equal(t, expect, hex.EncodeToString(bytes), "\nThe two array should be the same.")
+}
+
+func TestGoldenEncodeDelAllTunnelsRequest(t *testing.T) {
+	expect := "000a0006000003e900000000"
+	msg := &Message{
+		Header:               MsgHeader{Command: ZMQ_CMD_DEL_ALL_TUNNELS},
+		DelAllTunnelsRequest: MsgDelAllTunnelsRequest{FlowId: 1001},
+	}
+
+	l, err := RequestLength(msg)
+	if err != nil {
+		t.Fatalf("RequestLength failed. Err:%v", err)
+	}
+	assert.Equal(t, uint16(10), l, "\nThe two length should be the same.")
+	msg.Header.Length = l
+
+	encoder := &ZmqEncoder{}
+	bytes, err := encoder.Encode(msg)
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	assert.Equal(t, expect, hex.EncodeToString(bytes), "\nThe two array should be the same.")
+}
+
+func TestGoldenEncodeGetInfoRequest(t *testing.T) {
+	expect := "00060007000003e9"
+	msg := &Message{
+		Header:         MsgHeader{Command: ZMQ_CMD_GET_INFO},
+		GetInfoRequest: MsgGetInfoRequest{FlowId: 1001},
+	}
+
+	l, err := RequestLength(msg)
+	if err != nil {
+		t.Fatalf("RequestLength failed. Err:%v", err)
+	}
+	assert.Equal(t, uint16(6), l, "\nThe two length should be the same.")
+	msg.Header.Length = l
+
+	encoder := &ZmqEncoder{}
+	bytes, err := encoder.Encode(msg)
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	assert.Equal(t, expect, hex.EncodeToString(bytes), "\nThe two array should be the same.")
+}
+
+func TestGoldenDecodeStartResponse(t *testing.T) {
+	str := "00140001000007d15075626c69736865722d32303032"
+	expect := MsgStartResponse{FlowId: 2001, Publisher: "Publisher-2002"}
+
+	encoder := &ZmqEncoder{}
+	bytes, _ := hex.DecodeString(str)
+	msg, err := encoder.Decode(bytes)
+	if err != nil {
+		t.Fatalf("Decode failed. Err:%v", err)
+	}
+	assert.Equal(t, MsgHeader{Length: 20, Command: ZMQ_CMD_START}, msg.Header, "\nThe two ZMQ message header should be the same.")
+	assert.DeepEqual(t, expect, msg.StartResponse)
+}
+
+func TestGoldenDecodeStopResponse(t *testing.T) {
+	str := "00060002000007d1"
+	expect := MsgResponse{FlowId: 2001}
+
+	encoder := &ZmqEncoder{}
+	bytes, _ := hex.DecodeString(str)
+	msg, err := encoder.Decode(bytes)
+	if err != nil {
+		t.Fatalf("Decode failed. Err:%v", err)
+	}
+	assert.Equal(t, MsgHeader{Length: 6, Command: ZMQ_CMD_STOP}, msg.Header, "\nThe two ZMQ message header should be the same.")
+	assert.DeepEqual(t, expect, msg.Response)
+}
+
+func TestGoldenDecodeAddTunnelsResponse(t *testing.T) {
+	str := "000a0004000007d1000007d2"
+	expect := MsgTunnelResponse{FlowId: 2001, Tunnels: 2002}
+
+	encoder := &ZmqEncoder{}
+	bytes, _ := hex.DecodeString(str)
+	msg, err := encoder.Decode(bytes)
+	if err != nil {
+		t.Fatalf("Decode failed. Err:%v", err)
+	}
+	assert.Equal(t, MsgHeader{Length: 10, Command: ZMQ_CMD_ADD_TUNNELS}, msg.Header, "\nThe two ZMQ message header should be the same.")
+	assert.DeepEqual(t, expect, msg.TunnelResponse)
+}
+
+func TestGoldenDecodeDelTunnelsResponse(t *testing.T) {
+	str := "000a0005000007d1000007d2"
+	expect := MsgTunnelResponse{FlowId: 2001, Tunnels: 2002}
+
+	encoder := &ZmqEncoder{}
+	bytes, _ := hex.DecodeString(str)
+	msg, err := encoder.Decode(bytes)
+	if err != nil {
+		t.Fatalf("Decode failed. Err:%v", err)
+	}
+	assert.Equal(t, MsgHeader{Length: 10, Command: ZMQ_CMD_DEL_TUNNELS}, msg.Header, "\nThe two ZMQ message header should be the same.")
+	assert.DeepEqual(t, expect, msg.TunnelResponse)
+}
+
+func TestGoldenDecodeDelAllTunnelsResponse(t *testing.T) {
+	str := "000a0006000007d1000007d2"
+	expect := MsgTunnelResponse{FlowId: 2001, Tunnels: 2002}
+
+	encoder := &ZmqEncoder{}
+	bytes, _ := hex.DecodeString(str)
+	msg, err := encoder.Decode(bytes)
+	if err != nil {
+		t.Fatalf("Decode failed. Err:%v", err)
+	}
+	assert.Equal(t, MsgHeader{Length: 10, Command: ZMQ_CMD_DEL_ALL_TUNNELS}, msg.Header, "\nThe two ZMQ message header should be the same.")
+	assert.DeepEqual(t, expect, msg.TunnelResponse)
+}
+
+func TestGoldenDecodeGetInfoResponse(t *testing.T) {
+	str := "00120007000007d156657273696f6e2d32303032"
+	expect := MsgGetInfoResponse{FlowId: 2001, Version: "Version-2002"}
+
+	encoder := &ZmqEncoder{}
+	bytes, _ := hex.DecodeString(str)
+	msg, err := encoder.Decode(bytes)
+	if err != nil {
+		t.Fatalf("Decode failed. Err:%v", err)
+	}
+	assert.Equal(t, MsgHeader{Length: 18, Command: ZMQ_CMD_GET_INFO}, msg.Header, "\nThe two ZMQ message header should be the same.")
+	assert.DeepEqual(t, expect, msg.GetInfoResponse)
+}
+
+func TestGoldenDecodeErrorResponse(t *testing.T) {
+	str := "000c00084572726f722d32303031"
+	expect := ErrorResponse{Error: "Error-2001"}
+
+	encoder := &ZmqEncoder{}
+	bytes, _ := hex.DecodeString(str)
+	msg, err := encoder.Decode(bytes)
+	if err != nil {
+		t.Fatalf("Decode failed. Err:%v", err)
+	}
+	assert.Equal(t, MsgHeader{Length: 12, Command: ZMQ_CMD_ERROR}, msg.Header, "\nThe two ZMQ message header should be the same.")
+	assert.DeepEqual(t, expect, msg.ErrorResponse)
+}
+
+func TestGoldenDecodeMsgErrorResponse(t *testing.T) {
+	str := "00100009000007d14572726f722d32303032"
+	expect := MsgErrorResponse{FlowId: 2001, Error: "Error-2002"}
+
+	encoder := &ZmqEncoder{}
+	bytes, _ := hex.DecodeString(str)
+	msg, err := encoder.Decode(bytes)
+	if err != nil {
+		t.Fatalf("Decode failed. Err:%v", err)
+	}
+	assert.Equal(t, MsgHeader{Length: 16, Command: ZMQ_CMD_MSG_ERROR}, msg.Header, "\nThe two ZMQ message header should be the same.")
+	assert.DeepEqual(t, expect, msg.MsgErrorResponse)
+}
+
+func TestGoldenDecodeMetricsResponse(t *testing.T) {
+	str := "00a2000b000007d10000000207d407d5000007d6000007d700000000000007d900000000000007da00000000000007db00000000000007dc00000000000007dd00000000000007de00000000000007df00000000000007e007e207e3000007e4000007e500000000000007e700000000000007e800000000000007e900000000000007ea00000000000007eb00000000000007ec00000000000007ed00000000000007ee"
+	expect := MsgMetrics{FlowId: 2001, Metrics: []ProtocolMetrics{{Length: 2004, Command: 2005, FlowId: 2006, Protocol: 2007, Metric: Metric{PktRx: 2009, PktTx: 2010, ByteRx: 2011, ByteTx: 2012, BpsRx: 2013, BpsTx: 2014, ErrRx: 2015, ErrTx: 2016}}, {Length: 2018, Command: 2019, FlowId: 2020, Protocol: 2021, Metric: Metric{PktRx: 2023, PktTx: 2024, ByteRx: 2025, ByteTx: 2026, BpsRx: 2027, BpsTx: 2028, ErrRx: 2029, ErrTx: 2030}}}}
+
+	encoder := &ZmqEncoder{}
+	bytes, _ := hex.DecodeString(str)
+	msg, err := encoder.Decode(bytes)
+	if err != nil {
+		t.Fatalf("Decode failed. Err:%v", err)
+	}
+	assert.Equal(t, MsgHeader{Length: 162, Command: ZMQ_CMD_METRICS}, msg.Header, "\nThe two ZMQ message header should be the same.")
+	assert.DeepEqual(t, expect, msg.Metrics)
+}
@@ -0,0 +1,157 @@
+package zmqencdec
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrorCode - class of a dfxp failure, parsed from its error text
+type ErrorCode int
+
+const (
+	ERROR_CODE_UNKNOWN ErrorCode = iota
+	ERROR_CODE_UNKNOWN_COMMAND
+	ERROR_CODE_INVALID_MESSAGE
+	ERROR_CODE_UNKNOWN_FLOW
+	ERROR_CODE_FLOW_EXISTS
+	ERROR_CODE_FLOW_NOT_STARTED
+	ERROR_CODE_UNKNOWN_TUNNEL
+	ERROR_CODE_TUNNEL_EXISTS
+	ERROR_CODE_NO_RESOURCES
+)
+
+var errorCodeNames = map[ErrorCode]string{
+	ERROR_CODE_UNKNOWN:          "UNKNOWN",
+	ERROR_CODE_UNKNOWN_COMMAND:  "UNKNOWN_COMMAND",
+	ERROR_CODE_INVALID_MESSAGE:  "INVALID_MESSAGE",
+	ERROR_CODE_UNKNOWN_FLOW:     "UNKNOWN_FLOW",
+	ERROR_CODE_FLOW_EXISTS:      "FLOW_EXISTS",
+	ERROR_CODE_FLOW_NOT_STARTED: "FLOW_NOT_STARTED",
+	ERROR_CODE_UNKNOWN_TUNNEL:   "UNKNOWN_TUNNEL",
+	ERROR_CODE_TUNNEL_EXISTS:    "TUNNEL_EXISTS",
+	ERROR_CODE_NO_RESOURCES:     "NO_RESOURCES",
+}
+
+func (code ErrorCode) String() string {
+	if name, ok := errorCodeNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("ErrorCode(%d)", int(code))
+}
+
+// errorText - lower case fragment of a dfxp error string and its code
+type errorText struct {
+	text string
+	code ErrorCode
+}
+
+var (
+	errorTextsMu sync.RWMutex
+	errorTexts   = []errorText{
+		{"unknown command", ERROR_CODE_UNKNOWN_COMMAND},
+		{"invalid command", ERROR_CODE_UNKNOWN_COMMAND},
+		{"invalid message", ERROR_CODE_INVALID_MESSAGE},
+		{"malformed", ERROR_CODE_INVALID_MESSAGE},
+		{"invalid length", ERROR_CODE_INVALID_MESSAGE},
+		{"unknown flow", ERROR_CODE_UNKNOWN_FLOW},
+		{"no such flow", ERROR_CODE_UNKNOWN_FLOW},
+		{"flow not found", ERROR_CODE_UNKNOWN_FLOW},
+		{"flow exists", ERROR_CODE_FLOW_EXISTS},
+		{"flow already", ERROR_CODE_FLOW_EXISTS},
+		{"not started", ERROR_CODE_FLOW_NOT_STARTED},
+		{"unknown tunnel", ERROR_CODE_UNKNOWN_TUNNEL},
+		{"tunnel not found", ERROR_CODE_UNKNOWN_TUNNEL},
+		{"unknown teid", ERROR_CODE_UNKNOWN_TUNNEL},
+		{"tunnel exists", ERROR_CODE_TUNNEL_EXISTS},
+		{"duplicate teid", ERROR_CODE_TUNNEL_EXISTS},
+		{"no memory", ERROR_CODE_NO_RESOURCES},
+		{"out of memory", ERROR_CODE_NO_RESOURCES},
+		{"too many", ERROR_CODE_NO_RESOURCES},
+	}
+)
+
+// RegisterErrorText - map dfxp error strings containing text (case
+// insensitive) to code; registered texts are tried before the built-in ones
+func RegisterErrorText(text string, code ErrorCode) {
+	errorTextsMu.Lock()
+	defer errorTextsMu.Unlock()
+	errorTexts = append([]errorText{{strings.ToLower(text), code}}, errorTexts...)
+}
+
+// ParseErrorCode - code of a dfxp error string, ERROR_CODE_UNKNOWN if not recognized
+func ParseErrorCode(text string) ErrorCode {
+	text = strings.ToLower(text)
+	errorTextsMu.RLock()
+	defer errorTextsMu.RUnlock()
+	for _, known := range errorTexts {
+		if strings.Contains(text, known.text) {
+			return known.code
+		}
+	}
+	return ERROR_CODE_UNKNOWN
+}
+
+// DfxpError - failure reported by dfxp with ZMQ_CMD_ERROR or ZMQ_CMD_MSG_ERROR
+type DfxpError struct {
+	// Command - command of the failed request
+	Command ZmqMessageType
+	// Response - ZMQ_CMD_ERROR or ZMQ_CMD_MSG_ERROR
+	Response ZmqMessageType
+	// FlowId - flow of a MSG_ERROR, 0 for ERROR
+	FlowId  uint32
+	Message string
+	Code    ErrorCode
+}
+
+// sentinels for errors.Is, matching any DfxpError of the same code
+var (
+	ErrDfxp           = &DfxpError{}
+	ErrUnknownCommand = &DfxpError{Code: ERROR_CODE_UNKNOWN_COMMAND}
+	ErrInvalidMessage = &DfxpError{Code: ERROR_CODE_INVALID_MESSAGE}
+	ErrUnknownFlow    = &DfxpError{Code: ERROR_CODE_UNKNOWN_FLOW}
+	ErrFlowExists     = &DfxpError{Code: ERROR_CODE_FLOW_EXISTS}
+	ErrFlowNotStarted = &DfxpError{Code: ERROR_CODE_FLOW_NOT_STARTED}
+	ErrUnknownTunnel  = &DfxpError{Code: ERROR_CODE_UNKNOWN_TUNNEL}
+	ErrTunnelExists   = &DfxpError{Code: ERROR_CODE_TUNNEL_EXISTS}
+	ErrNoResources    = &DfxpError{Code: ERROR_CODE_NO_RESOURCES}
+)
+
+func (e *DfxpError) Error() string {
+	return fmt.Sprintf("%s failed. Error: %s", e.Command, e.Message)
+}
+
+// Is - ErrDfxp matches every DfxpError, the other sentinels their code
+func (e *DfxpError) Is(target error) bool {
+	t, ok := target.(*DfxpError)
+	if !ok {
+		return false
+	}
+	if t == ErrDfxp {
+		return true
+	}
+	return t.Message == "" && t.Code != ERROR_CODE_UNKNOWN && t.Code == e.Code
+}
+
+// ResponseError - DfxpError of an ERROR or MSG_ERROR response to a request
+// of command, nil for any other response
+func ResponseError(command ZmqMessageType, response *Message) error {
+	var dfxpErr *DfxpError
+	switch response.Header.Command {
+	case ZMQ_CMD_ERROR:
+		dfxpErr = &DfxpError{
+			Message: response.ErrorResponse.Error,
+		}
+	case ZMQ_CMD_MSG_ERROR:
+		dfxpErr = &DfxpError{
+			FlowId:  response.MsgErrorResponse.FlowId,
+			Message: response.MsgErrorResponse.Error,
+		}
+	default:
+		return nil
+	}
+	dfxpErr.Command = command
+	dfxpErr.Response = response.Header.Command
+	dfxpErr.Code = ParseErrorCode(dfxpErr.Message)
+	return dfxpErr
+}
@@ -0,0 +1,47 @@
+package zmqencdec
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestResponseError(t *testing.T) {
+	response := &Message{
+		Header:           MsgHeader{Command: ZMQ_CMD_MSG_ERROR},
+		MsgErrorResponse: MsgErrorResponse{FlowId: 12, Error: "No such flow"},
+	}
+	err := ResponseError(ZMQ_CMD_STOP, response)
+
+	var dfxpErr *DfxpError
+	assert.Assert(t, errors.As(err, &dfxpErr))
+	assert.Equal(t, DfxpError{
+		Command:  ZMQ_CMD_STOP,
+		Response: ZMQ_CMD_MSG_ERROR,
+		FlowId:   12,
+		Message:  "No such flow",
+		Code:     ERROR_CODE_UNKNOWN_FLOW,
+	}, *dfxpErr)
+	assert.Equal(t, "STOP failed. Error: No such flow", err.Error())
+	assert.Assert(t, errors.Is(err, ErrDfxp))
+	assert.Assert(t, errors.Is(fmt.Errorf("wrapped: %w", err), ErrUnknownFlow))
+	assert.Assert(t, !errors.Is(err, ErrFlowExists))
+
+	response = &Message{
+		Header:        MsgHeader{Command: ZMQ_CMD_ERROR},
+		ErrorResponse: ErrorResponse{Error: "bad header"},
+	}
+	err = ResponseError(ZMQ_CMD_START, response)
+	assert.Assert(t, errors.As(err, &dfxpErr))
+	assert.Equal(t, ERROR_CODE_UNKNOWN, dfxpErr.Code)
+	assert.Assert(t, errors.Is(err, ErrDfxp))
+	assert.Assert(t, !errors.Is(err, ErrInvalidMessage))
+
+	RegisterErrorText("Bad Header", ERROR_CODE_INVALID_MESSAGE)
+	assert.Assert(t, errors.Is(ResponseError(ZMQ_CMD_START, response), ErrInvalidMessage))
+
+	response = &Message{Header: MsgHeader{Command: ZMQ_CMD_START}}
+	assert.NilError(t, ResponseError(ZMQ_CMD_START, response))
+}
@@ -0,0 +1,159 @@
+package zmqencdec
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"zmqclient/internal/golden"
+
+	"gotest.tools/assert"
+)
+
+// addGolden - seed f with the request or response frames of the golden corpus
+func addGolden(f *testing.F, requests bool) {
+	for _, vector := range golden.Load(f) {
+		frame := vector.Response
+		if requests {
+			frame = vector.Request
+		}
+		if frame != nil {
+			f.Add(frame.Bytes(f))
+		}
+	}
+}
+
+// FuzzDecode - Decode never panics, and a frame it accepts is encoded back
+// to the same bytes
+func FuzzDecode(f *testing.F) {
+	addGolden(f, false)
+	f.Add(metricsFrame(7, 2))
+	encoder := &ZmqEncoder{}
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		msg, err := encoder.Decode(frame)
+		if err != nil {
+			return
+		}
+		encoded, err := encoder.EncodeResponse(msg)
+		if err != nil {
+			t.Fatalf("EncodeResponse failed. Err:%v", err)
+		}
+		assert.Assert(t, bytes.Equal(frame, encoded), "\nThe two array should be the same.\n%x\n%x", frame, encoded)
+	})
+}
+
+// FuzzDecodeRequest - DecodeRequest never panics, and a frame it accepts is
+// encoded back to the same bytes
+func FuzzDecodeRequest(f *testing.F) {
+	addGolden(f, true)
+	encoder := &ZmqEncoder{}
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		msg, err := encoder.DecodeRequest(frame)
+		if err != nil {
+			return
+		}
+		encoded, err := encoder.Encode(msg)
+		if err != nil {
+			t.Fatalf("Encode failed. Err:%v", err)
+		}
+		assert.Assert(t, bytes.Equal(frame, encoded), "\nThe two array should be the same.\n%x\n%x", frame, encoded)
+	})
+}
+
+// FuzzRoundTrip - requests and responses of every command, filled from data,
+// decode to the message they were encoded from
+func FuzzRoundTrip(f *testing.F) {
+	for command := range commandNames {
+		f.Add(uint16(command), []byte{})
+		f.Add(uint16(command), []byte("\x00\x00\x04\xd1\x02\x00\x00\x00\x0alocal:59001"))
+	}
+	encoder := &ZmqEncoder{}
+
+	f.Fuzz(func(t *testing.T, command uint16, data []byte) {
+		for _, section := range []string{RequestSection(ZmqMessageType(command)), ResponseSection(ZmqMessageType(command))} {
+			if section == "" {
+				continue
+			}
+			msg := &Message{Header: MsgHeader{Command: ZmqMessageType(command)}}
+			fill(reflect.ValueOf(msg).Elem().FieldByName(section), data)
+
+			var frame []byte
+			var decoded *Message
+			var err error
+			if section == RequestSection(msg.Header.Command) {
+				if msg.Header.Length, err = RequestLength(msg); err != nil {
+					continue
+				}
+				if frame, err = encoder.Encode(msg); err != nil {
+					t.Fatalf("Encode failed. Err:%v", err)
+				}
+				decoded, err = encoder.DecodeRequest(frame)
+			} else {
+				if msg.Header.Length, err = ResponseLength(msg); err != nil {
+					continue
+				}
+				if frame, err = encoder.EncodeResponse(msg); err != nil {
+					t.Fatalf("EncodeResponse failed. Err:%v", err)
+				}
+				decoded, err = encoder.Decode(frame)
+			}
+			if err != nil {
+				t.Fatalf("Decode %s failed. Err:%v", section, err)
+			}
+			assert.DeepEqual(t, msg, decoded)
+		}
+	})
+}
+
+// fill - set the fields of v from data: numbers take their size, slices take
+// one byte as element count, strings take the rest
+func fill(v reflect.Value, data []byte) []byte {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			data = fill(v.Field(i), data)
+		}
+	case reflect.Slice:
+		if len(data) == 0 || data[0] == 0 {
+			return data
+		}
+		v.Set(reflect.MakeSlice(v.Type(), int(data[0]), int(data[0])))
+		data = data[1:]
+		for i := 0; i < v.Len(); i++ {
+			data = fill(v.Index(i), data)
+		}
+	case reflect.String:
+		v.SetString(string(data))
+		data = nil
+	default:
+		var value uint64
+		for size := int(v.Type().Size()); size > 0 && len(data) > 0; size-- {
+			value = value<<8 | uint64(data[0])
+			data = data[1:]
+		}
+		v.SetUint(value)
+	}
+	return data
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	encoder := &ZmqEncoder{}
+	for frame, expect := range map[string]string{
+		"0002":                             "frame too short [2]",
+		"00060002000004":                   "frame truncated at FlowId: 4 bytes needed, 3 left",
+		"00060002000004d100":               "1 trailing bytes in frame",
+		"000a0004000004d1":                 "frame truncated at Tunnels: 4 bytes needed, 0 left",
+		"000e000b000004d1ffffffff00000000": "frame truncated at Metrics: 4294967295 elements need 326417514420 bytes, 4 left",
+		"00060063000004d1":                 "Wrong message command [99]",
+	} {
+		bytes, _ := hex.DecodeString(frame)
+		_, err := encoder.Decode(bytes)
+		assert.Error(t, err, expect, "\nframe %s", frame)
+	}
+
+	bytes, _ := hex.DecodeString("000a0006000004d100000003")
+	_, err := encoder.DecodeRequest(bytes)
+	assert.Error(t, err, "invalid tunnels number [3], expected 0")
+}
@@ -0,0 +1,94 @@
+package zmqencdec
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"zmqclient/internal/golden"
+
+	"gotest.tools/assert"
+)
+
+// goldenCodec - the encode, decode and length functions of one frame direction
+type goldenCodec struct {
+	section func(ZmqMessageType) string
+	length  func(*Message) (uint16, error)
+	encode  func(*ZmqEncoder, *Message) ([]byte, error)
+	decode  func(*ZmqEncoder, []byte) (*Message, error)
+}
+
+var (
+	goldenRequest = goldenCodec{
+		section: RequestSection,
+		length:  RequestLength,
+		encode:  (*ZmqEncoder).Encode,
+		decode:  (*ZmqEncoder).DecodeRequest,
+	}
+	goldenResponse = goldenCodec{
+		section: ResponseSection,
+		length:  ResponseLength,
+		encode:  (*ZmqEncoder).EncodeResponse,
+		decode:  (*ZmqEncoder).Decode,
+	}
+)
+
+func TestGoldenCorpus(t *testing.T) {
+	for _, vector := range golden.Load(t) {
+		vector := vector
+		t.Run(vector.Name(), func(t *testing.T) {
+			if vector.Request != nil {
+				checkGoldenFrame(t, goldenRequest, vector.Request)
+			}
+			if vector.Response != nil {
+				checkGoldenFrame(t, goldenResponse, vector.Response)
+			}
+			if golden.Update() {
+				vector.Save(t)
+			}
+		})
+	}
+}
+
+// checkGoldenFrame - encode the JSON form of frame and decode it back, or
+// with -update recompute the frame from its JSON form
+func checkGoldenFrame(t *testing.T, codec goldenCodec, frame *golden.Frame) {
+	msg := &Message{}
+	if err := json.Unmarshal(frame.Json, msg); err != nil {
+		t.Fatalf("Unmarshal failed. Err:%v", err)
+	}
+	section := codec.section(msg.Header.Command)
+	value := reflect.ValueOf(msg).Elem().FieldByName(section).Interface()
+	length, err := codec.length(msg)
+	if err != nil {
+		t.Fatalf("Length failed. Err:%v", err)
+	}
+	encoder := &ZmqEncoder{}
+
+	if golden.Update() {
+		msg.Header.Length = length
+		bytes, err := codec.encode(encoder, msg)
+		if err != nil {
+			t.Fatalf("Encode failed. Err:%v", err)
+		}
+		frame.Json, _ = json.Marshal(map[string]interface{}{"Header": msg.Header, section: value})
+		frame.Hex = hex.EncodeToString(bytes)
+		frame.Section = fmt.Sprintf("%+v", value)
+		return
+	}
+
+	assert.Equal(t, length, msg.Header.Length, "\nThe two length should be the same.")
+	bytes, err := codec.encode(encoder, msg)
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	assert.Equal(t, frame.Hex, hex.EncodeToString(bytes), "\nThe two array should be the same.")
+
+	decoded, err := codec.decode(encoder, frame.Bytes(t))
+	if err != nil {
+		t.Fatalf("Decode failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, msg, decoded)
+	assert.Equal(t, frame.Section, fmt.Sprintf("%+v", value), "\nThe two sections should be the same.")
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func genEncoder(schema *Schema) string {
+	p := &printer{}
+	p.WriteString(generatedHeader)
+	p.P("package zmqencdec")
+	p.P("")
+	p.P("import (")
+	p.P("\"encoding/binary\"")
+	p.P("\"fmt\"")
+	p.P("\"math\"")
+	p.P(")")
+	p.P("")
+
+	// dispatchers
+	p.P("// appendRequest - append the request frame of msg to dst, grown once to the frame size")
+	p.P("func (enc *ZmqEncoder) appendRequest(dst []byte, msg *Message) ([]byte, error) {")
+	p.P("switch msg.Header.Command {")
+	for _, cmd := range schema.Commands {
+		if cmd.Request != nil {
+			p.P("case %s:", cmd.Name)
+			p.P("return enc.append%sRequest(grow(dst, 2+request%sLength(msg)), msg), nil", camelName(cmd.Name), camelName(cmd.Name))
+		}
+	}
+	p.P("default:")
+	p.P("return nil, fmt.Errorf(\"Wrong message command [%%d]\", msg.Header.Command)")
+	p.P("}")
+	p.P("}")
+	p.P("")
+
+	p.P("// appendResponse - append the response frame of msg to dst, grown once to the frame size")
+	p.P("func (enc *ZmqEncoder) appendResponse(dst []byte, msg *Message) ([]byte, error) {")
+	p.P("switch msg.Header.Command {")
+	for _, cmd := range schema.Commands {
+		if cmd.Response != nil {
+			p.P("case %s:", cmd.Name)
+			p.P("return enc.append%sResponse(grow(dst, 2+response%sLength(msg)), msg), nil", camelName(cmd.Name), camelName(cmd.Name))
+		}
+	}
+	p.P("default:")
+	p.P("return nil, fmt.Errorf(\"Wrong message command [%%d]\", msg.Header.Command)")
+	p.P("}")
+	p.P("}")
+	p.P("")
+
+	for _, kind := range []string{"Request", "Response"} {
+		p.P("func (enc *ZmqEncoder) decode%s(r *frameReader, msg *Message) error {", kind)
+		p.P("switch msg.Header.Command {")
+		for _, cmd := range schema.Commands {
+			if binding(cmd, kind) != nil {
+				p.P("case %s:", cmd.Name)
+				p.P("enc.decode%s%s(r, msg)", camelName(cmd.Name), kind)
+			}
+		}
+		p.P("default:")
+		p.P("return fmt.Errorf(\"Wrong message command [%%d]\", msg.Header.Command)")
+		p.P("}")
+		p.P("return r.end()")
+		p.P("}")
+		p.P("")
+	}
+
+	for _, kind := range []string{"Request", "Response"} {
+		p.P("// %sLength - Header.Length of the %s in msg: command size plus payload size", kind, strings.ToLower(kind))
+		p.P("func %sLength(msg *Message) (uint16, error) {", kind)
+		p.P("var l int")
+		p.P("switch msg.Header.Command {")
+		for _, cmd := range schema.Commands {
+			if binding(cmd, kind) != nil {
+				p.P("case %s:", cmd.Name)
+				p.P("l = %s%sLength(msg)", strings.ToLower(kind), camelName(cmd.Name))
+			}
+		}
+		p.P("default:")
+		p.P("return 0, fmt.Errorf(\"Wrong message command [%%d]\", msg.Header.Command)")
+		p.P("}")
+		p.P("if l > math.MaxUint16 {")
+		p.P("return 0, fmt.Errorf(\"message too long [%%d]\", l)")
+		p.P("}")
+		p.P("return uint16(l), nil")
+		p.P("}")
+		p.P("")
+	}
+
+	for _, kind := range []string{"Request", "Response"} {
+		for _, cmd := range schema.Commands {
+			b := binding(cmd, kind)
+			if b == nil {
+				continue
+			}
+			layout := schema.structs[b.Layout]
+			expr := "msg." + b.Section
+
+			p.P("func (enc *ZmqEncoder) append%s%s(dst []byte, msg *Message) []byte {", camelName(cmd.Name), kind)
+			p.P("dst = binary.BigEndian.AppendUint16(dst, msg.Header.Length)")
+			p.P("dst = binary.BigEndian.AppendUint16(dst, uint16(msg.Header.Command))")
+			encodeFields(p, schema, layout, expr)
+			p.P("")
+			p.P("return dst")
+			p.P("}")
+			p.P("")
+
+			p.P("func %s%sLength(msg *Message) int {", strings.ToLower(kind), camelName(cmd.Name))
+			p.P("l := 2 // command")
+			lengthFields(p, schema, layout, expr)
+			p.P("return l")
+			p.P("}")
+			p.P("")
+
+			p.P("func (enc *ZmqEncoder) decode%s%s(r *frameReader, msg *Message) {", camelName(cmd.Name), kind)
+			decodeFields(p, schema, layout, expr)
+			p.P("}")
+			p.P("")
+		}
+	}
+	return p.String()
+}
+
+// binding - request or response binding of cmd, by kind
+func binding(cmd Command, kind string) *Binding {
+	if kind == "Request" {
+		return cmd.Request
+	}
+	return cmd.Response
+}
+
+func comment(field Field) string {
+	if field.Comment == "" {
+		return ""
+	}
+	return " // " + field.Comment
+}
+
+func encodeFields(p *printer, schema *Schema, s *Struct, expr string) {
+	for _, field := range s.Fields {
+		if field.Const {
+			p.P("dst = %s%s", appendScalar(field.Type, fmt.Sprintf("%d", field.Value)), comment(field))
+			continue
+		}
+		value := expr + "." + field.Name
+		encodeValue(p, schema, field, field.Type, value)
+	}
+}
+
+func encodeValue(p *printer, schema *Schema, field Field, t, value string) {
+	switch {
+	case t == "string":
+		p.P("dst = append(dst, %s...)", value)
+	case strings.HasPrefix(t, "[]"):
+		elem := t[2:]
+		v := varName(field, elem)
+		p.P("dst = %s", appendScalar("u32", "uint32(len("+value+"))"))
+		p.P("for _, %s := range %s {", v, value)
+		encodeValue(p, schema, field, elem, v)
+		p.P("}")
+	case schema.structs[t] != nil:
+		encodeFields(p, schema, schema.structs[t], value)
+	default:
+		p.P("dst = %s", appendScalar(t, value))
+	}
+}
+
+// appendScalar - expression appending the big endian value of scalar type t to dst
+func appendScalar(t, value string) string {
+	switch t {
+	case "u8":
+		return fmt.Sprintf("append(dst, %s)", value)
+	case "u16":
+		return fmt.Sprintf("binary.BigEndian.AppendUint16(dst, %s)", value)
+	case "u32":
+		return fmt.Sprintf("binary.BigEndian.AppendUint32(dst, %s)", value)
+	default:
+		return fmt.Sprintf("binary.BigEndian.AppendUint64(dst, %s)", value)
+	}
+}
+
+func lengthFields(p *printer, schema *Schema, s *Struct, expr string) {
+	for _, field := range s.Fields {
+		if field.Const {
+			p.P("l += %d%s", scalarSizes[field.Type], comment(field))
+			continue
+		}
+		lengthValue(p, schema, field, field.Type, expr+"."+field.Name)
+	}
+}
+
+func lengthValue(p *printer, schema *Schema, field Field, t, value string) {
+	if size := schema.FixedSize(t); size >= 0 {
+		p.P("l += %d // %s", size, field.Name)
+		return
+	}
+	switch {
+	case t == "string":
+		p.P("l += len(%s)", value)
+	case strings.HasPrefix(t, "[]"):
+		elem := t[2:]
+		p.P("l += 4 // %s number", field.Name)
+		if size := schema.FixedSize(elem); size >= 0 {
+			p.P("l += %d * len(%s)", size, value)
+			return
+		}
+		v := varName(field, elem)
+		p.P("for _, %s := range %s {", v, value)
+		lengthValue(p, schema, field, elem, v)
+		p.P("}")
+	default:
+		lengthFields(p, schema, schema.structs[t], value)
+	}
+}
+
+func decodeFields(p *printer, schema *Schema, s *Struct, expr string) {
+	for _, field := range s.Fields {
+		if field.Const {
+			name := field.Comment
+			if name == "" {
+				name = "const"
+			}
+			p.P("r.constant(%q, %d, %d)", name, scalarSizes[field.Type], field.Value)
+			continue
+		}
+		decodeValue(p, schema, field, field.Type, expr+"."+field.Name)
+	}
+}
+
+func decodeValue(p *printer, schema *Schema, field Field, t, value string) {
+	switch {
+	case t == "string":
+		p.P("%s = r.rest()", value)
+	case strings.HasPrefix(t, "[]"):
+		elem := t[2:]
+		size := schema.MinSize(elem)
+		if size == 0 {
+			size = 1
+		}
+		p.P("if n := r.count(%q, %d); n > 0 {", field.Name, size)
+		p.P("%s = make(%s, n)", value, schema.GoType(t))
+		p.P("for i := range %s {", value)
+		decodeValue(p, schema, field, elem, value+"[i]")
+		p.P("}")
+		p.P("}")
+	case schema.structs[t] != nil:
+		decodeFields(p, schema, schema.structs[t], value)
+	default:
+		p.P("%s = r.%s(%q)", value, goTypes[t], field.Name)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+func genMessages(schema *Schema) string {
+	p := &printer{}
+	p.WriteString(generatedHeader)
+	p.P("package zmqencdec")
+	p.P("")
+	p.P("import \"fmt\"")
+	p.P("")
+
+	p.P("const (")
+	for _, cmd := range schema.Commands {
+		if cmd.Provisional {
+			p.P("// %s - provisional value, not documented by dfxp yet", cmd.Name)
+		}
+		p.P("%s ZmqMessageType = %d", cmd.Name, cmd.Value)
+	}
+	p.P(")")
+	p.P("")
+
+	for _, s := range schema.Structs {
+		p.P("type %s struct {", s.Name)
+		for _, field := range s.Fields {
+			if field.Const {
+				continue
+			}
+			p.P("%s %s", field.Name, schema.GoType(field.Type))
+		}
+		p.P("}")
+		p.P("")
+	}
+
+	p.P("type Message struct {")
+	p.P("Header MsgHeader")
+	for _, section := range schema.Sections {
+		p.P("%s %s", section.Name, section.Type)
+	}
+	p.P("}")
+	p.P("")
+
+	p.P("var commandNames = map[ZmqMessageType]string{")
+	for _, cmd := range schema.Commands {
+		p.P("%s: %q,", cmd.Name, shortName(cmd.Name))
+	}
+	p.P("}")
+	p.P("")
+
+	p.P("var requestSections = map[ZmqMessageType]string{")
+	for _, cmd := range schema.Commands {
+		if cmd.Request != nil {
+			p.P("%s: %q,", cmd.Name, cmd.Request.Section)
+		}
+	}
+	p.P("}")
+	p.P("")
+
+	p.P("var responseSections = map[ZmqMessageType]string{")
+	for _, cmd := range schema.Commands {
+		if cmd.Response != nil {
+			p.P("%s: %q,", cmd.Name, cmd.Response.Section)
+		}
+	}
+	p.P("}")
+	p.P("")
+
+	p.P("func (t ZmqMessageType) String() string {")
+	p.P("if name, ok := commandNames[t]; ok {")
+	p.P("return name")
+	p.P("}")
+	p.P("return fmt.Sprintf(\"COMMAND_%%d\", uint16(t))")
+	p.P("}")
+	p.P("")
+
+	p.P("// RequestSection - name of the Message field carrying the request of cmd, \"\" if none")
+	p.P("func RequestSection(cmd ZmqMessageType) string {")
+	p.P("return requestSections[cmd]")
+	p.P("}")
+	p.P("")
+	p.P("// ResponseSection - name of the Message field carrying the response of cmd, \"\" if none")
+	p.P("func ResponseSection(cmd ZmqMessageType) string {")
+	p.P("return responseSections[cmd]")
+	p.P("}")
+	return p.String()
+}
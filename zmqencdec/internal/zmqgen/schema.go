@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Field - one line of a struct/message block
+type Field struct {
+	Name    string
+	Type    string
+	Const   bool
+	Value   uint64
+	Comment string
+}
+
+// Struct - a `struct` (embedded element) or `message` (section payload) block
+type Struct struct {
+	Name    string
+	Message bool
+	Fields  []Field
+}
+
+type Section struct {
+	Name string
+	Type string
+}
+
+// Binding - request or response section of a command
+type Binding struct {
+	Section string
+	Layout  string
+}
+
+type Command struct {
+	Name     string
+	Value    uint16
+	Request  *Binding
+	Response *Binding
+
+	// Provisional - the value is assumed, dfxp does not document it yet
+	Provisional bool
+}
+
+type Schema struct {
+	Structs  []*Struct
+	Sections []Section
+	Commands []Command
+
+	structs  map[string]*Struct
+	sections map[string]Section
+}
+
+var scalarSizes = map[string]int{
+	"u8":  1,
+	"u16": 2,
+	"u32": 4,
+	"u64": 8,
+}
+
+var goTypes = map[string]string{
+	"u8":     "uint8",
+	"u16":    "uint16",
+	"u32":    "uint32",
+	"u64":    "uint64",
+	"string": "string",
+}
+
+// ParseSchema - parse the dfxp schema language, see dfxp.schema for the syntax
+func ParseSchema(r io.Reader) (*Schema, error) {
+	schema := &Schema{
+		structs:  make(map[string]*Struct),
+		sections: make(map[string]Section),
+	}
+
+	var current *Struct
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line, comment := splitComment(scanner.Text())
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			continue
+		}
+
+		var err error
+		switch {
+		case current != nil && words[0] == "}":
+			current = nil
+		case current != nil:
+			err = current.parseField(words, comment)
+		case words[0] == "struct" || words[0] == "message":
+			current, err = schema.parseStruct(words)
+		case words[0] == "section":
+			err = schema.parseSection(words)
+		case words[0] == "command":
+			err = schema.parseCommand(words)
+		default:
+			err = fmt.Errorf("unexpected %q", words[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("schema line %d: %v", lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if current != nil {
+		return nil, fmt.Errorf("schema: block %s not closed", current.Name)
+	}
+	return schema, schema.check()
+}
+
+func splitComment(line string) (string, string) {
+	if idx := strings.Index(line, "#"); idx >= 0 {
+		return line[:idx], strings.TrimSpace(line[idx+1:])
+	}
+	return line, ""
+}
+
+func (schema *Schema) parseStruct(words []string) (*Struct, error) {
+	if len(words) != 3 || words[2] != "{" {
+		return nil, fmt.Errorf("expected `%s Name {`", words[0])
+	}
+	if _, ok := schema.structs[words[1]]; ok {
+		return nil, fmt.Errorf("%s defined twice", words[1])
+	}
+	s := &Struct{Name: words[1], Message: words[0] == "message"}
+	schema.Structs = append(schema.Structs, s)
+	schema.structs[s.Name] = s
+	return s, nil
+}
+
+func (s *Struct) parseField(words []string, comment string) error {
+	if words[0] == "const" {
+		if len(words) != 3 {
+			return fmt.Errorf("expected `const TYPE VALUE`")
+		}
+		if _, ok := scalarSizes[words[1]]; !ok {
+			return fmt.Errorf("const type must be an integer, got %s", words[1])
+		}
+		value, err := strconv.ParseUint(words[2], 0, 64)
+		if err != nil {
+			return err
+		}
+		s.Fields = append(s.Fields, Field{Type: words[1], Const: true, Value: value, Comment: comment})
+		return nil
+	}
+	if len(words) != 2 {
+		return fmt.Errorf("expected `Name TYPE`")
+	}
+	s.Fields = append(s.Fields, Field{Name: words[0], Type: words[1], Comment: comment})
+	return nil
+}
+
+func (schema *Schema) parseSection(words []string) error {
+	if len(words) != 3 {
+		return fmt.Errorf("expected `section Name Type`")
+	}
+	section := Section{Name: words[1], Type: words[2]}
+	schema.Sections = append(schema.Sections, section)
+	schema.sections[section.Name] = section
+	return nil
+}
+
+func (schema *Schema) parseCommand(words []string) error {
+	if len(words) < 3 {
+		return fmt.Errorf("expected `command NAME VALUE [provisional] ...`")
+	}
+	value, err := strconv.ParseUint(words[2], 0, 16)
+	if err != nil {
+		return err
+	}
+	cmd := Command{Name: words[1], Value: uint16(value)}
+
+	rest := words[3:]
+	if len(rest) > 0 && rest[0] == "provisional" {
+		cmd.Provisional = true
+		rest = rest[1:]
+	}
+	for len(rest) > 0 {
+		if len(rest) < 2 {
+			return fmt.Errorf("missing section after %s", rest[0])
+		}
+		binding := &Binding{Section: rest[1]}
+		kind := rest[0]
+		rest = rest[2:]
+		if len(rest) >= 2 && rest[0] == "as" {
+			binding.Layout = rest[1]
+			rest = rest[2:]
+		}
+		switch kind {
+		case "request":
+			cmd.Request = binding
+		case "response":
+			cmd.Response = binding
+		default:
+			return fmt.Errorf("unexpected %q", kind)
+		}
+	}
+	schema.Commands = append(schema.Commands, cmd)
+	return nil
+}
+
+// check - every referenced type and section must exist
+func (schema *Schema) check() error {
+	for _, s := range schema.Structs {
+		for _, field := range s.Fields {
+			if err := schema.checkType(field.Type); err != nil {
+				return fmt.Errorf("schema: %s.%s: %v", s.Name, field.Name, err)
+			}
+		}
+	}
+	for _, section := range schema.Sections {
+		if _, ok := schema.structs[section.Type]; !ok {
+			return fmt.Errorf("schema: section %s: unknown type %s", section.Name, section.Type)
+		}
+	}
+	for _, cmd := range schema.Commands {
+		for _, binding := range []*Binding{cmd.Request, cmd.Response} {
+			if binding == nil {
+				continue
+			}
+			section, ok := schema.sections[binding.Section]
+			if !ok {
+				return fmt.Errorf("schema: command %s: unknown section %s", cmd.Name, binding.Section)
+			}
+			if binding.Layout == "" {
+				binding.Layout = section.Type
+			}
+			if s, ok := schema.structs[binding.Layout]; !ok || !s.Message {
+				return fmt.Errorf("schema: command %s: %s is not a message", cmd.Name, binding.Layout)
+			}
+		}
+	}
+	return nil
+}
+
+func (schema *Schema) checkType(t string) error {
+	t = strings.TrimPrefix(t, "[]")
+	if _, ok := goTypes[t]; ok {
+		return nil
+	}
+	if _, ok := schema.structs[t]; ok {
+		return nil
+	}
+	return fmt.Errorf("unknown type %s", t)
+}
+
+// GoType - Go type of a schema type
+func (schema *Schema) GoType(t string) string {
+	if strings.HasPrefix(t, "[]") {
+		return "[]" + schema.GoType(t[2:])
+	}
+	if goType, ok := goTypes[t]; ok {
+		return goType
+	}
+	return t
+}
+
+// FixedSize - wire size of a type, or -1 when it depends on the value
+func (schema *Schema) FixedSize(t string) int {
+	if size, ok := scalarSizes[t]; ok {
+		return size
+	}
+	s, ok := schema.structs[t]
+	if !ok {
+		return -1
+	}
+	total := 0
+	for _, field := range s.Fields {
+		size := schema.FixedSize(field.Type)
+		if size < 0 {
+			return -1
+		}
+		total += size
+	}
+	return total
+}
+
+// MinSize - smallest wire size of a type: strings may be empty, slices hold
+// at least their element count
+func (schema *Schema) MinSize(t string) int {
+	if size, ok := scalarSizes[t]; ok {
+		return size
+	}
+	switch {
+	case t == "string":
+		return 0
+	case strings.HasPrefix(t, "[]"):
+		return 4
+	}
+	total := 0
+	for _, field := range schema.structs[t].Fields {
+		total += schema.MinSize(field.Type)
+	}
+	return total
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// sampler - deterministic sample values together with their expected wire bytes
+type sampler struct {
+	schema *Schema
+	next   uint64
+}
+
+func (s *sampler) fields(st *Struct) (string, []byte) {
+	var literal []string
+	var wire []byte
+	for _, field := range st.Fields {
+		if field.Const {
+			wire = appendUint(wire, field.Type, field.Value)
+			continue
+		}
+		value, bytes := s.value(field, field.Type)
+		literal = append(literal, fmt.Sprintf("%s: %s", field.Name, value))
+		wire = append(wire, bytes...)
+	}
+	return strings.Join(literal, ", "), wire
+}
+
+func (s *sampler) value(field Field, t string) (string, []byte) {
+	s.next++
+	switch {
+	case t == "string":
+		value := fmt.Sprintf("%s-%d", field.Name, s.next)
+		return fmt.Sprintf("%q", value), []byte(value)
+	case strings.HasPrefix(t, "[]"):
+		elem := t[2:]
+		var items []string
+		wire := appendUint(nil, "u32", 2)
+		for i := 0; i < 2; i++ {
+			item, bytes := s.value(field, elem)
+			items = append(items, strings.TrimPrefix(item, elem))
+			wire = append(wire, bytes...)
+		}
+		return fmt.Sprintf("%s{%s}", s.schema.GoType(t), strings.Join(items, ", ")), wire
+	case s.schema.structs[t] != nil:
+		literal, wire := s.fields(s.schema.structs[t])
+		return fmt.Sprintf("%s{%s}", t, literal), wire
+	default:
+		value := s.next
+		if size := scalarSizes[t]; size < 8 {
+			value %= 1 << (8 * uint(size))
+		}
+		return fmt.Sprintf("%d", value), appendUint(nil, t, value)
+	}
+}
+
+func appendUint(wire []byte, t string, value uint64) []byte {
+	switch t {
+	case "u8":
+		return append(wire, uint8(value))
+	case "u16":
+		return binary.BigEndian.AppendUint16(wire, uint16(value))
+	case "u32":
+		return binary.BigEndian.AppendUint32(wire, uint32(value))
+	default:
+		return binary.BigEndian.AppendUint64(wire, value)
+	}
+}
+
+func frame(cmd Command, payload []byte) []byte {
+	wire := appendUint(nil, "u16", uint64(2+len(payload)))
+	wire = appendUint(wire, "u16", uint64(cmd.Value))
+	return append(wire, payload...)
+}
+
+func genTests(schema *Schema) string {
+	p := &printer{}
+	p.WriteString(generatedHeader)
+	p.P("package zmqencdec")
+	p.P("")
+	p.P("import (")
+	p.P("\"encoding/hex\"")
+	p.P("\"testing\"")
+	p.P("")
+	p.P("\"gotest.tools/assert\"")
+	p.P(")")
+	p.P("")
+
+	for _, cmd := range schema.Commands {
+		if cmd.Request == nil {
+			continue
+		}
+		s := &sampler{schema: schema, next: 1000}
+		literal, payload := s.fields(schema.structs[cmd.Request.Layout])
+		wire := frame(cmd, payload)
+
+		p.P("func TestGoldenEncode%sRequest(t *testing.T) {", camelName(cmd.Name))
+		p.P("expect := %q", hex.EncodeToString(wire))
+		p.P("msg := &Message{")
+		p.P("Header: MsgHeader{Command: %s},", cmd.Name)
+		p.P("%s: %s{%s},", cmd.Request.Section, schema.sections[cmd.Request.Section].Type, literal)
+		p.P("}")
+		p.P("")
+		p.P("l, err := RequestLength(msg)")
+		p.P("if err != nil {")
+		p.P("t.Fatalf(\"RequestLength failed. Err:%%v\", err)")
+		p.P("}")
+		p.P("assert.Equal(t, uint16(%d), l, \"\\nThe two length should be the same.\")", len(wire)-2)
+		p.P("msg.Header.Length = l")
+		p.P("")
+		p.P("encoder := &ZmqEncoder{}")
+		p.P("bytes, err := encoder.Encode(msg)")
+		p.P("if err != nil {")
+		p.P("t.Fatalf(\"Encode failed. Err:%%v\", err)")
+		p.P("}")
+		p.P("assert.Equal(t, expect, hex.EncodeToString(bytes), \"\\nThe two array should be the same.\")")
+		p.P("}")
+		p.P("")
+	}
+
+	for _, cmd := range schema.Commands {
+		if cmd.Response == nil {
+			continue
+		}
+		s := &sampler{schema: schema, next: 2000}
+		literal, payload := s.fields(schema.structs[cmd.Response.Layout])
+		wire := frame(cmd, payload)
+
+		p.P("func TestGoldenDecode%sResponse(t *testing.T) {", camelName(cmd.Name))
+		p.P("str := %q", hex.EncodeToString(wire))
+		p.P("expect := %s{%s}", schema.sections[cmd.Response.Section].Type, literal)
+		p.P("")
+		p.P("encoder := &ZmqEncoder{}")
+		p.P("bytes, _ := hex.DecodeString(str)")
+		p.P("msg, err := encoder.Decode(bytes)")
+		p.P("if err != nil {")
+		p.P("t.Fatalf(\"Decode failed. Err:%%v\", err)")
+		p.P("}")
+		p.P("assert.Equal(t, MsgHeader{Length: %d, Command: %s}, msg.Header, \"\\nThe two ZMQ message header should be the same.\")", len(wire)-2, cmd.Name)
+		p.P("assert.DeepEqual(t, expect, msg.%s)", cmd.Response.Section)
+		p.P("}")
+		p.P("")
+	}
+	return p.String()
+}
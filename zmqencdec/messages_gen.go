@@ -0,0 +1,190 @@
+// Code generated by zmqgen from dfxp.schema. DO NOT EDIT.
+
+package zmqencdec
+
+import "fmt"
+
+const (
+	ZMQ_CMD_NONE            ZmqMessageType = 0
+	ZMQ_CMD_START           ZmqMessageType = 1
+	ZMQ_CMD_STOP            ZmqMessageType = 2
+	ZMQ_CMD_SHUTDOWN        ZmqMessageType = 3
+	ZMQ_CMD_ADD_TUNNELS     ZmqMessageType = 4
+	ZMQ_CMD_DEL_TUNNELS     ZmqMessageType = 5
+	ZMQ_CMD_DEL_ALL_TUNNELS ZmqMessageType = 6
+	ZMQ_CMD_GET_INFO        ZmqMessageType = 7
+	ZMQ_CMD_ERROR           ZmqMessageType = 8
+	ZMQ_CMD_MSG_ERROR       ZmqMessageType = 9
+	ZMQ_CMD_INVALID         ZmqMessageType = 10
+	// ZMQ_CMD_METRICS - provisional value, not documented by dfxp yet
+	ZMQ_CMD_METRICS ZmqMessageType = 11
+)
+
+type Tunnel struct {
+	TeidIn  uint32
+	TeidOut uint32
+	UeIpV4  uint32
+	SrvIpV4 uint32
+}
+
+type JsonTunnel struct {
+	TeidIn  uint32
+	TeidOut uint32
+	UeIpV4  string
+	SrvIpV4 string
+}
+
+type Metric struct {
+	PktRx  uint64
+	PktTx  uint64
+	ByteRx uint64
+	ByteTx uint64
+	BpsRx  uint64
+	BpsTx  uint64
+	ErrRx  uint64
+	ErrTx  uint64
+}
+
+type ProtocolMetrics struct {
+	Length   uint16
+	Command  uint16
+	FlowId   uint32
+	Protocol uint32
+	Metric   Metric
+}
+
+type MsgStartRequest struct {
+	FlowId          uint32
+	MetricsInterval uint32
+}
+
+type MsgStartResponse struct {
+	FlowId    uint32
+	Publisher string
+}
+
+type MsgResponse struct {
+	FlowId uint32
+}
+
+type MsgTunnelResponse struct {
+	FlowId  uint32
+	Tunnels uint32
+}
+
+type MsgStopRequest struct {
+	FlowId uint32
+}
+
+type MsgAddTunnelsRequest struct {
+	FlowId  uint32
+	Tunnels []Tunnel
+}
+
+type MsgAddJsonTunnelsRequest struct {
+	FlowId      uint32
+	JsonTunnels []JsonTunnel
+}
+
+type MsgDelTunnelsRequest struct {
+	FlowId uint32
+	Teids  []uint32
+}
+
+type MsgDelAllTunnelsRequest struct {
+	FlowId uint32
+}
+
+type MsgGetInfoRequest struct {
+	FlowId uint32
+}
+
+type MsgGetInfoResponse struct {
+	FlowId  uint32
+	Version string
+}
+
+type MsgMetrics struct {
+	FlowId  uint32
+	Metrics []ProtocolMetrics
+}
+
+type ErrorResponse struct {
+	Error string
+}
+
+type MsgErrorResponse struct {
+	FlowId uint32
+	Error  string
+}
+
+type Message struct {
+	Header               MsgHeader
+	StartRequest         MsgStartRequest
+	StopRequest          MsgStopRequest
+	StartResponse        MsgStartResponse
+	Response             MsgResponse
+	AddTunnelRequest     MsgAddTunnelsRequest
+	AddJsonTunnelRequest MsgAddJsonTunnelsRequest
+	DelTunnelsRequest    MsgDelTunnelsRequest
+	DelAllTunnelsRequest MsgDelAllTunnelsRequest
+	TunnelResponse       MsgTunnelResponse
+	GetInfoRequest       MsgGetInfoRequest
+	GetInfoResponse      MsgGetInfoResponse
+	ErrorResponse        ErrorResponse
+	MsgErrorResponse     MsgErrorResponse
+	Metrics              MsgMetrics
+}
+
+var commandNames = map[ZmqMessageType]string{
+	ZMQ_CMD_NONE:            "NONE",
+	ZMQ_CMD_START:           "START",
+	ZMQ_CMD_STOP:            "STOP",
+	ZMQ_CMD_SHUTDOWN:        "SHUTDOWN",
+	ZMQ_CMD_ADD_TUNNELS:     "ADD_TUNNELS",
+	ZMQ_CMD_DEL_TUNNELS:     "DEL_TUNNELS",
+	ZMQ_CMD_DEL_ALL_TUNNELS: "DEL_ALL_TUNNELS",
+	ZMQ_CMD_GET_INFO:        "GET_INFO",
+	ZMQ_CMD_ERROR:           "ERROR",
+	ZMQ_CMD_MSG_ERROR:       "MSG_ERROR",
+	ZMQ_CMD_INVALID:         "INVALID",
+	ZMQ_CMD_METRICS:         "METRICS",
+}
+
+var requestSections = map[ZmqMessageType]string{
+	ZMQ_CMD_START:           "StartRequest",
+	ZMQ_CMD_STOP:            "StopRequest",
+	ZMQ_CMD_ADD_TUNNELS:     "AddTunnelRequest",
+	ZMQ_CMD_DEL_TUNNELS:     "DelTunnelsRequest",
+	ZMQ_CMD_DEL_ALL_TUNNELS: "DelAllTunnelsRequest",
+	ZMQ_CMD_GET_INFO:        "GetInfoRequest",
+}
+
+var responseSections = map[ZmqMessageType]string{
+	ZMQ_CMD_START:           "StartResponse",
+	ZMQ_CMD_STOP:            "Response",
+	ZMQ_CMD_ADD_TUNNELS:     "TunnelResponse",
+	ZMQ_CMD_DEL_TUNNELS:     "TunnelResponse",
+	ZMQ_CMD_DEL_ALL_TUNNELS: "TunnelResponse",
+	ZMQ_CMD_GET_INFO:        "GetInfoResponse",
+	ZMQ_CMD_ERROR:           "ErrorResponse",
+	ZMQ_CMD_MSG_ERROR:       "MsgErrorResponse",
+	ZMQ_CMD_METRICS:         "Metrics",
+}
+
+func (t ZmqMessageType) String() string {
+	if name, ok := commandNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("COMMAND_%d", uint16(t))
+}
+
+// RequestSection - name of the Message field carrying the request of cmd, "" if none
+func RequestSection(cmd ZmqMessageType) string {
+	return requestSections[cmd]
+}
+
+// ResponseSection - name of the Message field carrying the response of cmd, "" if none
+func ResponseSection(cmd ZmqMessageType) string {
+	return responseSections[cmd]
+}
@@ -0,0 +1,184 @@
+package zmqencdec
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ProtocolMetrics.Protocol values, in the README order
+const (
+	METRICS_PROTOCOL_UDP uint32 = iota
+	METRICS_PROTOCOL_TCP
+	METRICS_PROTOCOL_HTTP
+	METRICS_PROTOCOL_ICMP
+)
+
+// MetricCounter - counter of a Metric, in the frame order
+type MetricCounter int
+
+const (
+	COUNTER_PKT_RX MetricCounter = iota
+	COUNTER_PKT_TX
+	COUNTER_BYTE_RX
+	COUNTER_BYTE_TX
+	COUNTER_BPS_RX
+	COUNTER_BPS_TX
+	COUNTER_ERR_RX
+	COUNTER_ERR_TX
+	// METRIC_COUNTERS - number of counters of a Metric
+	METRIC_COUNTERS
+)
+
+var counterNames = [METRIC_COUNTERS]string{"PktRx", "PktTx", "ByteRx", "ByteTx", "BpsRx", "BpsTx", "ErrRx", "ErrTx"}
+
+func (counter MetricCounter) String() string {
+	if counter >= 0 && counter < METRIC_COUNTERS {
+		return counterNames[counter]
+	}
+	return fmt.Sprintf("MetricCounter(%d)", int(counter))
+}
+
+const (
+	// metricsHeaderSize - Length, Command, FlowId and the record count
+	metricsHeaderSize = 2 + 2 + 4 + 4
+	// protocolMetricsSize - Length, Command, FlowId, Protocol and the counters
+	protocolMetricsSize = 2 + 2 + 4 + 4 + 8*int(METRIC_COUNTERS)
+)
+
+// MetricsView - METRICS frame read in place: nothing is copied or allocated,
+// so the view is only valid as long as the frame is not reused
+type MetricsView struct {
+	frame []byte
+}
+
+// ViewMetrics - view of a METRICS frame, checked to hold exactly the records it announces
+func ViewMetrics(frame []byte) (MetricsView, error) {
+	if len(frame) < metricsHeaderSize {
+		return MetricsView{}, fmt.Errorf("metrics frame too short [%d]", len(frame))
+	}
+	if command := ZmqMessageType(binary.BigEndian.Uint16(frame[2:])); command != ZMQ_CMD_METRICS {
+		return MetricsView{}, fmt.Errorf("Wrong message command [%d]", command)
+	}
+	count := uint64(binary.BigEndian.Uint32(frame[8:]))
+	if size := uint64(metricsHeaderSize) + count*uint64(protocolMetricsSize); size > uint64(len(frame)) {
+		return MetricsView{}, fmt.Errorf("metrics frame truncated: %d records need %d bytes, got %d", count, size, len(frame))
+	} else if size < uint64(len(frame)) {
+		return MetricsView{}, fmt.Errorf("%d trailing bytes in frame", uint64(len(frame))-size)
+	}
+	return MetricsView{frame: frame}, nil
+}
+
+// Header - Length and Command of the frame
+func (view MetricsView) Header() MsgHeader {
+	return MsgHeader{Length: binary.BigEndian.Uint16(view.frame), Command: ZMQ_CMD_METRICS}
+}
+
+// FlowId - flow the metrics were published for
+func (view MetricsView) FlowId() uint32 {
+	return binary.BigEndian.Uint32(view.frame[4:])
+}
+
+// Len - number of protocol records
+func (view MetricsView) Len() int {
+	if view.frame == nil {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(view.frame[8:]))
+}
+
+// At - protocol record i, 0 <= i < Len()
+func (view MetricsView) At(i int) ProtocolMetricsView {
+	offset := metricsHeaderSize + i*protocolMetricsSize
+	return ProtocolMetricsView(view.frame[offset : offset+protocolMetricsSize])
+}
+
+// Iter - iterator over the protocol records
+func (view MetricsView) Iter() MetricsIterator {
+	return MetricsIterator{view: view}
+}
+
+// Decode - copy the frame into metrics, reusing the capacity of metrics.Metrics
+func (view MetricsView) Decode(metrics *MsgMetrics) {
+	metrics.FlowId = view.FlowId()
+	metrics.Metrics = metrics.Metrics[:0]
+	for it := view.Iter(); it.Next(); {
+		metrics.Metrics = append(metrics.Metrics, it.Record().ProtocolMetrics())
+	}
+}
+
+// MetricsIterator - walk the records of a MetricsView:
+//
+//	for it := view.Iter(); it.Next(); {
+//		record := it.Record()
+//	}
+type MetricsIterator struct {
+	view   MetricsView
+	next   int
+	record ProtocolMetricsView
+}
+
+// Next - advance to the next record, false after the last one
+func (it *MetricsIterator) Next() bool {
+	if it.next >= it.view.Len() {
+		it.record = nil
+		return false
+	}
+	it.record = it.view.At(it.next)
+	it.next++
+	return true
+}
+
+// Record - record the last Next stopped on
+func (it *MetricsIterator) Record() ProtocolMetricsView {
+	return it.record
+}
+
+// ProtocolMetricsView - one ProtocolMetrics record of a METRICS frame
+type ProtocolMetricsView []byte
+
+func (record ProtocolMetricsView) Length() uint16 {
+	return binary.BigEndian.Uint16(record)
+}
+
+func (record ProtocolMetricsView) Command() uint16 {
+	return binary.BigEndian.Uint16(record[2:])
+}
+
+func (record ProtocolMetricsView) FlowId() uint32 {
+	return binary.BigEndian.Uint32(record[4:])
+}
+
+// Protocol - one of the METRICS_PROTOCOL values
+func (record ProtocolMetricsView) Protocol() uint32 {
+	return binary.BigEndian.Uint32(record[8:])
+}
+
+// Counter - value of counter, 0 <= counter < METRIC_COUNTERS
+func (record ProtocolMetricsView) Counter(counter MetricCounter) uint64 {
+	return binary.BigEndian.Uint64(record[12+8*int(counter):])
+}
+
+// Metric - every counter of the record
+func (record ProtocolMetricsView) Metric() Metric {
+	return Metric{
+		PktRx:  record.Counter(COUNTER_PKT_RX),
+		PktTx:  record.Counter(COUNTER_PKT_TX),
+		ByteRx: record.Counter(COUNTER_BYTE_RX),
+		ByteTx: record.Counter(COUNTER_BYTE_TX),
+		BpsRx:  record.Counter(COUNTER_BPS_RX),
+		BpsTx:  record.Counter(COUNTER_BPS_TX),
+		ErrRx:  record.Counter(COUNTER_ERR_RX),
+		ErrTx:  record.Counter(COUNTER_ERR_TX),
+	}
+}
+
+// ProtocolMetrics - copy of the record
+func (record ProtocolMetricsView) ProtocolMetrics() ProtocolMetrics {
+	return ProtocolMetrics{
+		Length:   record.Length(),
+		Command:  record.Command(),
+		FlowId:   record.FlowId(),
+		Protocol: record.Protocol(),
+		Metric:   record.Metric(),
+	}
+}
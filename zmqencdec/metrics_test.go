@@ -0,0 +1,169 @@
+package zmqencdec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+// metricsFrame - METRICS frame of flowId with protocols records
+func metricsFrame(flowId uint32, protocols int) []byte {
+	frame := make([]byte, 0, metricsHeaderSize+protocols*protocolMetricsSize)
+	frame = binary.BigEndian.AppendUint16(frame, uint16(metricsHeaderSize-2+protocols*protocolMetricsSize))
+	frame = binary.BigEndian.AppendUint16(frame, uint16(ZMQ_CMD_METRICS))
+	frame = binary.BigEndian.AppendUint32(frame, flowId)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(protocols))
+	for i := 0; i < protocols; i++ {
+		frame = binary.BigEndian.AppendUint16(frame, uint16(protocolMetricsSize-2))
+		frame = binary.BigEndian.AppendUint16(frame, uint16(ZMQ_CMD_METRICS))
+		frame = binary.BigEndian.AppendUint32(frame, flowId)
+		frame = binary.BigEndian.AppendUint32(frame, uint32(i)%4)
+		for counter := COUNTER_PKT_RX; counter < METRIC_COUNTERS; counter++ {
+			frame = binary.BigEndian.AppendUint64(frame, uint64(1000*i)+uint64(counter))
+		}
+	}
+	return frame
+}
+
+func TestMetricsView(t *testing.T) {
+	str := "00a2000b000007d10000000207d407d5000007d6000007d700000000000007d900000000000007da00000000000007db00000000000007dc00000000000007dd00000000000007de00000000000007df00000000000007e007e207e3000007e4000007e500000000000007e700000000000007e800000000000007e900000000000007ea00000000000007eb00000000000007ec00000000000007ed00000000000007ee"
+	frame, _ := hex.DecodeString(str)
+	msg, err := (&ZmqEncoder{}).Decode(frame)
+	if err != nil {
+		t.Fatalf("Decode failed. Err:%v", err)
+	}
+
+	view, err := ViewMetrics(frame)
+	if err != nil {
+		t.Fatalf("ViewMetrics failed. Err:%v", err)
+	}
+	assert.Equal(t, msg.Header, view.Header())
+	assert.Equal(t, uint32(2001), view.FlowId())
+	assert.Equal(t, 2, view.Len())
+	var records []ProtocolMetrics
+	for it := view.Iter(); it.Next(); {
+		records = append(records, it.Record().ProtocolMetrics())
+	}
+	assert.DeepEqual(t, msg.Metrics.Metrics, records)
+	assert.Equal(t, uint64(2029), view.At(1).Counter(COUNTER_ERR_RX))
+	assert.Equal(t, "ErrRx", COUNTER_ERR_RX.String())
+
+	var metrics MsgMetrics
+	view.Decode(&metrics)
+	assert.DeepEqual(t, msg.Metrics, metrics)
+}
+
+func TestViewMetricsErrors(t *testing.T) {
+	frame := metricsFrame(1, 2)
+
+	_, err := ViewMetrics(frame[:10])
+	assert.ErrorContains(t, err, "metrics frame too short")
+	_, err = ViewMetrics(frame[:len(frame)-1])
+	assert.ErrorContains(t, err, "metrics frame truncated: 2 records need 164 bytes, got 163")
+
+	_, err = ViewMetrics(append(frame, 0))
+	assert.ErrorContains(t, err, "1 trailing bytes in frame")
+
+	binary.BigEndian.PutUint32(frame[8:], 0xffffffff)
+	_, err = ViewMetrics(frame)
+	assert.ErrorContains(t, err, "metrics frame truncated")
+
+	binary.BigEndian.PutUint16(frame[2:], uint16(ZMQ_CMD_START))
+	_, err = ViewMetrics(frame)
+	assert.ErrorContains(t, err, "Wrong message command")
+
+	empty, err := ViewMetrics(metricsFrame(1, 0))
+	if err != nil {
+		t.Fatalf("ViewMetrics failed. Err:%v", err)
+	}
+	it := empty.Iter()
+	assert.Assert(t, !it.Next())
+}
+
+func TestMetricsViewAllocs(t *testing.T) {
+	frame := metricsFrame(7, 64)
+	metrics := MsgMetrics{Metrics: make([]ProtocolMetrics, 0, 64)}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		view, _ := ViewMetrics(frame)
+		var total uint64
+		for it := view.Iter(); it.Next(); {
+			total += it.Record().Counter(COUNTER_BYTE_RX)
+		}
+		view.Decode(&metrics)
+	})
+	assert.Equal(t, float64(0), allocs, "\nThe metrics view must not allocate.")
+	assert.Equal(t, 64, len(metrics.Metrics))
+}
+
+// decodeMetricsReflect - the bytes.Buffer and binary.Read decoding of the
+// other responses applied to METRICS, kept as benchmark reference
+func decodeMetricsReflect(frame []byte) *Message {
+	msg := &Message{}
+	buffer := bytes.NewBuffer(frame)
+	binary.Read(buffer, binary.BigEndian, &msg.Header.Length)
+	binary.Read(buffer, binary.BigEndian, &msg.Header.Command)
+	binary.Read(buffer, binary.BigEndian, &msg.Metrics.FlowId)
+	var protocols uint32
+	binary.Read(buffer, binary.BigEndian, &protocols)
+	msg.Metrics.Metrics = make([]ProtocolMetrics, protocols)
+	for i := range msg.Metrics.Metrics {
+		binary.Read(buffer, binary.BigEndian, &msg.Metrics.Metrics[i])
+	}
+	return msg
+}
+
+func TestDecodeMetricsReflect(t *testing.T) {
+	frame := metricsFrame(7, 5)
+	msg, err := (&ZmqEncoder{}).DecodeMetrics(frame)
+	if err != nil {
+		t.Fatalf("DecodeMetrics failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, decodeMetricsReflect(frame).Metrics, msg.Metrics)
+}
+
+func BenchmarkDecodeMetrics(b *testing.B) {
+	for _, protocols := range []int{4, 64, 512} {
+		frame := metricsFrame(7, protocols)
+		encoder := &ZmqEncoder{}
+
+		b.Run(fmt.Sprintf("reflect/%d", protocols), func(b *testing.B) {
+			benchmarkPer(b, protocols, "record", func() {
+				decodeMetricsReflect(frame)
+			})
+		})
+		b.Run(fmt.Sprintf("Decode/%d", protocols), func(b *testing.B) {
+			benchmarkPer(b, protocols, "record", func() {
+				encoder.Decode(frame)
+			})
+		})
+		b.Run(fmt.Sprintf("DecodeMetrics/%d", protocols), func(b *testing.B) {
+			benchmarkPer(b, protocols, "record", func() {
+				encoder.DecodeMetrics(frame)
+			})
+		})
+		b.Run(fmt.Sprintf("view/%d", protocols), func(b *testing.B) {
+			var total uint64
+			benchmarkPer(b, protocols, "record", func() {
+				view, _ := ViewMetrics(frame)
+				for it := view.Iter(); it.Next(); {
+					record := it.Record()
+					for counter := COUNTER_PKT_RX; counter < METRIC_COUNTERS; counter++ {
+						total += record.Counter(counter)
+					}
+				}
+			})
+		})
+		b.Run(fmt.Sprintf("view-decode/%d", protocols), func(b *testing.B) {
+			var metrics MsgMetrics
+			benchmarkPer(b, protocols, "record", func() {
+				view, _ := ViewMetrics(frame)
+				view.Decode(&metrics)
+			})
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package zmqencdec
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// frameReader - bounds checked big endian reads of a frame. The first failure
+// is kept in err and every later read returns zero values, so the generated
+// decoders check err once at the end.
+type frameReader struct {
+	data []byte
+	err  error
+}
+
+// header - Length and Command. The zmq frame size is authoritative: Length
+// is kept as sent, the fields are read from the bytes received.
+func (r *frameReader) header(header *MsgHeader) error {
+	if len(r.data) < 4 {
+		return fmt.Errorf("frame too short [%d]", len(r.data))
+	}
+	header.Length = r.uint16("Length")
+	header.Command = ZmqMessageType(r.uint16("Command"))
+	return nil
+}
+
+// take - next n bytes, nil once the frame is too short
+func (r *frameReader) take(field string, n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = fmt.Errorf("frame truncated at %s: %d bytes needed, %d left", field, n, len(r.data))
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *frameReader) uint8(field string) uint8 {
+	if b := r.take(field, 1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *frameReader) uint16(field string) uint16 {
+	if b := r.take(field, 2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *frameReader) uint32(field string) uint32 {
+	if b := r.take(field, 4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *frameReader) uint64(field string) uint64 {
+	if b := r.take(field, 8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+// rest - the remaining bytes as a string
+func (r *frameReader) rest() string {
+	s := string(r.data)
+	r.data = nil
+	return s
+}
+
+// count - element count of a slice whose elements take at least size bytes,
+// checked against the bytes left before anything is allocated
+func (r *frameReader) count(field string, size int) int {
+	n := r.uint32(field + " number")
+	if r.err == nil && uint64(n)*uint64(size) > uint64(len(r.data)) {
+		r.err = fmt.Errorf("frame truncated at %s: %d elements need %d bytes, %d left", field, n, uint64(n)*uint64(size), len(r.data))
+		return 0
+	}
+	return int(n)
+}
+
+// constant - skip a const field, which must hold value
+func (r *frameReader) constant(field string, size int, value uint64) {
+	var got uint64
+	switch size {
+	case 1:
+		got = uint64(r.uint8(field))
+	case 2:
+		got = uint64(r.uint16(field))
+	case 4:
+		got = uint64(r.uint32(field))
+	default:
+		got = r.uint64(field)
+	}
+	if r.err == nil && got != value {
+		r.err = fmt.Errorf("invalid %s [%d], expected %d", field, got, value)
+	}
+}
+
+// end - the first read error, or an error if bytes are left over
+func (r *frameReader) end() error {
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.data) > 0 {
+		return fmt.Errorf("%d trailing bytes in frame", len(r.data))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package zmqencdec
+
+import "reflect"
+
+// RequestFlowId - FlowId of the request section of msg, 0 if none
+func (msg *Message) RequestFlowId() uint32 {
+	return msg.sectionFlowId(RequestSection(msg.Header.Command))
+}
+
+// ResponseFlowId - FlowId of the response section of msg, 0 if none
+func (msg *Message) ResponseFlowId() uint32 {
+	return msg.sectionFlowId(ResponseSection(msg.Header.Command))
+}
+
+func (msg *Message) sectionFlowId(section string) uint32 {
+	if section == "" {
+		return 0
+	}
+	flowId := reflect.ValueOf(msg).Elem().FieldByName(section).FieldByName("FlowId")
+	if !flowId.IsValid() || flowId.Kind() != reflect.Uint32 {
+		return 0
+	}
+	return uint32(flowId.Uint())
+}
+
+// Section - pointer to the named section of msg, e.g. *MsgStartRequest for
+// "StartRequest", nil for an unknown name
+func (msg *Message) Section(section string) interface{} {
+	if section == "" {
+		return nil
+	}
+	field := reflect.ValueOf(msg).Elem().FieldByName(section)
+	if !field.IsValid() {
+		return nil
+	}
+	return field.Addr().Interface()
+}
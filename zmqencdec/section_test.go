@@ -0,0 +1,26 @@
+package zmqencdec
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestSectionFlowId(t *testing.T) {
+	msg := &Message{
+		Header:       MsgHeader{Command: ZMQ_CMD_START},
+		StartRequest: MsgStartRequest{FlowId: 7},
+	}
+	assert.Equal(t, uint32(7), msg.RequestFlowId())
+	assert.Equal(t, uint32(0), msg.ResponseFlowId())
+
+	msg = &Message{
+		Header:  MsgHeader{Command: ZMQ_CMD_METRICS},
+		Metrics: MsgMetrics{FlowId: 9},
+	}
+	assert.Equal(t, uint32(0), msg.RequestFlowId())
+	assert.Equal(t, uint32(9), msg.ResponseFlowId())
+
+	msg = &Message{Header: MsgHeader{Command: ZMQ_CMD_SHUTDOWN}}
+	assert.Equal(t, uint32(0), msg.RequestFlowId())
+}
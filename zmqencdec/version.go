@@ -0,0 +1,104 @@
+package zmqencdec
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Version - dfxp semantic version
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// Capabilities - what the connected dfxp reported in its GET_INFO response and
+// the message variants derived from its version. dfxp documents one layout
+// per command so far, the flags mark what cannot be assumed for every version.
+type Capabilities struct {
+	Version Version
+	Info    string
+
+	// Metrics - METRICS publishes use the layout of README "Metrics message"
+	// and the provisional ZMQ_CMD_METRICS, known for dfxp 1.x only
+	Metrics bool
+}
+
+// metricsVersions - [first, end) of the dfxp versions publishing the known
+// METRICS layout
+var metricsVersions = [2]Version{{Major: 1}, {Major: 2}}
+
+// NewCapabilities - capabilities of a dfxp reporting version, info being the
+// whole GET_INFO version string
+func NewCapabilities(version Version, info string) *Capabilities {
+	return &Capabilities{
+		Version: version,
+		Info:    info,
+		Metrics: version.AtLeast(metricsVersions[0]) && !version.AtLeast(metricsVersions[1]),
+	}
+}
+
+// versionRegexp, majorRegexp - a whole version token: numbers inside words,
+// e.g. the 2 of "dfxp-2", are not versions. A major.minor token is preferred
+// to a bare major one.
+var (
+	versionRegexp = regexp.MustCompile(`(?:^|[^\w.-])v?(\d+)\.(\d+)(?:\.(\d+))?(?:$|[^\w.])`)
+	majorRegexp   = regexp.MustCompile(`(?:^|[^\w.-])v?(\d+)(?:$|[^\w.])`)
+)
+
+// ParseVersion - parse "1.2.3", "v1.2" or "dfxp v1.1" style versions
+func ParseVersion(s string) (Version, error) {
+	match := versionRegexp.FindStringSubmatch(s)
+	if match == nil {
+		match = majorRegexp.FindStringSubmatch(s)
+	}
+	if match == nil {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+
+	var parts [3]int
+	for i, part := range match[1:] {
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q: %v", s, err)
+		}
+		parts[i] = n
+	}
+	return Version{Major: parts[0], Minor: parts[1], Patch: parts[2]}, nil
+}
+
+// Compare - -1, 0 or 1 when v is older, equal or newer than other
+func (v Version) Compare(other Version) int {
+	switch {
+	case v.Major != other.Major:
+		return compareInt(v.Major, other.Major)
+	case v.Minor != other.Minor:
+		return compareInt(v.Minor, other.Minor)
+	default:
+		return compareInt(v.Patch, other.Patch)
+	}
+}
+
+// AtLeast - v >= other
+func (v Version) AtLeast(other Version) bool {
+	return v.Compare(other) >= 0
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
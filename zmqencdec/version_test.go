@@ -0,0 +1,43 @@
+package zmqencdec
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in     string
+		expect Version
+	}{
+		{"1.0.0", Version{1, 0, 0}},
+		{"dfxp v1.1", Version{1, 1, 0}},
+		{"v2", Version{2, 0, 0}},
+		{"dfxp 3.4.5-rc1", Version{3, 4, 5}},
+		{"dfxp-2 build 1.4.0", Version{1, 4, 0}},
+		{"dfxp2 v1.1", Version{1, 1, 0}},
+		{"dfxp build 7 v1.2", Version{1, 2, 0}},
+		{"dfxp (v2.0)", Version{2, 0, 0}},
+	}
+	for _, test := range tests {
+		v, err := ParseVersion(test.in)
+		if err != nil {
+			t.Fatalf("ParseVersion(%q) failed. Err:%v", test.in, err)
+		}
+		assert.Equal(t, test.expect, v, "\nversion %q", test.in)
+	}
+
+	for _, in := range []string{"dfxp", "dfxp-2", "dfxp2", "1.2.3.4"} {
+		_, err := ParseVersion(in)
+		assert.ErrorContains(t, err, "invalid version", "\nversion %q", in)
+	}
+}
+
+func TestCompareVersion(t *testing.T) {
+	assert.Equal(t, 0, Version{1, 1, 0}.Compare(Version{1, 1, 0}))
+	assert.Equal(t, -1, Version{1, 1, 0}.Compare(Version{1, 2, 0}))
+	assert.Equal(t, 1, Version{2, 0, 0}.Compare(Version{1, 9, 9}))
+	assert.Assert(t, Version{1, 0, 1}.AtLeast(Version{1, 0, 0}))
+	assert.Equal(t, "1.2.3", Version{1, 2, 3}.String())
+}
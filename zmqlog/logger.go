@@ -0,0 +1,85 @@
+package zmqlog
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"sync/atomic"
+)
+
+// Logger - leveled logger taking slog style key/value pairs; *slog.Logger implements it
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+var defaultLogger atomic.Pointer[Logger]
+
+// Default - logger set with SetDefault, slog.Default() otherwise
+func Default() Logger {
+	if logger := defaultLogger.Load(); logger != nil {
+		return *logger
+	}
+	return slog.Default()
+}
+
+// SetDefault - logger used by the packages when none is injected, nil restores slog.Default()
+func SetDefault(logger Logger) {
+	if logger == nil {
+		defaultLogger.Store(nil)
+		return
+	}
+	defaultLogger.Store(&logger)
+}
+
+// Or - logger when set, Default() otherwise
+func Or(logger Logger) Logger {
+	if logger != nil {
+		return logger
+	}
+	return Default()
+}
+
+// Discard - logger dropping everything
+func Discard() Logger {
+	return discard{}
+}
+
+type discard struct{}
+
+func (discard) Debug(msg string, args ...any) {}
+func (discard) Info(msg string, args ...any)  {}
+func (discard) Warn(msg string, args ...any)  {}
+func (discard) Error(msg string, args ...any) {}
+
+type contextKey struct{}
+
+// NewContext - ctx carrying logger, e.g. for the interceptors
+func NewContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// ContextOr - ctx carrying logger, unless it already carries one
+func ContextOr(ctx context.Context, logger Logger) context.Context {
+	if _, ok := ctx.Value(contextKey{}).(Logger); ok {
+		return ctx
+	}
+	return NewContext(ctx, logger)
+}
+
+// FromContext - logger of ctx, Default() when it has none
+func FromContext(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(contextKey{}).(Logger); ok {
+		return logger
+	}
+	return Default()
+}
+
+// Hex - frame attribute, hex encoded only when a handler formats it
+type Hex []byte
+
+func (h Hex) LogValue() slog.Value {
+	return slog.StringValue(hex.EncodeToString(h))
+}
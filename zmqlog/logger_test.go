@@ -0,0 +1,41 @@
+package zmqlog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestDefault(t *testing.T) {
+	assert.Equal(t, slog.Default(), Default())
+
+	buffer := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(buffer, nil))
+	SetDefault(logger)
+	defer SetDefault(nil)
+
+	assert.Equal(t, Logger(logger), Default())
+	assert.Equal(t, Logger(logger), Or(nil))
+	assert.Equal(t, Discard(), Or(Discard()))
+	assert.Equal(t, Logger(logger), FromContext(context.Background()))
+	assert.Equal(t, Discard(), FromContext(NewContext(context.Background(), Discard())))
+
+	ctx := ContextOr(NewContext(context.Background(), Discard()), logger)
+	assert.Equal(t, Discard(), FromContext(ctx), "\nA logger already in the context must be kept.")
+	assert.Equal(t, Logger(logger), FromContext(ContextOr(context.Background(), logger)))
+}
+
+func TestHexOnlyAtDebug(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(buffer, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	logger.Debug("decode", "frame", Hex{0x00, 0x06, 0x00, 0x07})
+	assert.Equal(t, "", buffer.String(), "\nDebug records must be dropped at info level.")
+
+	logger.Info("decode", "frame", Hex{0x00, 0x06, 0x00, 0x07})
+	assert.Assert(t, strings.Contains(buffer.String(), "frame=00060007"), buffer.String())
+}
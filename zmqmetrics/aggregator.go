@@ -0,0 +1,341 @@
+package zmqmetrics
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+	"zmqclient/zmqencdec"
+)
+
+// DefaultWindow - sliding window of the aggregator when none is set
+const DefaultWindow = time.Minute
+
+const counters = int(zmqencdec.METRIC_COUNTERS)
+
+// AggregatorOptions - parameters of NewAggregator
+type AggregatorOptions struct {
+	// Window - samples older than Window before the newest of their series
+	// leave the moving average, min, max and percentile; 0 for DefaultWindow
+	Window time.Duration
+	// Now - clock of Handle, nil for time.Now
+	Now func() time.Time
+}
+
+// SeriesKey - one flow and protocol
+type SeriesKey struct {
+	FlowId   uint32
+	Protocol uint32
+}
+
+// CounterStats - one counter of a series. For counts (packets, bytes,
+// errors) the windowed figures are rates per second; for gauges (bps) they
+// are the published values.
+type CounterStats struct {
+	// Last - value of the newest sample
+	Last uint64
+	// Delta - growth between the two newest samples, 0 for gauges
+	Delta uint64
+	// Total - growth since the first sample, 0 for gauges. A value lower than
+	// the previous one is taken as a counter reset.
+	Total uint64
+	// Rate - Delta per second, or Last for gauges
+	Rate float64
+	// Mean, Min, Max, P95 - of Rate over the window
+	Mean float64
+	Min  float64
+	Max  float64
+	P95  float64
+}
+
+// SeriesSnapshot - state of one flow and protocol
+type SeriesSnapshot struct {
+	SeriesKey
+	// Samples - samples received, Window - samples in the window
+	Samples  int
+	Window   int
+	First    time.Time
+	Last     time.Time
+	Counters [counters]CounterStats
+}
+
+// Counter - stats of counter
+func (series *SeriesSnapshot) Counter(counter zmqencdec.MetricCounter) CounterStats {
+	return series.Counters[counter]
+}
+
+// Totals - sum over several series: Total of the counts and Rate of every
+// counter, e.g. the bps of a flow over all its protocols
+type Totals struct {
+	Series int
+	Total  [counters]uint64
+	Rate   [counters]float64
+}
+
+func (totals *Totals) add(series *SeriesSnapshot) {
+	totals.Series++
+	for i := range series.Counters {
+		totals.Total[i] += series.Counters[i].Total
+		totals.Rate[i] += series.Counters[i].Rate
+	}
+}
+
+// Snapshot - every series of the aggregator, sorted by flow and protocol
+type Snapshot struct {
+	Time   time.Time
+	Series []SeriesSnapshot
+}
+
+// Flow - totals of the series of flowId
+func (snapshot *Snapshot) Flow(flowId uint32) Totals {
+	var totals Totals
+	for i := range snapshot.Series {
+		if snapshot.Series[i].FlowId == flowId {
+			totals.add(&snapshot.Series[i])
+		}
+	}
+	return totals
+}
+
+// Fleet - totals of every series
+func (snapshot *Snapshot) Fleet() Totals {
+	var totals Totals
+	for i := range snapshot.Series {
+		totals.add(&snapshot.Series[i])
+	}
+	return totals
+}
+
+// Summary - a flow once stopped: its series and their totals
+type Summary struct {
+	FlowId uint32
+	First  time.Time
+	Last   time.Time
+	Series []SeriesSnapshot
+	Totals Totals
+}
+
+// Duration - time between the first and last sample of the flow
+func (summary *Summary) Duration() time.Duration {
+	return summary.Last.Sub(summary.First)
+}
+
+// Aggregator - deltas, rates and sliding window statistics of the samples
+// per flow and protocol. Handle plugs it in as a zmqclient.MetricsHandler.
+type Aggregator struct {
+	options AggregatorOptions
+
+	mu     sync.Mutex
+	series map[SeriesKey]*series
+}
+
+// NewAggregator - create an aggregator, nil options for the defaults
+func NewAggregator(options *AggregatorOptions) *Aggregator {
+	agg := &Aggregator{series: make(map[SeriesKey]*series)}
+	if options != nil {
+		agg.options = *options
+	}
+	if agg.options.Window <= 0 {
+		agg.options.Window = DefaultWindow
+	}
+	if agg.options.Now == nil {
+		agg.options.Now = time.Now
+	}
+	return agg
+}
+
+// Handle - add the samples of a METRICS message, stamped with the aggregator
+// clock; a zmqclient.MetricsHandler
+func (agg *Aggregator) Handle(ctx context.Context, msg *zmqencdec.Message) error {
+	for _, sample := range Samples(msg, agg.options.Now()) {
+		agg.Add(sample)
+	}
+	return nil
+}
+
+// Add - add one sample. Samples older than the newest of their series are
+// dropped.
+func (agg *Aggregator) Add(sample Sample) {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	key := SeriesKey{FlowId: sample.FlowId, Protocol: sample.Protocol}
+	s := agg.series[key]
+	if s == nil {
+		s = &series{key: key}
+		agg.series[key] = s
+	}
+	s.add(sample, agg.options.Window)
+}
+
+// Snapshot - state of every series
+func (agg *Aggregator) Snapshot() Snapshot {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	snapshot := Snapshot{Time: agg.options.Now(), Series: make([]SeriesSnapshot, 0, len(agg.series))}
+	for _, s := range agg.series {
+		snapshot.Series = append(snapshot.Series, s.snapshot())
+	}
+	sortSeries(snapshot.Series)
+	return snapshot
+}
+
+// Summary - series and totals of flowId
+func (agg *Aggregator) Summary(flowId uint32) Summary {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+	return agg.summary(flowId)
+}
+
+// Finish - summary of flowId, whose series are then dropped; call it once
+// the flow is stopped
+func (agg *Aggregator) Finish(flowId uint32) Summary {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+	summary := agg.summary(flowId)
+	for key := range agg.series {
+		if key.FlowId == flowId {
+			delete(agg.series, key)
+		}
+	}
+	return summary
+}
+
+func (agg *Aggregator) summary(flowId uint32) Summary {
+	summary := Summary{FlowId: flowId}
+	for key, s := range agg.series {
+		if key.FlowId != flowId {
+			continue
+		}
+		series := s.snapshot()
+		if summary.First.IsZero() || series.First.Before(summary.First) {
+			summary.First = series.First
+		}
+		if series.Last.After(summary.Last) {
+			summary.Last = series.Last
+		}
+		summary.Series = append(summary.Series, series)
+		summary.Totals.add(&series)
+	}
+	sortSeries(summary.Series)
+	return summary
+}
+
+func sortSeries(series []SeriesSnapshot) {
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].FlowId != series[j].FlowId {
+			return series[i].FlowId < series[j].FlowId
+		}
+		return series[i].Protocol < series[j].Protocol
+	})
+}
+
+// point - rates of one sample, kept while in the window; NaN where a count
+// has no rate yet
+type point struct {
+	time  time.Time
+	rates [counters]float64
+}
+
+// series - samples of one flow and protocol
+type series struct {
+	key     SeriesKey
+	samples int
+	first   time.Time
+	last    Sample
+	stats   [counters]CounterStats
+	window  []point
+}
+
+func (s *series) add(sample Sample, window time.Duration) {
+	if s.samples > 0 && sample.Time.Before(s.last.Time) {
+		return
+	}
+
+	var p point
+	p.time = sample.Time
+	elapsed := sample.Time.Sub(s.last.Time).Seconds()
+	for i := range s.stats {
+		counter := zmqencdec.MetricCounter(i)
+		value := sample.Counter(counter)
+		stats := &s.stats[i]
+		stats.Last = value
+		switch {
+		case Gauge(counter):
+			stats.Rate = float64(value)
+		case s.samples == 0:
+			// the first sample of a count has no rate yet
+			stats.Rate = 0
+			p.rates[i] = math.NaN()
+			continue
+		default:
+			previous := s.last.Counter(counter)
+			if value >= previous {
+				stats.Delta = value - previous
+			} else {
+				stats.Delta = value
+			}
+			stats.Total += stats.Delta
+			if elapsed <= 0 {
+				// duplicate timestamp: a growth with no time span, the
+				// rate of the previous sample stands
+				p.rates[i] = math.NaN()
+				continue
+			}
+			stats.Rate = float64(stats.Delta) / elapsed
+		}
+		p.rates[i] = stats.Rate
+	}
+
+	if s.samples == 0 {
+		s.first = sample.Time
+	}
+	s.samples++
+	s.last = sample
+
+	s.window = append(s.window, p)
+	cut := 0
+	for cut < len(s.window) && sample.Time.Sub(s.window[cut].time) > window {
+		cut++
+	}
+	s.window = append(s.window[:0], s.window[cut:]...)
+}
+
+func (s *series) snapshot() SeriesSnapshot {
+	snapshot := SeriesSnapshot{
+		SeriesKey: s.key,
+		Samples:   s.samples,
+		Window:    len(s.window),
+		First:     s.first,
+		Last:      s.last.Time,
+		Counters:  s.stats,
+	}
+	values := make([]float64, 0, len(s.window))
+	for i := range snapshot.Counters {
+		values = values[:0]
+		for _, p := range s.window {
+			if !math.IsNaN(p.rates[i]) {
+				values = append(values, p.rates[i])
+			}
+		}
+		stats := &snapshot.Counters[i]
+		stats.Mean, stats.Min, stats.Max, stats.P95 = windowStats(values)
+	}
+	return snapshot
+}
+
+// windowStats - mean, min, max and 95th percentile (nearest rank) of values
+func windowStats(values []float64) (mean, min, max, p95 float64) {
+	if len(values) == 0 {
+		return 0, 0, 0, 0
+	}
+	sort.Float64s(values)
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	rank := int(math.Ceil(0.95*float64(len(values)))) - 1
+	return sum / float64(len(values)), values[0], values[len(values)-1], values[rank]
+}
@@ -0,0 +1,124 @@
+package zmqmetrics
+
+import (
+	"context"
+	"testing"
+	"time"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func sampleAt(second int, flowId uint32, protocol uint32, byteRx uint64, bpsRx uint64) Sample {
+	return Sample{
+		Time:     start.Add(time.Duration(second) * time.Second),
+		FlowId:   flowId,
+		Protocol: protocol,
+		Metric:   zmqencdec.Metric{ByteRx: byteRx, BpsRx: bpsRx},
+	}
+}
+
+func TestAggregatorSeries(t *testing.T) {
+	agg := NewAggregator(&AggregatorOptions{Window: 3 * time.Second})
+	agg.Add(sampleAt(0, 1, zmqencdec.METRICS_PROTOCOL_TCP, 1000, 10))
+	agg.Add(sampleAt(1, 1, zmqencdec.METRICS_PROTOCOL_TCP, 3000, 40))
+	agg.Add(sampleAt(3, 1, zmqencdec.METRICS_PROTOCOL_TCP, 7000, 20))
+	// counter reset: the new value is the growth
+	agg.Add(sampleAt(4, 1, zmqencdec.METRICS_PROTOCOL_TCP, 500, 30))
+	// out of order, dropped
+	agg.Add(sampleAt(2, 1, zmqencdec.METRICS_PROTOCOL_TCP, 9000, 90))
+
+	snapshot := agg.Snapshot()
+	assert.Equal(t, 1, len(snapshot.Series))
+	series := snapshot.Series[0]
+	assert.Equal(t, SeriesKey{FlowId: 1, Protocol: zmqencdec.METRICS_PROTOCOL_TCP}, series.SeriesKey)
+	assert.Equal(t, 4, series.Samples)
+	assert.Equal(t, 3, series.Window, "\nThe sample at 0s must have left the window.")
+	assert.Equal(t, start, series.First)
+	assert.Equal(t, start.Add(4*time.Second), series.Last)
+
+	// rates in the window: 2000/s, 2000/s, 500/s
+	assert.Equal(t, CounterStats{Last: 500, Delta: 500, Total: 6500, Rate: 500, Mean: 1500, Min: 500, Max: 2000, P95: 2000},
+		series.Counter(zmqencdec.COUNTER_BYTE_RX))
+	// gauges: 40, 20, 30
+	assert.Equal(t, CounterStats{Last: 30, Rate: 30, Mean: 30, Min: 20, Max: 40, P95: 40},
+		series.Counter(zmqencdec.COUNTER_BPS_RX))
+}
+
+func TestAggregatorFirstSample(t *testing.T) {
+	agg := NewAggregator(nil)
+	agg.Add(sampleAt(0, 1, zmqencdec.METRICS_PROTOCOL_UDP, 1000, 10))
+
+	series := agg.Snapshot().Series[0]
+	assert.Equal(t, CounterStats{Last: 1000}, series.Counter(zmqencdec.COUNTER_BYTE_RX), "\nA single count sample has no rate.")
+	assert.Equal(t, CounterStats{Last: 10, Rate: 10, Mean: 10, Min: 10, Max: 10, P95: 10}, series.Counter(zmqencdec.COUNTER_BPS_RX))
+}
+
+func TestAggregatorDuplicateTime(t *testing.T) {
+	agg := NewAggregator(nil)
+	agg.Add(sampleAt(0, 1, zmqencdec.METRICS_PROTOCOL_TCP, 1000, 10))
+	agg.Add(sampleAt(1, 1, zmqencdec.METRICS_PROTOCOL_TCP, 2000, 20))
+	agg.Add(sampleAt(1, 1, zmqencdec.METRICS_PROTOCOL_TCP, 2500, 30))
+	agg.Add(sampleAt(2, 1, zmqencdec.METRICS_PROTOCOL_TCP, 4500, 40))
+
+	series := agg.Snapshot().Series[0]
+	assert.Equal(t, 4, series.Samples)
+	// rates in the window: 1000/s, none for the duplicate, 2000/s
+	assert.Equal(t, CounterStats{Last: 4500, Delta: 2000, Total: 3500, Rate: 2000, Mean: 1500, Min: 1000, Max: 2000, P95: 2000},
+		series.Counter(zmqencdec.COUNTER_BYTE_RX))
+
+	agg.Add(sampleAt(2, 1, zmqencdec.METRICS_PROTOCOL_TCP, 5000, 50))
+	assert.Equal(t, float64(2000), agg.Snapshot().Series[0].Counter(zmqencdec.COUNTER_BYTE_RX).Rate,
+		"\nA duplicate timestamp must keep the last rate.")
+}
+
+func TestAggregatorTotals(t *testing.T) {
+	agg := NewAggregator(nil)
+	for second, byteRx := range []uint64{0, 100, 300} {
+		agg.Add(sampleAt(second, 1, zmqencdec.METRICS_PROTOCOL_UDP, byteRx, 8*byteRx))
+		agg.Add(sampleAt(second, 1, zmqencdec.METRICS_PROTOCOL_TCP, 2*byteRx, 16*byteRx))
+		agg.Add(sampleAt(second, 2, zmqencdec.METRICS_PROTOCOL_UDP, 3*byteRx, 24*byteRx))
+	}
+
+	snapshot := agg.Snapshot()
+	assert.Equal(t, 3, len(snapshot.Series))
+	flow := snapshot.Flow(1)
+	assert.Equal(t, 2, flow.Series)
+	assert.Equal(t, uint64(900), flow.Total[zmqencdec.COUNTER_BYTE_RX])
+	assert.Equal(t, float64(600), flow.Rate[zmqencdec.COUNTER_BYTE_RX])
+	assert.Equal(t, float64(7200), flow.Rate[zmqencdec.COUNTER_BPS_RX])
+	fleet := snapshot.Fleet()
+	assert.Equal(t, 3, fleet.Series)
+	assert.Equal(t, uint64(1800), fleet.Total[zmqencdec.COUNTER_BYTE_RX])
+
+	summary := agg.Finish(1)
+	assert.Equal(t, uint32(1), summary.FlowId)
+	assert.Equal(t, 2*time.Second, summary.Duration())
+	assert.Equal(t, 2, len(summary.Series))
+	assert.Equal(t, zmqencdec.METRICS_PROTOCOL_UDP, summary.Series[0].Protocol)
+	assert.DeepEqual(t, flow, summary.Totals)
+	assert.Equal(t, 1, len(agg.Snapshot().Series), "\nFinish must drop the series of the flow.")
+}
+
+func TestAggregatorHandle(t *testing.T) {
+	now := start
+	agg := NewAggregator(&AggregatorOptions{Now: func() time.Time { return now }})
+	msg := &zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_METRICS}}
+	msg.Metrics = zmqencdec.MsgMetrics{FlowId: 7, Metrics: []zmqencdec.ProtocolMetrics{
+		{Protocol: zmqencdec.METRICS_PROTOCOL_TCP, Metric: zmqencdec.Metric{PktRx: 10}},
+		{FlowId: 7, Protocol: zmqencdec.METRICS_PROTOCOL_UDP, Metric: zmqencdec.Metric{PktRx: 20}},
+	}}
+	if err := agg.Handle(context.Background(), msg); err != nil {
+		t.Fatalf("Handle failed. Err:%v", err)
+	}
+	now = now.Add(2 * time.Second)
+	msg.Metrics.Metrics[0].Metric.PktRx = 30
+	agg.Handle(context.Background(), msg)
+
+	summary := agg.Summary(7)
+	assert.Equal(t, 2, len(summary.Series))
+	assert.Equal(t, float64(10), summary.Series[1].Counter(zmqencdec.COUNTER_PKT_RX).Rate)
+	assert.Equal(t, uint64(20), summary.Totals.Total[zmqencdec.COUNTER_PKT_RX])
+}
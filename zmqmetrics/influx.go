@@ -0,0 +1,132 @@
+package zmqmetrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"zmqclient/zmqencdec"
+)
+
+// InfluxOptions - parameters of the Influx line protocol sinks
+type InfluxOptions struct {
+	// Measurement - "dfxp" when empty
+	Measurement string
+	// Tags - added to the flow_id and protocol tags of every line, e.g. host
+	Tags map[string]string
+	// Unsigned - write the counters as unsigned integers (InfluxDB 2),
+	// signed ones clamped to MaxInt64 otherwise (InfluxDB 1)
+	Unsigned bool
+
+	// Token - Authorization token of the HTTP sink, empty for none
+	Token string
+	// Client - HTTP client of the HTTP sink, nil for http.DefaultClient
+	Client *http.Client
+	// MaxPacket - datagram size of the UDP sink, 0 for DefaultMaxPacket
+	MaxPacket int
+}
+
+// NewInfluxWriter - Influx line protocol to w, e.g. a file or os.Stdout
+func NewInfluxWriter(w io.Writer, options *InfluxOptions) SampleWriter {
+	return &streamSink{writer: w, format: influxFormat(options)}
+}
+
+// DialInflux - Influx line protocol to an InfluxDB or Telegraf endpoint:
+// udp://host:port, or the http(s) URL of the write API, e.g.
+// http://localhost:8086/write?db=dfxp or
+// http://localhost:8086/api/v2/write?org=lab&bucket=dfxp
+func DialInflux(endpoint string, options *InfluxOptions) (SampleWriter, error) {
+	if options == nil {
+		options = &InfluxOptions{}
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid influx endpoint %q: %v", endpoint, err)
+	}
+	switch u.Scheme {
+	case "udp":
+		return dialPacketSink(u.Host, influxFormat(options), options.MaxPacket)
+	case "http", "https":
+		sink := &httpSink{url: endpoint, header: http.Header{}, client: options.Client, format: influxFormat(options)}
+		sink.header.Set("Content-Type", "text/plain; charset=utf-8")
+		if options.Token != "" {
+			sink.header.Set("Authorization", "Token "+options.Token)
+		}
+		if sink.client == nil {
+			sink.client = http.DefaultClient
+		}
+		return sink, nil
+	}
+	return nil, fmt.Errorf("invalid influx endpoint %q: udp, http or https expected", endpoint)
+}
+
+// AppendInfluxLine - append the line of sample to dst:
+//
+//	dfxp,flow_id=1,protocol=tcp pkt_rx=10i,...,err_tx=0i 1704067200000000000
+func AppendInfluxLine(dst []byte, sample *Sample, options *InfluxOptions) []byte {
+	return influxFormat(options)(dst, sample)
+}
+
+// influxFormat - line format of the options, the measurement and extra tags
+// escaped once
+func influxFormat(options *InfluxOptions) lineFormat {
+	if options == nil {
+		options = &InfluxOptions{}
+	}
+	measurement := options.Measurement
+	if measurement == "" {
+		measurement = "dfxp"
+	}
+	prefix := influxMeasurementEscaper.Replace(measurement)
+	keys := make([]string, 0, len(options.Tags))
+	for key := range options.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var tags string
+	for _, key := range keys {
+		tags += "," + influxTagEscaper.Replace(key) + "=" + influxTagEscaper.Replace(options.Tags[key])
+	}
+	unsigned := options.Unsigned
+
+	return func(dst []byte, sample *Sample) []byte {
+		dst = append(dst, prefix...)
+		dst = append(dst, ",flow_id="...)
+		dst = strconv.AppendUint(dst, uint64(sample.FlowId), 10)
+		dst = append(dst, ",protocol="...)
+		dst = append(dst, influxTagEscaper.Replace(ProtocolName(sample.Protocol))...)
+		dst = append(dst, tags...)
+		for counter := zmqencdec.COUNTER_PKT_RX; counter < zmqencdec.METRIC_COUNTERS; counter++ {
+			if counter == zmqencdec.COUNTER_PKT_RX {
+				dst = append(dst, ' ')
+			} else {
+				dst = append(dst, ',')
+			}
+			dst = append(dst, counterColumns[counter]...)
+			dst = append(dst, '=')
+			value := sample.Counter(counter)
+			if unsigned {
+				dst = strconv.AppendUint(dst, value, 10)
+				dst = append(dst, 'u')
+			} else {
+				if value > math.MaxInt64 {
+					value = math.MaxInt64
+				}
+				dst = strconv.AppendUint(dst, value, 10)
+				dst = append(dst, 'i')
+			}
+		}
+		dst = append(dst, ' ')
+		dst = strconv.AppendInt(dst, sample.Time.UnixNano(), 10)
+		return append(dst, '\n')
+	}
+}
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
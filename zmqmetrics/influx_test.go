@@ -0,0 +1,125 @@
+package zmqmetrics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+func TestInfluxLine(t *testing.T) {
+	sample := &Sample{Time: start, FlowId: 3, Protocol: zmqencdec.METRICS_PROTOCOL_TCP,
+		Metric: zmqencdec.Metric{PktRx: 10, PktTx: 20, ByteRx: math.MaxUint64, BpsRx: 8000}}
+
+	line := AppendInfluxLine(nil, sample, nil)
+	assert.Equal(t, "dfxp,flow_id=3,protocol=tcp pkt_rx=10i,pkt_tx=20i,byte_rx=9223372036854775807i,byte_tx=0i,"+
+		"bps_rx=8000i,bps_tx=0i,err_rx=0i,err_tx=0i 1704067200000000000\n", string(line))
+
+	options := &InfluxOptions{Measurement: "lab metrics", Tags: map[string]string{"site": "a,b", "host": "dut=1"}, Unsigned: true}
+	sample.Protocol = 9
+	line = AppendInfluxLine(nil, sample, options)
+	assert.Equal(t, `lab\ metrics,flow_id=3,protocol=9,host=dut\=1,site=a\,b pkt_rx=10u,pkt_tx=20u,byte_rx=18446744073709551615u,byte_tx=0u,`+
+		"bps_rx=8000u,bps_tx=0u,err_rx=0u,err_tx=0u 1704067200000000000\n", string(line))
+}
+
+func TestInfluxWriter(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	writer := NewInfluxWriter(buffer, nil)
+	msg := &zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_METRICS}}
+	msg.Metrics = zmqencdec.MsgMetrics{FlowId: 7, Metrics: []zmqencdec.ProtocolMetrics{
+		{FlowId: 7, Protocol: zmqencdec.METRICS_PROTOCOL_UDP, Metric: zmqencdec.Metric{PktRx: 1}},
+		{FlowId: 7, Protocol: zmqencdec.METRICS_PROTOCOL_ICMP, Metric: zmqencdec.Metric{PktRx: 2}},
+	}}
+	handle := Handler(writer, func() time.Time { return start })
+	if err := handle(context.Background(), msg); err != nil {
+		t.Fatalf("Handle failed. Err:%v", err)
+	}
+	assert.Equal(t, 2, bytes.Count(buffer.Bytes(), []byte("\n")))
+	assert.Assert(t, bytes.HasPrefix(buffer.Bytes(), []byte("dfxp,flow_id=7,protocol=udp pkt_rx=1i,")))
+	assert.Assert(t, bytes.Contains(buffer.Bytes(), []byte("\ndfxp,flow_id=7,protocol=icmp pkt_rx=2i,")))
+	assert.NilError(t, writer.Close())
+}
+
+func TestInfluxUDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed. Err:%v", err)
+	}
+	defer listener.Close()
+
+	// room for two lines per datagram
+	line := AppendInfluxLine(nil, &Sample{Time: start, FlowId: 1}, nil)
+	writer, err := DialInflux("udp://"+listener.LocalAddr().String(), &InfluxOptions{MaxPacket: 2*len(line) + 1})
+	if err != nil {
+		t.Fatalf("DialInflux failed. Err:%v", err)
+	}
+	defer writer.Close()
+
+	samples := make([]Sample, 5)
+	for i := range samples {
+		samples[i] = Sample{Time: start, FlowId: 1}
+	}
+	if err := writer.Write(samples...); err != nil {
+		t.Fatalf("Write failed. Err:%v", err)
+	}
+
+	var received []byte
+	buffer := make([]byte, 64*1024)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for _, lines := range []int{2, 2, 1} {
+		n, _, err := listener.ReadFrom(buffer)
+		if err != nil {
+			t.Fatalf("ReadFrom failed. Err:%v", err)
+		}
+		assert.Equal(t, lines*len(line), n)
+		received = append(received, buffer[:n]...)
+	}
+	assert.Equal(t, string(bytes.Repeat(line, 5)), string(received))
+}
+
+func TestInfluxHTTP(t *testing.T) {
+	var body []byte
+	var query, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/write" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		query = r.URL.RawQuery
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	writer, err := DialInflux(server.URL+"/api/v2/write?org=lab&bucket=dfxp", &InfluxOptions{Token: "secret", Client: server.Client()})
+	if err != nil {
+		t.Fatalf("DialInflux failed. Err:%v", err)
+	}
+	defer writer.Close()
+	sample := Sample{Time: start, FlowId: 2, Protocol: zmqencdec.METRICS_PROTOCOL_HTTP}
+	if err := writer.Write(sample); err != nil {
+		t.Fatalf("Write failed. Err:%v", err)
+	}
+	assert.Equal(t, string(AppendInfluxLine(nil, &sample, nil)), string(body))
+	assert.Equal(t, "org=lab&bucket=dfxp", query)
+	assert.Equal(t, "Token secret", authorization)
+
+	writer, err = DialInflux(server.URL+"/write?db=dfxp", nil)
+	if err != nil {
+		t.Fatalf("DialInflux failed. Err:%v", err)
+	}
+	err = writer.Write(sample)
+	assert.ErrorContains(t, err, "404 Not Found not found")
+
+	_, err = DialInflux("tcp://localhost:8086", nil)
+	assert.ErrorContains(t, err, "invalid influx endpoint")
+}
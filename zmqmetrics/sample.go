@@ -0,0 +1,59 @@
+// Package zmqmetrics - aggregation and storage of the metrics dfxp publishes
+// per flow and protocol.
+package zmqmetrics
+
+import (
+	"time"
+	"zmqclient/zmqencdec"
+)
+
+// Sample - the counters of one flow and protocol at one time
+type Sample struct {
+	Time     time.Time
+	FlowId   uint32
+	Protocol uint32
+	Metric   zmqencdec.Metric
+}
+
+// Counter - value of counter in the sample
+func (sample *Sample) Counter(counter zmqencdec.MetricCounter) uint64 {
+	switch counter {
+	case zmqencdec.COUNTER_PKT_RX:
+		return sample.Metric.PktRx
+	case zmqencdec.COUNTER_PKT_TX:
+		return sample.Metric.PktTx
+	case zmqencdec.COUNTER_BYTE_RX:
+		return sample.Metric.ByteRx
+	case zmqencdec.COUNTER_BYTE_TX:
+		return sample.Metric.ByteTx
+	case zmqencdec.COUNTER_BPS_RX:
+		return sample.Metric.BpsRx
+	case zmqencdec.COUNTER_BPS_TX:
+		return sample.Metric.BpsTx
+	case zmqencdec.COUNTER_ERR_RX:
+		return sample.Metric.ErrRx
+	case zmqencdec.COUNTER_ERR_TX:
+		return sample.Metric.ErrTx
+	}
+	return 0
+}
+
+// Samples - one sample per protocol record of a METRICS message, at time at.
+// Records without a flow id take the one of the message.
+func Samples(msg *zmqencdec.Message, at time.Time) []Sample {
+	samples := make([]Sample, 0, len(msg.Metrics.Metrics))
+	for _, record := range msg.Metrics.Metrics {
+		flowId := record.FlowId
+		if flowId == 0 {
+			flowId = msg.Metrics.FlowId
+		}
+		samples = append(samples, Sample{Time: at, FlowId: flowId, Protocol: record.Protocol, Metric: record.Metric})
+	}
+	return samples
+}
+
+// Gauge - whether counter is a rate dfxp computes (BpsRx, BpsTx) rather than
+// a count growing since the flow started
+func Gauge(counter zmqencdec.MetricCounter) bool {
+	return counter == zmqencdec.COUNTER_BPS_RX || counter == zmqencdec.COUNTER_BPS_TX
+}
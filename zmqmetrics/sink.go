@@ -0,0 +1,156 @@
+package zmqmetrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+	"zmqclient/zmqencdec"
+)
+
+// protocolNames - names of the METRICS_PROTOCOL values in tags and metric names
+var protocolNames = map[uint32]string{
+	zmqencdec.METRICS_PROTOCOL_UDP:  "udp",
+	zmqencdec.METRICS_PROTOCOL_TCP:  "tcp",
+	zmqencdec.METRICS_PROTOCOL_HTTP: "http",
+	zmqencdec.METRICS_PROTOCOL_ICMP: "icmp",
+}
+
+// ProtocolName - udp, tcp, http, icmp, or the number of another protocol
+func ProtocolName(protocol uint32) string {
+	if name, ok := protocolNames[protocol]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", protocol)
+}
+
+// Handler - zmqclient.MetricsHandler writing the samples of every METRICS
+// message to writer, stamped with now (nil for time.Now)
+func Handler(writer SampleWriter, now func() time.Time) func(ctx context.Context, msg *zmqencdec.Message) error {
+	if now == nil {
+		now = time.Now
+	}
+	return func(ctx context.Context, msg *zmqencdec.Message) error {
+		return writer.Write(Samples(msg, now())...)
+	}
+}
+
+// lineFormat - append the text lines of a sample to dst, each ending in '\n'
+type lineFormat func(dst []byte, sample *Sample) []byte
+
+// streamSink - lines written to an io.Writer, one Write per call
+type streamSink struct {
+	writer io.Writer
+	format lineFormat
+	buffer []byte
+}
+
+func (sink *streamSink) Write(samples ...Sample) error {
+	sink.buffer = sink.buffer[:0]
+	for i := range samples {
+		sink.buffer = sink.format(sink.buffer, &samples[i])
+	}
+	if len(sink.buffer) == 0 {
+		return nil
+	}
+	_, err := sink.writer.Write(sink.buffer)
+	return err
+}
+
+func (sink *streamSink) Close() error {
+	return nil
+}
+
+// DefaultMaxPacket - datagram size of the UDP sinks when none is set, below
+// the usual 1500 bytes MTU
+const DefaultMaxPacket = 1432
+
+// packetSink - lines sent in datagrams of at most maxPacket bytes, never
+// splitting a line
+type packetSink struct {
+	conn      net.Conn
+	format    lineFormat
+	maxPacket int
+	buffer    []byte
+}
+
+func dialPacketSink(address string, format lineFormat, maxPacket int) (*packetSink, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	if maxPacket <= 0 {
+		maxPacket = DefaultMaxPacket
+	}
+	return &packetSink{conn: conn, format: format, maxPacket: maxPacket}, nil
+}
+
+func (sink *packetSink) Write(samples ...Sample) error {
+	sink.buffer = sink.buffer[:0]
+	for i := range samples {
+		sink.buffer = sink.format(sink.buffer, &samples[i])
+	}
+
+	packet := sink.buffer
+	for len(packet) > 0 {
+		end := len(packet)
+		if end > sink.maxPacket {
+			// the last line end that fits, or the first one if no line does
+			end = bytes.LastIndexByte(packet[:sink.maxPacket], '\n') + 1
+			if end == 0 {
+				end = bytes.IndexByte(packet, '\n') + 1
+			}
+		}
+		if _, err := sink.conn.Write(packet[:end]); err != nil {
+			return err
+		}
+		packet = packet[end:]
+	}
+	return nil
+}
+
+func (sink *packetSink) Close() error {
+	return sink.conn.Close()
+}
+
+// httpSink - lines POSTed to url, one request per call
+type httpSink struct {
+	url    string
+	header http.Header
+	client *http.Client
+	format lineFormat
+}
+
+func (sink *httpSink) Write(samples ...Sample) error {
+	var body []byte
+	for i := range samples {
+		body = sink.format(body, &samples[i])
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	request, err := http.NewRequest(http.MethodPost, sink.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header = sink.header.Clone()
+	response, err := sink.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("POST %s failed. Error: %s %s", sink.url, response.Status, bytes.TrimSpace(message))
+	}
+	io.Copy(io.Discard, response.Body)
+	return nil
+}
+
+func (sink *httpSink) Close() error {
+	sink.client.CloseIdleConnections()
+	return nil
+}
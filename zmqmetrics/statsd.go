@@ -0,0 +1,65 @@
+package zmqmetrics
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"zmqclient/zmqencdec"
+)
+
+// StatsdOptions - parameters of the StatsD sinks
+type StatsdOptions struct {
+	// Prefix - first part of the metric names, "dfxp" when empty
+	Prefix string
+	// MaxPacket - datagram size of the UDP sink, 0 for DefaultMaxPacket
+	MaxPacket int
+}
+
+// NewStatsdWriter - StatsD gauges to w
+func NewStatsdWriter(w io.Writer, options *StatsdOptions) SampleWriter {
+	return &streamSink{writer: w, format: statsdFormat(options)}
+}
+
+// DialStatsd - StatsD gauges to the UDP address of a StatsD server, e.g.
+// localhost:8125
+func DialStatsd(address string, options *StatsdOptions) (SampleWriter, error) {
+	maxPacket := 0
+	if options != nil {
+		maxPacket = options.MaxPacket
+	}
+	return dialPacketSink(address, statsdFormat(options), maxPacket)
+}
+
+// AppendStatsdGauges - append one gauge per counter of sample to dst:
+//
+//	dfxp.flow_1.tcp.pkt_rx:10|g
+func AppendStatsdGauges(dst []byte, sample *Sample, options *StatsdOptions) []byte {
+	return statsdFormat(options)(dst, sample)
+}
+
+func statsdFormat(options *StatsdOptions) lineFormat {
+	prefix := "dfxp"
+	if options != nil && options.Prefix != "" {
+		prefix = strings.TrimSuffix(options.Prefix, ".")
+	}
+	prefix = statsdEscaper.Replace(prefix)
+
+	return func(dst []byte, sample *Sample) []byte {
+		for counter := zmqencdec.COUNTER_PKT_RX; counter < zmqencdec.METRIC_COUNTERS; counter++ {
+			dst = append(dst, prefix...)
+			dst = append(dst, ".flow_"...)
+			dst = strconv.AppendUint(dst, uint64(sample.FlowId), 10)
+			dst = append(dst, '.')
+			dst = append(dst, ProtocolName(sample.Protocol)...)
+			dst = append(dst, '.')
+			dst = append(dst, counterColumns[counter]...)
+			dst = append(dst, ':')
+			dst = strconv.AppendUint(dst, sample.Counter(counter), 10)
+			dst = append(dst, "|g\n"...)
+		}
+		return dst
+	}
+}
+
+// statsdEscaper - characters with a meaning in the StatsD line format
+var statsdEscaper = strings.NewReplacer(":", "_", "|", "_", "@", "_", "\n", "_")
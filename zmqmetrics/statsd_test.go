@@ -0,0 +1,72 @@
+package zmqmetrics
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+func TestStatsdGauges(t *testing.T) {
+	sample := &Sample{Time: start, FlowId: 4, Protocol: zmqencdec.METRICS_PROTOCOL_UDP,
+		Metric: zmqencdec.Metric{PktRx: 10, ByteTx: 1 << 40, ErrTx: 3}}
+
+	buffer := new(bytes.Buffer)
+	writer := NewStatsdWriter(buffer, &StatsdOptions{Prefix: "lab:1."})
+	if err := writer.Write(*sample); err != nil {
+		t.Fatalf("Write failed. Err:%v", err)
+	}
+	assert.Equal(t, "lab_1.flow_4.udp.pkt_rx:10|g\n"+
+		"lab_1.flow_4.udp.pkt_tx:0|g\n"+
+		"lab_1.flow_4.udp.byte_rx:0|g\n"+
+		"lab_1.flow_4.udp.byte_tx:1099511627776|g\n"+
+		"lab_1.flow_4.udp.bps_rx:0|g\n"+
+		"lab_1.flow_4.udp.bps_tx:0|g\n"+
+		"lab_1.flow_4.udp.err_rx:0|g\n"+
+		"lab_1.flow_4.udp.err_tx:3|g\n", buffer.String())
+	assert.Equal(t, buffer.String(), strings.ReplaceAll(string(AppendStatsdGauges(nil, sample, nil)), "dfxp.", "lab_1."))
+}
+
+func TestStatsdUDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed. Err:%v", err)
+	}
+	defer listener.Close()
+
+	writer, err := DialStatsd(listener.LocalAddr().String(), nil)
+	if err != nil {
+		t.Fatalf("DialStatsd failed. Err:%v", err)
+	}
+	defer writer.Close()
+
+	// 30 samples of 8 gauges, more than one datagram
+	samples := make([]Sample, 30)
+	var expected []byte
+	for i := range samples {
+		samples[i] = Sample{Time: start, FlowId: uint32(i), Protocol: zmqencdec.METRICS_PROTOCOL_TCP,
+			Metric: zmqencdec.Metric{PktRx: uint64(i)}}
+		expected = AppendStatsdGauges(expected, &samples[i], nil)
+	}
+	if err := writer.Write(samples...); err != nil {
+		t.Fatalf("Write failed. Err:%v", err)
+	}
+
+	var received []byte
+	buffer := make([]byte, 64*1024)
+	listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(received) < len(expected) {
+		n, _, err := listener.ReadFrom(buffer)
+		if err != nil {
+			t.Fatalf("ReadFrom failed. Err:%v", err)
+		}
+		assert.Assert(t, n <= DefaultMaxPacket)
+		assert.Equal(t, byte('\n'), buffer[n-1])
+		received = append(received, buffer[:n]...)
+	}
+	assert.Equal(t, string(expected), string(received))
+}
@@ -0,0 +1,506 @@
+package zmqmetrics
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"zmqclient/zmqencdec"
+)
+
+// StorageFormat - file format of stored samples
+type StorageFormat int
+
+const (
+	FORMAT_CSV StorageFormat = iota
+	FORMAT_NDJSON
+	// FORMAT_COLUMNAR - binary row groups, each column stored contiguously
+	FORMAT_COLUMNAR
+)
+
+func (format StorageFormat) String() string {
+	switch format {
+	case FORMAT_CSV:
+		return "csv"
+	case FORMAT_NDJSON:
+		return "ndjson"
+	case FORMAT_COLUMNAR:
+		return "col"
+	}
+	return fmt.Sprintf("StorageFormat(%d)", int(format))
+}
+
+// Ext - file extension of the format, with the dot
+func (format StorageFormat) Ext() string {
+	return "." + format.String()
+}
+
+// ParseStorageFormat - format of its name or of a file extension (.csv,
+// .ndjson, .jsonl, .col)
+func ParseStorageFormat(s string) (StorageFormat, error) {
+	switch strings.TrimPrefix(strings.ToLower(s), ".") {
+	case "csv":
+		return FORMAT_CSV, nil
+	case "ndjson", "jsonl":
+		return FORMAT_NDJSON, nil
+	case "col", "columnar":
+		return FORMAT_COLUMNAR, nil
+	}
+	return 0, fmt.Errorf("unknown metrics storage format %q", s)
+}
+
+// Record - a stored sample and the run it was recorded in
+type Record struct {
+	RunId string
+	Sample
+}
+
+// SampleWriter - writes the samples of one run. Close flushes, the
+// underlying writer is left open.
+type SampleWriter interface {
+	Write(samples ...Sample) error
+	Close() error
+}
+
+// storageWriter - SampleWriter of a storage format; Flush pushes what is
+// buffered to the underlying writer, so that a file is readable before Close
+type storageWriter interface {
+	SampleWriter
+	Flush() error
+}
+
+// NewSampleWriter - writer of the samples of runId to w in format
+func NewSampleWriter(w io.Writer, format StorageFormat, runId string) (SampleWriter, error) {
+	return newStorageWriter(w, format, runId)
+}
+
+func newStorageWriter(w io.Writer, format StorageFormat, runId string) (storageWriter, error) {
+	switch format {
+	case FORMAT_CSV:
+		return &csvWriter{writer: csv.NewWriter(w), runId: runId}, nil
+	case FORMAT_NDJSON:
+		return &ndjsonWriter{writer: bufio.NewWriter(w), runId: runId}, nil
+	case FORMAT_COLUMNAR:
+		return &columnarWriter{writer: bufio.NewWriter(w), runId: runId}, nil
+	}
+	return nil, fmt.Errorf("unknown metrics storage format %v", format)
+}
+
+// ReadRecords - every record of r
+func ReadRecords(r io.Reader, format StorageFormat) ([]Record, error) {
+	var records []Record
+	var err error
+	switch format {
+	case FORMAT_CSV:
+		records, err = readCSV(r)
+	case FORMAT_NDJSON:
+		records, err = readNDJSON(r)
+	case FORMAT_COLUMNAR:
+		records, err = readColumnar(r)
+	default:
+		return nil, fmt.Errorf("unknown metrics storage format %v", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s failed. Error: %v", format, err)
+	}
+	return records, nil
+}
+
+// WriteRecords - records to w in format, whatever their run
+func WriteRecords(w io.Writer, format StorageFormat, records []Record) error {
+	var writer SampleWriter
+	runId := ""
+	for i, record := range records {
+		if writer == nil || record.RunId != runId {
+			if writer != nil {
+				if err := writer.Close(); err != nil {
+					return err
+				}
+			}
+			var err error
+			runId = record.RunId
+			if writer, err = newRunWriter(w, format, runId, i == 0); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write(record.Sample); err != nil {
+			return err
+		}
+	}
+	if writer == nil {
+		return nil
+	}
+	return writer.Close()
+}
+
+// newRunWriter - a writer of the next run of a file: CSV writes its header
+// once per file, columnar once per run
+func newRunWriter(w io.Writer, format StorageFormat, runId string, first bool) (SampleWriter, error) {
+	writer, err := newStorageWriter(w, format, runId)
+	if csvWriter, ok := writer.(*csvWriter); ok && !first {
+		csvWriter.headerDone = true
+	}
+	return writer, err
+}
+
+// ///////////////////////////////////////////////////////////
+// CSV
+// ///////////////////////////////////////////////////////////
+
+// counterColumns - CSV columns and NDJSON keys of the counters
+var counterColumns = [counters]string{"pkt_rx", "pkt_tx", "byte_rx", "byte_tx", "bps_rx", "bps_tx", "err_rx", "err_tx"}
+
+var csvHeader = append([]string{"run_id", "time", "flow_id", "protocol"}, counterColumns[:]...)
+
+type csvWriter struct {
+	writer     *csv.Writer
+	runId      string
+	headerDone bool
+	line       []string
+}
+
+func (w *csvWriter) Write(samples ...Sample) error {
+	if !w.headerDone {
+		w.headerDone = true
+		if err := w.writer.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	for i := range samples {
+		w.line = append(w.line[:0], w.runId, samples[i].Time.Format(time.RFC3339Nano),
+			strconv.FormatUint(uint64(samples[i].FlowId), 10), strconv.FormatUint(uint64(samples[i].Protocol), 10))
+		for counter := zmqencdec.COUNTER_PKT_RX; counter < zmqencdec.METRIC_COUNTERS; counter++ {
+			w.line = append(w.line, strconv.FormatUint(samples[i].Counter(counter), 10))
+		}
+		if err := w.writer.Write(w.line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *csvWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *csvWriter) Close() error {
+	return w.Flush()
+}
+
+func readCSV(r io.Reader) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvHeader)
+	reader.ReuseRecord = true
+
+	var records []Record
+	for line := 1; ; line++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 {
+			if strings.Join(fields, ",") != strings.Join(csvHeader, ",") {
+				return nil, fmt.Errorf("line 1: header %q, want %q", strings.Join(fields, ","), strings.Join(csvHeader, ","))
+			}
+			continue
+		}
+		record, err := parseCSVRecord(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		records = append(records, record)
+	}
+}
+
+func parseCSVRecord(fields []string) (Record, error) {
+	record := Record{RunId: fields[0]}
+	var err error
+	if record.Time, err = time.Parse(time.RFC3339Nano, fields[1]); err != nil {
+		return record, err
+	}
+	values := make([]uint64, 2+counters)
+	for i := range values {
+		bits := 64
+		if i < 2 {
+			bits = 32
+		}
+		if values[i], err = strconv.ParseUint(fields[2+i], 10, bits); err != nil {
+			return record, fmt.Errorf("%s: %v", csvHeader[2+i], err)
+		}
+	}
+	record.FlowId = uint32(values[0])
+	record.Protocol = uint32(values[1])
+	record.Metric = metricOf(values[2:])
+	return record, nil
+}
+
+// metricOf - Metric of the counter values, in MetricCounter order
+func metricOf(values []uint64) zmqencdec.Metric {
+	return zmqencdec.Metric{
+		PktRx:  values[zmqencdec.COUNTER_PKT_RX],
+		PktTx:  values[zmqencdec.COUNTER_PKT_TX],
+		ByteRx: values[zmqencdec.COUNTER_BYTE_RX],
+		ByteTx: values[zmqencdec.COUNTER_BYTE_TX],
+		BpsRx:  values[zmqencdec.COUNTER_BPS_RX],
+		BpsTx:  values[zmqencdec.COUNTER_BPS_TX],
+		ErrRx:  values[zmqencdec.COUNTER_ERR_RX],
+		ErrTx:  values[zmqencdec.COUNTER_ERR_TX],
+	}
+}
+
+// ///////////////////////////////////////////////////////////
+// NDJSON
+// ///////////////////////////////////////////////////////////
+
+// ndjsonRecord - one NDJSON line
+type ndjsonRecord struct {
+	RunId    string    `json:"run_id"`
+	Time     time.Time `json:"time"`
+	FlowId   uint32    `json:"flow_id"`
+	Protocol uint32    `json:"protocol"`
+	PktRx    uint64    `json:"pkt_rx"`
+	PktTx    uint64    `json:"pkt_tx"`
+	ByteRx   uint64    `json:"byte_rx"`
+	ByteTx   uint64    `json:"byte_tx"`
+	BpsRx    uint64    `json:"bps_rx"`
+	BpsTx    uint64    `json:"bps_tx"`
+	ErrRx    uint64    `json:"err_rx"`
+	ErrTx    uint64    `json:"err_tx"`
+}
+
+type ndjsonWriter struct {
+	writer *bufio.Writer
+	runId  string
+}
+
+func (w *ndjsonWriter) Write(samples ...Sample) error {
+	encoder := json.NewEncoder(w.writer)
+	for i := range samples {
+		metric := &samples[i].Metric
+		err := encoder.Encode(&ndjsonRecord{
+			RunId: w.runId, Time: samples[i].Time, FlowId: samples[i].FlowId, Protocol: samples[i].Protocol,
+			PktRx: metric.PktRx, PktTx: metric.PktTx, ByteRx: metric.ByteRx, ByteTx: metric.ByteTx,
+			BpsRx: metric.BpsRx, BpsTx: metric.BpsTx, ErrRx: metric.ErrRx, ErrTx: metric.ErrTx,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.writer.Flush()
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.Flush()
+}
+
+func readNDJSON(r io.Reader) ([]Record, error) {
+	scanner := bufio.NewScanner(r)
+	var records []Record
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var value ndjsonRecord
+		if err := json.Unmarshal(scanner.Bytes(), &value); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		records = append(records, Record{RunId: value.RunId, Sample: Sample{
+			Time: value.Time, FlowId: value.FlowId, Protocol: value.Protocol,
+			Metric: zmqencdec.Metric{
+				PktRx: value.PktRx, PktTx: value.PktTx, ByteRx: value.ByteRx, ByteTx: value.ByteTx,
+				BpsRx: value.BpsRx, BpsTx: value.BpsTx, ErrRx: value.ErrRx, ErrTx: value.ErrTx,
+			},
+		}})
+	}
+	return records, scanner.Err()
+}
+
+// ///////////////////////////////////////////////////////////
+// Columnar
+// ///////////////////////////////////////////////////////////
+
+// Columnar layout, big endian: per run the magic and the run id (u16 length
+// and bytes), then row groups of a u32 row count followed by the columns
+// time (i64 unix nanoseconds), flow id (u32), protocol (u32) and the eight
+// counters (u64), each stored contiguously. A run ends with a zero row count;
+// a file cut after a row group, e.g. by a killed load test, is read up to it.
+const (
+	columnarMagic     = "DFXPCOL1"
+	columnarGroupRows = 1024
+)
+
+type columnarWriter struct {
+	writer     *bufio.Writer
+	runId      string
+	headerDone bool
+	rows       []Sample
+	buffer     []byte
+}
+
+func (w *columnarWriter) Write(samples ...Sample) error {
+	if !w.headerDone {
+		w.headerDone = true
+		if len(w.runId) > 0xffff {
+			return fmt.Errorf("run id too long [%d]", len(w.runId))
+		}
+		w.buffer = append(w.buffer[:0], columnarMagic...)
+		w.buffer = binary.BigEndian.AppendUint16(w.buffer, uint16(len(w.runId)))
+		w.buffer = append(w.buffer, w.runId...)
+		if _, err := w.writer.Write(w.buffer); err != nil {
+			return err
+		}
+	}
+	for _, sample := range samples {
+		w.rows = append(w.rows, sample)
+		if len(w.rows) == columnarGroupRows {
+			if err := w.flushGroup(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *columnarWriter) flushGroup() error {
+	buffer := binary.BigEndian.AppendUint32(w.buffer[:0], uint32(len(w.rows)))
+	for i := range w.rows {
+		buffer = binary.BigEndian.AppendUint64(buffer, uint64(w.rows[i].Time.UnixNano()))
+	}
+	for i := range w.rows {
+		buffer = binary.BigEndian.AppendUint32(buffer, w.rows[i].FlowId)
+	}
+	for i := range w.rows {
+		buffer = binary.BigEndian.AppendUint32(buffer, w.rows[i].Protocol)
+	}
+	for counter := zmqencdec.COUNTER_PKT_RX; counter < zmqencdec.METRIC_COUNTERS; counter++ {
+		for i := range w.rows {
+			buffer = binary.BigEndian.AppendUint64(buffer, w.rows[i].Counter(counter))
+		}
+	}
+	w.buffer = buffer
+	w.rows = w.rows[:0]
+	if _, err := w.writer.Write(buffer); err != nil {
+		return err
+	}
+	return w.writer.Flush()
+}
+
+// Flush - write the header; rows are written by row group, once complete
+func (w *columnarWriter) Flush() error {
+	if err := w.Write(); err != nil {
+		return err
+	}
+	return w.writer.Flush()
+}
+
+func (w *columnarWriter) Close() error {
+	if err := w.Write(); err != nil {
+		return err
+	}
+	if len(w.rows) > 0 {
+		if err := w.flushGroup(); err != nil {
+			return err
+		}
+	}
+	if err := w.flushGroup(); err != nil {
+		return err
+	}
+	return w.writer.Flush()
+}
+
+func readColumnar(r io.Reader) ([]Record, error) {
+	reader := bufio.NewReader(r)
+	var records []Record
+	for {
+		magic := make([]byte, len(columnarMagic))
+		if _, err := io.ReadFull(reader, magic); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if string(magic) != columnarMagic {
+			return nil, fmt.Errorf("bad magic %q", magic)
+		}
+		var length uint16
+		if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		runId := make([]byte, length)
+		if _, err := io.ReadFull(reader, runId); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		var err error
+		if records, err = readColumnarRun(reader, string(runId), records); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readColumnarRun(reader io.Reader, runId string, records []Record) ([]Record, error) {
+	for {
+		var rows uint32
+		if err := binary.Read(reader, binary.BigEndian, &rows); err == io.EOF {
+			// run not closed: the file ends after its last row group
+			return records, nil
+		} else if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if rows == 0 {
+			return records, nil
+		}
+		if rows > columnarGroupRows {
+			return nil, fmt.Errorf("row group of %d rows, at most %d", rows, columnarGroupRows)
+		}
+		group := make([]byte, int(rows)*(8+4+4+8*counters))
+		if _, err := io.ReadFull(reader, group); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		n := int(rows)
+		first := len(records)
+		for i := 0; i < n; i++ {
+			records = append(records, Record{RunId: runId})
+		}
+		rowsOf := records[first:]
+		for i := range rowsOf {
+			rowsOf[i].Time = time.Unix(0, int64(binary.BigEndian.Uint64(group[8*i:]))).UTC()
+		}
+		group = group[8*n:]
+		for i := range rowsOf {
+			rowsOf[i].FlowId = binary.BigEndian.Uint32(group[4*i:])
+		}
+		group = group[4*n:]
+		for i := range rowsOf {
+			rowsOf[i].Protocol = binary.BigEndian.Uint32(group[4*i:])
+		}
+		group = group[4*n:]
+		values := make([]uint64, counters)
+		for i := range rowsOf {
+			for counter := 0; counter < counters; counter++ {
+				values[counter] = binary.BigEndian.Uint64(group[8*(counter*n+i):])
+			}
+			rowsOf[i].Metric = metricOf(values)
+		}
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
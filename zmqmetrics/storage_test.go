@@ -0,0 +1,203 @@
+package zmqmetrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+func storageRecords(runId string, count int) []Record {
+	records := make([]Record, count)
+	for i := range records {
+		records[i] = Record{RunId: runId, Sample: Sample{
+			Time:     start.Add(time.Duration(i) * 1500 * time.Millisecond),
+			FlowId:   uint32(1 + i%3),
+			Protocol: uint32(i % 4),
+			Metric: zmqencdec.Metric{PktRx: uint64(i), PktTx: uint64(2 * i), ByteRx: uint64(1500 * i), ByteTx: 1 << 40,
+				BpsRx: 8000, BpsTx: 16000, ErrRx: 1, ErrTx: uint64(i % 2)},
+		}}
+	}
+	return records
+}
+
+func TestStorageFormats(t *testing.T) {
+	// two runs, the columnar one over several row groups
+	records := append(storageRecords("run-1", 3), storageRecords("run-2", columnarGroupRows+5)...)
+	for _, format := range []StorageFormat{FORMAT_CSV, FORMAT_NDJSON, FORMAT_COLUMNAR} {
+		t.Run(format.String(), func(t *testing.T) {
+			buffer := new(bytes.Buffer)
+			if err := WriteRecords(buffer, format, records); err != nil {
+				t.Fatalf("WriteRecords failed. Err:%v", err)
+			}
+			read, err := ReadRecords(buffer, format)
+			if err != nil {
+				t.Fatalf("ReadRecords failed. Err:%v", err)
+			}
+			assert.DeepEqual(t, records, read)
+		})
+	}
+}
+
+func TestStorageCSV(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	if err := WriteRecords(buffer, FORMAT_CSV, storageRecords("run-1", 1)); err != nil {
+		t.Fatalf("WriteRecords failed. Err:%v", err)
+	}
+	assert.Equal(t, "run_id,time,flow_id,protocol,pkt_rx,pkt_tx,byte_rx,byte_tx,bps_rx,bps_tx,err_rx,err_tx\n"+
+		"run-1,2024-01-01T00:00:00Z,1,0,0,0,0,1099511627776,8000,16000,1,0\n", buffer.String())
+
+	_, err := ReadRecords(bytes.NewBufferString("run_id,time\n"), FORMAT_CSV)
+	assert.ErrorContains(t, err, "read csv failed")
+	bad := "run_id,time,flow_id,protocol,pkt_rx,pkt_tx,byte_rx,byte_tx,bps_rx,bps_tx,err_rx,err_tx\n" +
+		"run-1,2024-01-01T00:00:00Z,99999999999,0,0,0,0,0,0,0,0,0\n"
+	_, err = ReadRecords(bytes.NewBufferString(bad), FORMAT_CSV)
+	assert.ErrorContains(t, err, "line 2: flow_id")
+}
+
+func TestStorageColumnarErrors(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	if err := WriteRecords(buffer, FORMAT_COLUMNAR, storageRecords("run-1", 2)); err != nil {
+		t.Fatalf("WriteRecords failed. Err:%v", err)
+	}
+	frame := buffer.Bytes()
+
+	_, err := ReadRecords(bytes.NewReader(frame[:len(frame)-5]), FORMAT_COLUMNAR)
+	assert.ErrorContains(t, err, "unexpected EOF")
+	_, err = ReadRecords(bytes.NewReader(append([]byte("NOTMAGIC"), frame[8:]...)), FORMAT_COLUMNAR)
+	assert.ErrorContains(t, err, "bad magic")
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	now := start
+	store, err := OpenStore(&StoreOptions{Dir: dir, Format: FORMAT_NDJSON, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("OpenStore failed. Err:%v", err)
+	}
+	assert.Equal(t, "20240101T000000.000000000Z", store.RunId())
+
+	msg := &zmqencdec.Message{Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_METRICS}}
+	msg.Metrics = zmqencdec.MsgMetrics{FlowId: 7, Metrics: []zmqencdec.ProtocolMetrics{
+		{Protocol: zmqencdec.METRICS_PROTOCOL_UDP, Metric: zmqencdec.Metric{PktRx: 1}},
+		{Protocol: zmqencdec.METRICS_PROTOCOL_TCP, Metric: zmqencdec.Metric{PktRx: 2}},
+	}}
+	if err := store.Handle(context.Background(), msg); err != nil {
+		t.Fatalf("Handle failed. Err:%v", err)
+	}
+	if err := store.Rotate("second"); err != nil {
+		t.Fatalf("Rotate failed. Err:%v", err)
+	}
+	now = now.Add(time.Second)
+	store.Handle(context.Background(), msg)
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed. Err:%v", err)
+	}
+	assert.ErrorContains(t, store.Write(Sample{}), "metrics store closed")
+
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a run"), 0644)
+	runs, err := Runs(dir)
+	if err != nil {
+		t.Fatalf("Runs failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, []RunFile{
+		{RunId: "20240101T000000.000000000Z", Path: filepath.Join(dir, "20240101T000000.000000000Z.ndjson"), Format: FORMAT_NDJSON},
+		{RunId: "second", Path: filepath.Join(dir, "second.ndjson"), Format: FORMAT_NDJSON},
+	}, runs)
+
+	records, err := ReadFile(runs[1].Path)
+	if err != nil {
+		t.Fatalf("ReadFile failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, []Record{
+		{RunId: "second", Sample: Sample{Time: start.Add(time.Second), FlowId: 7, Protocol: zmqencdec.METRICS_PROTOCOL_UDP, Metric: zmqencdec.Metric{PktRx: 1}}},
+		{RunId: "second", Sample: Sample{Time: start.Add(time.Second), FlowId: 7, Protocol: zmqencdec.METRICS_PROTOCOL_TCP, Metric: zmqencdec.Metric{PktRx: 2}}},
+	}, records)
+}
+
+func TestStoreRotateInvalid(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenStore(&StoreOptions{Dir: dir, Format: FORMAT_CSV, RunId: "first"})
+	if err != nil {
+		t.Fatalf("OpenStore failed. Err:%v", err)
+	}
+	defer store.Close()
+
+	assert.ErrorContains(t, store.Rotate("a/b"), "invalid run id")
+	os.Mkdir(filepath.Join(dir, "taken.csv"), 0755)
+	assert.ErrorContains(t, store.Rotate("taken"), "open metrics run failed")
+
+	// the first run goes on
+	assert.Equal(t, "first", store.RunId())
+	if err := store.Write(Sample{Time: start, FlowId: 7}); err != nil {
+		t.Fatalf("Write failed. Err:%v", err)
+	}
+	records, err := ReadFile(store.Path())
+	if err != nil {
+		t.Fatalf("ReadFile failed. Err:%v", err)
+	}
+	assert.Equal(t, 1, len(records))
+
+	// the current run replaced by itself
+	if err := store.Rotate("first"); err != nil {
+		t.Fatalf("Rotate failed. Err:%v", err)
+	}
+	records, err = ReadFile(store.Path())
+	if err != nil {
+		t.Fatalf("ReadFile failed. Err:%v", err)
+	}
+	assert.Equal(t, 0, len(records))
+}
+
+func TestStoreNotClosed(t *testing.T) {
+	// a killed load test leaves its run file readable up to the last write,
+	// the last complete row group for the columnar format
+	samples := make([]Sample, columnarGroupRows+5)
+	for i, record := range storageRecords("run", len(samples)) {
+		samples[i] = record.Sample
+	}
+	for _, format := range []StorageFormat{FORMAT_CSV, FORMAT_NDJSON, FORMAT_COLUMNAR} {
+		t.Run(format.String(), func(t *testing.T) {
+			store, err := OpenStore(&StoreOptions{Dir: t.TempDir(), Format: format, RunId: "run"})
+			if err != nil {
+				t.Fatalf("OpenStore failed. Err:%v", err)
+			}
+			defer store.Close()
+			if err := store.Write(samples...); err != nil {
+				t.Fatalf("Write failed. Err:%v", err)
+			}
+
+			records, err := ReadFile(store.Path())
+			if err != nil {
+				t.Fatalf("ReadFile failed. Err:%v", err)
+			}
+			stored := len(samples)
+			if format == FORMAT_COLUMNAR {
+				stored = columnarGroupRows
+			}
+			assert.DeepEqual(t, storageRecords("run", stored), records)
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+	records := append(storageRecords("run-1", 8), storageRecords("run-2", 8)...)
+	for _, test := range []struct {
+		query Query
+		count int
+	}{
+		{Query{}, 16},
+		{Query{RunIds: []string{"run-2"}}, 8},
+		{Query{FlowIds: []uint32{1}}, 6},
+		{Query{FlowIds: []uint32{1}, Protocols: []uint32{0}}, 2},
+		{Query{From: start.Add(3 * time.Second), To: start.Add(6 * time.Second)}, 4},
+	} {
+		assert.Equal(t, test.count, len(test.query.Filter(records)), fmt.Sprintf("\nquery %+v", test.query))
+	}
+}
@@ -0,0 +1,224 @@
+package zmqmetrics
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"zmqclient/zmqencdec"
+)
+
+// StoreOptions - parameters of OpenStore
+type StoreOptions struct {
+	// Dir - directory of the run files, created if missing
+	Dir    string
+	Format StorageFormat
+	// RunId - id of the first run, empty for one made of the clock
+	RunId string
+	// Now - clock of Handle and of the run ids, nil for time.Now
+	Now func() time.Time
+}
+
+// Store - samples written to one file per run, Dir/<run id>.<format>.
+// Handle plugs it in as a zmqclient.MetricsHandler.
+type Store struct {
+	options StoreOptions
+
+	mu     sync.Mutex
+	runId  string
+	path   string
+	file   *os.File
+	writer storageWriter
+}
+
+// OpenStore - open the store and the file of its first run
+func OpenStore(options *StoreOptions) (*Store, error) {
+	store := &Store{options: *options}
+	if store.options.Now == nil {
+		store.options.Now = time.Now
+	}
+	if err := os.MkdirAll(store.options.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("open metrics store failed. Error: %v", err)
+	}
+	if err := store.Rotate(store.options.RunId); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// RunId - id of the current run
+func (store *Store) RunId() string {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.runId
+}
+
+// Path - file of the current run
+func (store *Store) Path() string {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.path
+}
+
+// Rotate - start run runId, one made of the clock when empty, then close the
+// current run file. An existing file of the run is replaced. The current run
+// goes on when runId is invalid or its file cannot be created.
+func (store *Store) Rotate(runId string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if runId == "" {
+		runId = store.options.Now().UTC().Format("20060102T150405.000000000Z")
+	}
+	if strings.ContainsAny(runId, `/\`) {
+		return fmt.Errorf("invalid run id %q", runId)
+	}
+	path := filepath.Join(store.options.Dir, runId+store.options.Format.Ext())
+	if path == store.path {
+		// the current run is replaced, its file is closed first
+		if err := store.close(); err != nil {
+			return err
+		}
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("open metrics run failed. Error: %v", err)
+	}
+	writer, err := newStorageWriter(file, store.options.Format, runId)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = store.close()
+	store.runId, store.path, store.file, store.writer = runId, path, file, writer
+	return err
+}
+
+// Handle - write the samples of a METRICS message, stamped with the store
+// clock; a zmqclient.MetricsHandler
+func (store *Store) Handle(ctx context.Context, msg *zmqencdec.Message) error {
+	return store.Write(Samples(msg, store.options.Now())...)
+}
+
+// Write - write samples to the current run and flush them to its file; the
+// columnar format writes complete row groups only, the last one at Close
+func (store *Store) Write(samples ...Sample) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.writer == nil {
+		return fmt.Errorf("metrics store closed")
+	}
+	if err := store.writer.Write(samples...); err != nil {
+		return err
+	}
+	return store.writer.Flush()
+}
+
+// Close - flush and close the current run file
+func (store *Store) Close() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.close()
+}
+
+func (store *Store) close() error {
+	if store.writer == nil {
+		return nil
+	}
+	err := store.writer.Close()
+	if closeErr := store.file.Close(); err == nil {
+		err = closeErr
+	}
+	store.writer, store.file = nil, nil
+	return err
+}
+
+// RunFile - a run file of a store directory
+type RunFile struct {
+	RunId  string
+	Path   string
+	Format StorageFormat
+}
+
+// Runs - the run files of dir, sorted by run id
+func Runs(dir string) ([]RunFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var runs []RunFile
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		format, err := ParseStorageFormat(ext)
+		if entry.IsDir() || err != nil {
+			continue
+		}
+		runs = append(runs, RunFile{
+			RunId:  strings.TrimSuffix(entry.Name(), ext),
+			Path:   filepath.Join(dir, entry.Name()),
+			Format: format,
+		})
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].RunId < runs[j].RunId })
+	return runs, nil
+}
+
+// ReadFile - records of a run file, format from its extension
+func ReadFile(path string) ([]Record, error) {
+	format, err := ParseStorageFormat(filepath.Ext(path))
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadRecords(file, format)
+}
+
+// Query - filter of records; zero fields match everything
+type Query struct {
+	RunIds    []string
+	FlowIds   []uint32
+	Protocols []uint32
+	// From, To - records in [From, To)
+	From time.Time
+	To   time.Time
+}
+
+// Match - whether record passes the query
+func (query *Query) Match(record *Record) bool {
+	if len(query.RunIds) > 0 && !slices.Contains(query.RunIds, record.RunId) {
+		return false
+	}
+	if len(query.FlowIds) > 0 && !slices.Contains(query.FlowIds, record.FlowId) {
+		return false
+	}
+	if len(query.Protocols) > 0 && !slices.Contains(query.Protocols, record.Protocol) {
+		return false
+	}
+	if !query.From.IsZero() && record.Time.Before(query.From) {
+		return false
+	}
+	if !query.To.IsZero() && !record.Time.Before(query.To) {
+		return false
+	}
+	return true
+}
+
+// Filter - the records matching the query, in order
+func (query *Query) Filter(records []Record) []Record {
+	var matched []Record
+	for i := range records {
+		if query.Match(&records[i]) {
+			matched = append(matched, records[i])
+		}
+	}
+	return matched
+}
@@ -0,0 +1,126 @@
+package zmqprom
+
+import (
+	"zmqclient/zmqclient"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Instrumentation - zmqclient.Instrumentation exporting Prometheus metrics
+type Instrumentation struct {
+	duration       *prometheus.HistogramVec
+	timeouts       *prometheus.CounterVec
+	retries        *prometheus.CounterVec
+	sentBytes      *prometheus.CounterVec
+	receivedBytes  *prometheus.CounterVec
+	errorResponses *prometheus.CounterVec
+	reconnects     *prometheus.CounterVec
+	listenerErrors prometheus.Counter
+}
+
+var _ zmqclient.Instrumentation = (*Instrumentation)(nil)
+
+// NewInstrumentation - create the client metrics under namespace (e.g. "dfxp")
+// and register them; a nil registerer uses prometheus.DefaultRegisterer
+func NewInstrumentation(registerer prometheus.Registerer, namespace string) (*Instrumentation, error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+	instrumentation := &Instrumentation{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "client",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of the control requests by command and outcome.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, []string{"command", "outcome"}),
+		timeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "client",
+			Name:      "timeouts_total",
+			Help:      "Control requests without response in time.",
+		}, []string{"command"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "client",
+			Name:      "retries_total",
+			Help:      "Control requests resent after a transport failure.",
+		}, []string{"command"}),
+		sentBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "client",
+			Name:      "sent_bytes_total",
+			Help:      "Bytes sent on the control socket.",
+		}, []string{"command"}),
+		receivedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "client",
+			Name:      "received_bytes_total",
+			Help:      "Bytes received on the control socket.",
+		}, []string{"command"}),
+		errorResponses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "client",
+			Name:      "error_responses_total",
+			Help:      "ERROR and MSG_ERROR responses by request command.",
+		}, []string{"command", "type"}),
+		reconnects: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "client",
+			Name:      "reconnects_total",
+			Help:      "Attempts to reopen the control socket by endpoint and result.",
+		}, []string{"endpoint", "result"}),
+		listenerErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "client",
+			Name:      "listener_errors_total",
+			Help:      "Receive and decode failures on the metrics socket.",
+		}),
+	}
+
+	for _, collector := range []prometheus.Collector{
+		instrumentation.duration,
+		instrumentation.timeouts,
+		instrumentation.retries,
+		instrumentation.sentBytes,
+		instrumentation.receivedBytes,
+		instrumentation.errorResponses,
+		instrumentation.reconnects,
+		instrumentation.listenerErrors,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return instrumentation, nil
+}
+
+func (instrumentation *Instrumentation) ObserveRequest(observation zmqclient.RequestObservation) {
+	command := observation.Command.String()
+	outcome := observation.Outcome()
+
+	instrumentation.duration.WithLabelValues(command, outcome).Observe(observation.Latency.Seconds())
+	instrumentation.sentBytes.WithLabelValues(command).Add(float64(observation.BytesSent))
+	instrumentation.receivedBytes.WithLabelValues(command).Add(float64(observation.BytesReceived))
+	if observation.Retry {
+		instrumentation.retries.WithLabelValues(command).Inc()
+	}
+	switch outcome {
+	case zmqclient.OUTCOME_TIMEOUT:
+		instrumentation.timeouts.WithLabelValues(command).Inc()
+	case zmqclient.OUTCOME_ERROR_RESPONSE:
+		instrumentation.errorResponses.WithLabelValues(command, observation.Response.String()).Inc()
+	}
+}
+
+func (instrumentation *Instrumentation) ObserveReconnect(endpoint string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "failed"
+	}
+	instrumentation.reconnects.WithLabelValues(endpoint, result).Inc()
+}
+
+func (instrumentation *Instrumentation) ObserveListenerError(err error) {
+	instrumentation.listenerErrors.Inc()
+}
@@ -0,0 +1,73 @@
+package zmqprom
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"zmqclient/zmqclient"
+	"zmqclient/zmqencdec"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gotest.tools/assert"
+)
+
+func TestInstrumentation(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	instrumentation, err := NewInstrumentation(registry, "dfxp")
+	if err != nil {
+		t.Fatalf("NewInstrumentation failed. Err:%v", err)
+	}
+
+	instrumentation.ObserveRequest(zmqclient.RequestObservation{
+		Command:       zmqencdec.ZMQ_CMD_ADD_TUNNELS,
+		Response:      zmqencdec.ZMQ_CMD_ADD_TUNNELS,
+		Latency:       time.Millisecond,
+		BytesSent:     60,
+		BytesReceived: 12,
+	})
+	instrumentation.ObserveRequest(zmqclient.RequestObservation{
+		Command:       zmqencdec.ZMQ_CMD_ADD_TUNNELS,
+		Response:      zmqencdec.ZMQ_CMD_MSG_ERROR,
+		BytesSent:     60,
+		BytesReceived: 20,
+	})
+	instrumentation.ObserveRequest(zmqclient.RequestObservation{
+		Command: zmqencdec.ZMQ_CMD_STOP,
+		Retry:   true,
+		Err:     fmt.Errorf("receive failed. Error: %w", zmqclient.ErrTimeout),
+	})
+	instrumentation.ObserveReconnect("tcp://127.0.0.1:5555", nil)
+	instrumentation.ObserveReconnect("tcp://127.0.0.2:5555", errors.New("refused"))
+	instrumentation.ObserveListenerError(errors.New("closed"))
+
+	assert.Equal(t, float64(120), testutil.ToFloat64(instrumentation.sentBytes.WithLabelValues("ADD_TUNNELS")))
+	assert.Equal(t, float64(32), testutil.ToFloat64(instrumentation.receivedBytes.WithLabelValues("ADD_TUNNELS")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(instrumentation.errorResponses.WithLabelValues("ADD_TUNNELS", "MSG_ERROR")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(instrumentation.timeouts.WithLabelValues("STOP")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(instrumentation.retries.WithLabelValues("STOP")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(instrumentation.reconnects.WithLabelValues("tcp://127.0.0.2:5555", "failed")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(instrumentation.listenerErrors))
+	assert.Equal(t, 3, testutil.CollectAndCount(instrumentation.duration))
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed. Err:%v", err)
+	}
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	assert.Assert(t, names["dfxp_client_request_duration_seconds"])
+	assert.Assert(t, names["dfxp_client_error_responses_total"])
+}
+
+func TestInstrumentationRegisterTwice(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if _, err := NewInstrumentation(registry, "dfxp"); err != nil {
+		t.Fatalf("NewInstrumentation failed. Err:%v", err)
+	}
+	_, err := NewInstrumentation(registry, "dfxp")
+	assert.ErrorContains(t, err, "duplicate metrics collector registration")
+}
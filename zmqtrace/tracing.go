@@ -0,0 +1,90 @@
+package zmqtrace
+
+import (
+	"context"
+	"errors"
+	"time"
+	"zmqclient/zmqclient"
+	"zmqclient/zmqencdec"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "zmqclient/zmqtrace"
+
+// span attributes
+const (
+	AttrCommand       = attribute.Key("dfxp.command")
+	AttrFlowId        = attribute.Key("dfxp.flow_id")
+	AttrTunnels       = attribute.Key("dfxp.tunnels")
+	AttrRequestBytes  = attribute.Key("dfxp.request_bytes")
+	AttrResponseBytes = attribute.Key("dfxp.response_bytes")
+	AttrLatencyMs     = attribute.Key("dfxp.latency_ms")
+	AttrServerError   = attribute.Key("dfxp.server_error")
+	AttrResponse      = attribute.Key("dfxp.response")
+)
+
+// Interceptor - client span "dfxp <COMMAND>" around every request, child of
+// the span in the request context. A nil provider uses the global one.
+func Interceptor(provider trace.TracerProvider) zmqclient.Interceptor {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	tracer := provider.Tracer(instrumentationName)
+
+	return func(ctx context.Context, msg *zmqencdec.Message, next zmqclient.RequestHandler) (*zmqencdec.Message, error) {
+		command := msg.Header.Command
+		ctx, span := tracer.Start(ctx, "dfxp "+command.String(),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				AttrCommand.String(command.String()),
+				AttrFlowId.Int64(int64(msg.RequestFlowId())),
+			))
+		defer span.End()
+
+		if tunnels, ok := requestTunnels(msg); ok {
+			span.SetAttributes(AttrTunnels.Int(tunnels))
+		}
+
+		// frame sizes as encoded by the client codec
+		ctx, sizes := zmqclient.WithFrameSizes(ctx)
+		start := time.Now()
+		response, err := next(ctx, msg)
+		span.SetAttributes(AttrLatencyMs.Float64(float64(time.Since(start).Microseconds()) / 1000))
+
+		if sizes.Sent != 0 {
+			span.SetAttributes(AttrRequestBytes.Int(sizes.Sent))
+		}
+		if response != nil {
+			span.SetAttributes(AttrResponse.String(response.Header.Command.String()))
+		}
+		if sizes.Received != 0 {
+			span.SetAttributes(AttrResponseBytes.Int(sizes.Received))
+		}
+
+		var dfxpErr *zmqencdec.DfxpError
+		switch {
+		case errors.As(err, &dfxpErr):
+			span.SetAttributes(AttrServerError.String(dfxpErr.Message))
+			span.SetStatus(codes.Error, dfxpErr.Message)
+		case err != nil:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return response, err
+	}
+}
+
+// requestTunnels - number of tunnels or TEIDs carried by msg
+func requestTunnels(msg *zmqencdec.Message) (int, bool) {
+	switch msg.Header.Command {
+	case zmqencdec.ZMQ_CMD_ADD_TUNNELS:
+		return len(msg.AddTunnelRequest.Tunnels), true
+	case zmqencdec.ZMQ_CMD_DEL_TUNNELS:
+		return len(msg.DelTunnelsRequest.Teids), true
+	}
+	return 0, false
+}
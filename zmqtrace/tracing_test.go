@@ -0,0 +1,206 @@
+package zmqtrace
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"zmqclient/zmqcbor"
+	"zmqclient/zmqclient"
+	"zmqclient/zmqencdec"
+
+	"github.com/go-zeromq/zmq4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gotest.tools/assert"
+)
+
+func newProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return provider, exporter
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestInterceptorSpan(t *testing.T) {
+	provider, exporter := newProvider()
+	interceptor := Interceptor(provider)
+
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_ADD_TUNNELS},
+		AddTunnelRequest: zmqencdec.MsgAddTunnelsRequest{
+			FlowId:  1234,
+			Tunnels: make([]zmqencdec.Tunnel, 3),
+		},
+	}
+	_, err := interceptor(context.Background(), msg, func(ctx context.Context, msg *zmqencdec.Message) (*zmqencdec.Message, error) {
+		assert.Assert(t, trace.SpanFromContext(ctx).SpanContext().IsValid(), "\nThe span must be in the request context.")
+		sizes := zmqclient.FrameSizesFrom(ctx)
+		assert.Assert(t, sizes != nil, "\nThe frame sizes must be in the request context.")
+		sizes.Sent, sizes.Received = 4+4+4+3*16, 12
+		return &zmqencdec.Message{
+			Header:         zmqencdec.MsgHeader{Length: 10, Command: zmqencdec.ZMQ_CMD_ADD_TUNNELS},
+			TunnelResponse: zmqencdec.MsgTunnelResponse{FlowId: 1234, Tunnels: 3},
+		}, nil
+	})
+	if err != nil {
+		t.Fatalf("interceptor failed. Err:%v", err)
+	}
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, "dfxp ADD_TUNNELS", spans[0].Name)
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind)
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+
+	attrs := attributes(spans[0])
+	assert.Equal(t, "ADD_TUNNELS", attrs[AttrCommand].AsString())
+	assert.Equal(t, int64(1234), attrs[AttrFlowId].AsInt64())
+	assert.Equal(t, int64(3), attrs[AttrTunnels].AsInt64())
+	assert.Equal(t, int64(4+4+4+3*16), attrs[AttrRequestBytes].AsInt64())
+	assert.Equal(t, int64(12), attrs[AttrResponseBytes].AsInt64())
+	_, ok := attrs[AttrLatencyMs]
+	assert.Assert(t, ok, "\nThe latency must be recorded.")
+}
+
+func TestInterceptorErrors(t *testing.T) {
+	provider, exporter := newProvider()
+	interceptor := Interceptor(provider)
+	msg := &zmqencdec.Message{
+		Header:      zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_STOP},
+		StopRequest: zmqencdec.MsgStopRequest{FlowId: 7},
+	}
+
+	_, err := interceptor(context.Background(), msg, func(ctx context.Context, msg *zmqencdec.Message) (*zmqencdec.Message, error) {
+		response := &zmqencdec.Message{
+			Header:           zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_MSG_ERROR},
+			MsgErrorResponse: zmqencdec.MsgErrorResponse{FlowId: 7, Error: "flow not started"},
+		}
+		return response, zmqencdec.ResponseError(msg.Header.Command, response)
+	})
+	assert.Assert(t, errors.Is(err, zmqencdec.ErrFlowNotStarted))
+
+	failure := errors.New("receive failed")
+	_, err = interceptor(context.Background(), msg, func(ctx context.Context, msg *zmqencdec.Message) (*zmqencdec.Message, error) {
+		return nil, failure
+	})
+	assert.Equal(t, failure, err)
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "flow not started", attributes(spans[0])[AttrServerError].AsString())
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	assert.Equal(t, "receive failed", spans[1].Status.Description)
+	assert.Equal(t, 1, len(spans[1].Events), "\nThe error must be recorded as an event.")
+}
+
+func TestInterceptorClient(t *testing.T) {
+	server := zmq4.NewRep(context.Background())
+	if err := server.Listen("inproc://zmqtrace-client"); err != nil {
+		t.Fatalf("Listen failed. Err:%v", err)
+	}
+	defer server.Close()
+	go func() {
+		for {
+			request, err := server.Recv()
+			if err != nil {
+				return
+			}
+			response := new(bytes.Buffer)
+			binary.Write(response, binary.BigEndian, uint16(2+4+9))
+			binary.Write(response, binary.BigEndian, zmqencdec.ZMQ_CMD_GET_INFO)
+			response.Write(request.Bytes()[4:8])
+			response.WriteString("dfxp v1.0")
+			server.Send(zmq4.NewMsg(response.Bytes()))
+		}
+	}()
+
+	provider, exporter := newProvider()
+	client := zmqclient.NewZmqClient(&zmqclient.ClientOptions{Endpoint: "inproc://zmqtrace-client", To: 1})
+	client.WithInterceptors(Interceptor(provider))
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "orchestration")
+	msg := &zmqencdec.Message{
+		Header:         zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_GET_INFO},
+		GetInfoRequest: zmqencdec.MsgGetInfoRequest{FlowId: 5},
+	}
+	if _, err := client.Request(ctx, msg); err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, "dfxp GET_INFO", spans[0].Name)
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID(), "\nThe request span must be a child of the caller span.")
+	assert.Equal(t, "GET_INFO", attributes(spans[0])[AttrResponse].AsString())
+	assert.Equal(t, int64(2+2+4), attributes(spans[0])[AttrRequestBytes].AsInt64())
+	assert.Equal(t, int64(2+2+4+9), attributes(spans[0])[AttrResponseBytes].AsInt64())
+}
+
+func TestInterceptorCborClient(t *testing.T) {
+	codec := &zmqcbor.Codec{}
+	server := zmq4.NewRep(context.Background())
+	if err := server.Listen("inproc://zmqtrace-cbor"); err != nil {
+		t.Fatalf("Listen failed. Err:%v", err)
+	}
+	defer server.Close()
+	responses := make(chan int, 1)
+	go func() {
+		request, err := server.Recv()
+		if err != nil {
+			return
+		}
+		msg, err := codec.DecodeRequest(request.Bytes())
+		if err != nil {
+			return
+		}
+		frame, _ := codec.EncodeResponse(&zmqencdec.Message{
+			Header:          zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_GET_INFO},
+			GetInfoResponse: zmqencdec.MsgGetInfoResponse{FlowId: msg.GetInfoRequest.FlowId, Version: "dfxp v1.0"},
+		})
+		responses <- len(frame)
+		server.Send(zmq4.NewMsg(frame))
+	}()
+
+	provider, exporter := newProvider()
+	client := zmqclient.NewZmqClient(&zmqclient.ClientOptions{Endpoint: "inproc://zmqtrace-cbor", To: 1, Codec: codec})
+	if err := client.Connect(1); err != nil {
+		t.Fatalf("Connect failed. Err:%v", err)
+	}
+	defer client.Close()
+	client.WithInterceptors(Interceptor(provider))
+
+	msg := &zmqencdec.Message{
+		Header:         zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_GET_INFO},
+		GetInfoRequest: zmqencdec.MsgGetInfoRequest{FlowId: 5},
+	}
+	request, err := codec.Encode(msg)
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	if _, err := client.Request(context.Background(), msg); err != nil {
+		t.Fatalf("Request failed. Err:%v", err)
+	}
+
+	spans := exporter.GetSpans()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, int64(len(request)), attributes(spans[0])[AttrRequestBytes].AsInt64())
+	assert.Equal(t, int64(<-responses), attributes(spans[0])[AttrResponseBytes].AsInt64())
+}
@@ -0,0 +1,36 @@
+package zmqtunnel
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// ParseIPv4 - dotted IPv4 address as carried in Tunnel.UeIpV4/SrvIpV4
+func ParseIPv4(s string) (uint32, error) {
+	ip := net.ParseIP(s).To4()
+	if ip == nil {
+		return 0, fmt.Errorf("invalid IPv4 address %q", s)
+	}
+	return binary.BigEndian.Uint32(ip), nil
+}
+
+// FormatIPv4 - dotted form of a Tunnel.UeIpV4/SrvIpV4 address
+func FormatIPv4(ip uint32) string {
+	b := make(net.IP, 4)
+	binary.BigEndian.PutUint32(b, ip)
+	return b.String()
+}
+
+// parsePrefix - first address and size of an IPv4 CIDR prefix
+func parsePrefix(s string) (uint32, uint64, error) {
+	_, prefix, err := net.ParseCIDR(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid prefix %q. Error: %v", s, err)
+	}
+	ones, bits := prefix.Mask.Size()
+	if bits != 32 {
+		return 0, 0, fmt.Errorf("invalid prefix %q. Error: not IPv4", s)
+	}
+	return binary.BigEndian.Uint32(prefix.IP.To4()), uint64(1) << (32 - ones), nil
+}
@@ -0,0 +1,125 @@
+package zmqtunnel
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadCSV - rows of a CSV tunnel table. The first line names the columns, in
+// any order: teid_in, teid_out, ue_ip, srv_ip and the optional flow_id.
+// All invalid rows are reported in one *TableError.
+func ReadCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("read csv failed. Error: no header")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read csv failed. Error: %v", err)
+	}
+	columns, err := csvColumns(header)
+	if err != nil {
+		return nil, fmt.Errorf("read csv failed. Error: %v", err)
+	}
+
+	var rows []Row
+	var errs []*RowError
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		parser := &rowParser{row: n, line: line}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && parseErr.Err == csv.ErrFieldCount {
+			parser.fail("", fmt.Errorf("%d fields, want %d", len(record), len(header)))
+			errs = append(errs, parser.errs...)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv failed. Error: %v", err)
+		}
+
+		row := Row{}
+		if idx, ok := columns[COLUMN_FLOW_ID]; ok {
+			row.FlowId = parser.flowId(record[idx], 0)
+		}
+		row.Tunnel.TeidIn = parser.teid(COLUMN_TEID_IN, record[columns[COLUMN_TEID_IN]])
+		row.Tunnel.TeidOut = parser.teid(COLUMN_TEID_OUT, record[columns[COLUMN_TEID_OUT]])
+		row.Tunnel.UeIpV4 = parser.ip(COLUMN_UE_IP, record[columns[COLUMN_UE_IP]])
+		row.Tunnel.SrvIpV4 = parser.ip(COLUMN_SRV_IP, record[columns[COLUMN_SRV_IP]])
+		if len(parser.errs) > 0 {
+			errs = append(errs, parser.errs...)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if err := tableError(errs); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// WriteCSV - rows as a CSV tunnel table; the flow_id column is written only
+// when a row has a flow id
+func WriteCSV(w io.Writer, rows []Row) error {
+	withFlow := false
+	for _, row := range rows {
+		withFlow = withFlow || row.FlowId != 0
+	}
+
+	writer := csv.NewWriter(w)
+	header := []string{COLUMN_TEID_IN, COLUMN_TEID_OUT, COLUMN_UE_IP, COLUMN_SRV_IP}
+	if withFlow {
+		header = append([]string{COLUMN_FLOW_ID}, header...)
+	}
+	writer.Write(header)
+	for _, row := range rows {
+		record := []string{
+			strconv.FormatUint(uint64(row.Tunnel.TeidIn), 10),
+			strconv.FormatUint(uint64(row.Tunnel.TeidOut), 10),
+			FormatIPv4(row.Tunnel.UeIpV4),
+			FormatIPv4(row.Tunnel.SrvIpV4),
+		}
+		if withFlow {
+			record = append([]string{strconv.FormatUint(uint64(row.FlowId), 10)}, record...)
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("write csv failed. Error: %v", err)
+	}
+	return nil
+}
+
+// csvColumns - index of every known column of header
+func csvColumns(header []string) (map[string]int, error) {
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case COLUMN_FLOW_ID, COLUMN_TEID_IN, COLUMN_TEID_OUT, COLUMN_UE_IP, COLUMN_SRV_IP:
+		default:
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{COLUMN_TEID_IN, COLUMN_TEID_OUT, COLUMN_UE_IP, COLUMN_SRV_IP} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+	return columns, nil
+}
@@ -0,0 +1,127 @@
+package zmqtunnel
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"zmqclient/zmqencdec"
+	"zmqclient/zmqlog"
+)
+
+// TableFormat - text format of a tunnel table
+type TableFormat int
+
+const (
+	FORMAT_CSV TableFormat = iota
+	FORMAT_YAML
+)
+
+func (format TableFormat) String() string {
+	switch format {
+	case FORMAT_CSV:
+		return "csv"
+	case FORMAT_YAML:
+		return "yaml"
+	}
+	return fmt.Sprintf("TableFormat(%d)", int(format))
+}
+
+// ParseTableFormat - format of its name or of a file extension (.csv, .yaml, .yml)
+func ParseTableFormat(s string) (TableFormat, error) {
+	switch strings.TrimPrefix(strings.ToLower(s), ".") {
+	case "csv":
+		return FORMAT_CSV, nil
+	case "yaml", "yml":
+		return FORMAT_YAML, nil
+	}
+	return 0, fmt.Errorf("unknown tunnel table format %q", s)
+}
+
+// TableEncoder - ADD_TUNNELS messages to and from tunnel tables, like
+// jsonencdec.JsonEncoder for JSON
+type TableEncoder struct {
+	Format TableFormat
+	// Logger - nil for zmqlog.Default(); tables are logged at debug level only
+	Logger zmqlog.Logger
+}
+
+// Encode - ADD_TUNNELS message of a table; every row must be in the same flow
+func (enc *TableEncoder) Encode(data string) (*zmqencdec.Message, error) {
+	logger := zmqlog.Or(enc.Logger)
+	logger.Debug("encode tunnel table", "format", enc.Format, "table", data)
+
+	rows, err := enc.read(data)
+	if err != nil {
+		logger.Error("tunnel table decode failed", "format", enc.Format, "error", err)
+		return nil, err
+	}
+	flows := ByFlow(rows)
+	if len(flows) > 1 {
+		return nil, fmt.Errorf("encode tunnel table failed. Error: %d flows in one ADD_TUNNELS", len(flows))
+	}
+
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_ADD_TUNNELS},
+		AddTunnelRequest: zmqencdec.MsgAddTunnelsRequest{
+			Tunnels: Tunnels(rows),
+		},
+	}
+	if len(rows) > 0 {
+		msg.AddTunnelRequest.FlowId = rows[0].FlowId
+	}
+	return msg, nil
+}
+
+// EncodeFlows - one ADD_TUNNELS message per flow of a table
+func (enc *TableEncoder) EncodeFlows(data string) (map[uint32]*zmqencdec.Message, error) {
+	rows, err := enc.read(data)
+	if err != nil {
+		zmqlog.Or(enc.Logger).Error("tunnel table decode failed", "format", enc.Format, "error", err)
+		return nil, err
+	}
+	msgs := make(map[uint32]*zmqencdec.Message)
+	for flowId, tunnels := range ByFlow(rows) {
+		msgs[flowId] = &zmqencdec.Message{
+			Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_ADD_TUNNELS},
+			AddTunnelRequest: zmqencdec.MsgAddTunnelsRequest{
+				FlowId:  flowId,
+				Tunnels: tunnels,
+			},
+		}
+	}
+	return msgs, nil
+}
+
+// Decode - table of the tunnels of an ADD_TUNNELS message
+func (enc *TableEncoder) Decode(msg *zmqencdec.Message) (string, error) {
+	if msg.Header.Command != zmqencdec.ZMQ_CMD_ADD_TUNNELS {
+		return "", fmt.Errorf("no tunnel table for command %s", msg.Header.Command)
+	}
+	rows := FlowRows(msg.AddTunnelRequest.FlowId, msg.AddTunnelRequest.Tunnels)
+
+	buffer := new(bytes.Buffer)
+	var err error
+	switch enc.Format {
+	case FORMAT_CSV:
+		err = WriteCSV(buffer, rows)
+	case FORMAT_YAML:
+		err = WriteYAML(buffer, rows)
+	default:
+		err = fmt.Errorf("unknown tunnel table format %s", enc.Format)
+	}
+	if err != nil {
+		zmqlog.Or(enc.Logger).Error("tunnel table encode failed", "format", enc.Format, "error", err)
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func (enc *TableEncoder) read(data string) ([]Row, error) {
+	switch enc.Format {
+	case FORMAT_CSV:
+		return ReadCSV(strings.NewReader(data))
+	case FORMAT_YAML:
+		return ReadYAML(strings.NewReader(data))
+	}
+	return nil, fmt.Errorf("unknown tunnel table format %s", enc.Format)
+}
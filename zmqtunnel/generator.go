@@ -0,0 +1,166 @@
+package zmqtunnel
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"zmqclient/zmqencdec"
+)
+
+// MaxTunnelsPerRequest - tunnels fitting in one ADD_TUNNELS frame
+// (u16 length, command, flow id and tunnels number, 16 bytes per tunnel)
+const MaxTunnelsPerRequest = (math.MaxUint16 - 10) / 16
+
+// GeneratorSpec - tunnels to generate for bulk provisioning
+type GeneratorSpec struct {
+	Count int
+	// TeidStart - first sequential TEID, 1 when zero
+	TeidStart uint32
+	// TeidStep - gap between sequential TEIDs, 1 when zero
+	TeidStep uint32
+	// TeidRandom - unique random TEIDs instead of sequential ones
+	TeidRandom bool
+	// TeidOutOffset - TeidOut = TeidIn + TeidOutOffset
+	TeidOutOffset uint32
+	// UePrefix - IPv4 CIDR the UE addresses are taken from, network and
+	// broadcast addresses excluded (except for /31 and /32)
+	UePrefix string
+	// UeRandom - unique random UE addresses instead of sequential ones
+	UeRandom bool
+	// ServerIps - server addresses, assigned round robin
+	ServerIps []string
+	// Seed - seed of the random TEIDs and UE addresses, same seed same tunnels
+	Seed int64
+}
+
+// Generate - tunnels of spec, in generation order
+func Generate(spec *GeneratorSpec) ([]zmqencdec.Tunnel, error) {
+	if spec.Count <= 0 {
+		return nil, fmt.Errorf("generate tunnels failed. Error: count %d", spec.Count)
+	}
+	if len(spec.ServerIps) == 0 {
+		return nil, fmt.Errorf("generate tunnels failed. Error: no server address")
+	}
+	servers := make([]uint32, len(spec.ServerIps))
+	for i, s := range spec.ServerIps {
+		ip, err := ParseIPv4(s)
+		if err != nil {
+			return nil, fmt.Errorf("generate tunnels failed. Error: %v", err)
+		}
+		servers[i] = ip
+	}
+
+	random := rand.New(rand.NewSource(spec.Seed))
+	teids, err := spec.teids(random)
+	if err != nil {
+		return nil, fmt.Errorf("generate tunnels failed. Error: %v", err)
+	}
+	ues, err := spec.ueAddresses(random)
+	if err != nil {
+		return nil, fmt.Errorf("generate tunnels failed. Error: %v", err)
+	}
+
+	tunnels := make([]zmqencdec.Tunnel, spec.Count)
+	for i := range tunnels {
+		tunnels[i] = zmqencdec.Tunnel{
+			TeidIn:  teids[i],
+			TeidOut: teids[i] + spec.TeidOutOffset,
+			UeIpV4:  ues[i],
+			SrvIpV4: servers[i%len(servers)],
+		}
+	}
+	return tunnels, nil
+}
+
+// Batches - tunnels split in slices of at most size tunnels, e.g.
+// MaxTunnelsPerRequest; the slices share the tunnels array
+func Batches(tunnels []zmqencdec.Tunnel, size int) [][]zmqencdec.Tunnel {
+	if size <= 0 || size > MaxTunnelsPerRequest {
+		size = MaxTunnelsPerRequest
+	}
+	batches := make([][]zmqencdec.Tunnel, 0, (len(tunnels)+size-1)/size)
+	for len(tunnels) > size {
+		batches = append(batches, tunnels[:size:size])
+		tunnels = tunnels[size:]
+	}
+	if len(tunnels) > 0 {
+		batches = append(batches, tunnels)
+	}
+	return batches
+}
+
+// teids - Count unique non zero TEIDs whose TeidOut does not overflow
+func (spec *GeneratorSpec) teids(random *rand.Rand) ([]uint32, error) {
+	last := uint64(math.MaxUint32 - spec.TeidOutOffset)
+	teids := make([]uint32, spec.Count)
+
+	if spec.TeidRandom {
+		if uint64(spec.Count) > last/2 {
+			return nil, fmt.Errorf("%d random TEIDs do not fit below %d", spec.Count, last)
+		}
+		used := make(map[uint32]bool, spec.Count)
+		for i := range teids {
+			teid := uint32(1 + random.Int63n(int64(last)))
+			for used[teid] {
+				teid = uint32(1 + random.Int63n(int64(last)))
+			}
+			used[teid] = true
+			teids[i] = teid
+		}
+		return teids, nil
+	}
+
+	start, step := uint64(spec.TeidStart), uint64(spec.TeidStep)
+	if start == 0 {
+		start = 1
+	}
+	if step == 0 {
+		step = 1
+	}
+	if end := start + uint64(spec.Count-1)*step; end > last {
+		return nil, fmt.Errorf("TEID %d of tunnel %d overflows", end, spec.Count)
+	}
+	for i := range teids {
+		teids[i] = uint32(start + uint64(i)*step)
+	}
+	return teids, nil
+}
+
+// ueAddresses - Count unique host addresses of UePrefix
+func (spec *GeneratorSpec) ueAddresses(random *rand.Rand) ([]uint32, error) {
+	base, size, err := parsePrefix(spec.UePrefix)
+	if err != nil {
+		return nil, err
+	}
+	first, hosts := uint64(base), size
+	if size > 2 {
+		first, hosts = first+1, size-2
+	}
+	if uint64(spec.Count) > hosts {
+		return nil, fmt.Errorf("prefix %s has %d addresses for %d tunnels", spec.UePrefix, hosts, spec.Count)
+	}
+
+	ues := make([]uint32, spec.Count)
+	switch {
+	case !spec.UeRandom:
+		for i := range ues {
+			ues[i] = uint32(first + uint64(i))
+		}
+	case uint64(spec.Count) > hosts/2:
+		// dense: shuffle the whole prefix
+		for i, offset := range random.Perm(int(hosts))[:spec.Count] {
+			ues[i] = uint32(first + uint64(offset))
+		}
+	default:
+		used := make(map[uint32]bool, spec.Count)
+		for i := range ues {
+			ue := uint32(first + uint64(random.Int63n(int64(hosts))))
+			for used[ue] {
+				ue = uint32(first + uint64(random.Int63n(int64(hosts))))
+			}
+			used[ue] = true
+			ues[i] = ue
+		}
+	}
+	return ues, nil
+}
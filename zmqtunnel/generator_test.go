@@ -0,0 +1,98 @@
+package zmqtunnel
+
+import (
+	"testing"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+func TestGenerateSequential(t *testing.T) {
+	tunnels, err := Generate(&GeneratorSpec{
+		Count:         3,
+		TeidStart:     100,
+		TeidStep:      10,
+		TeidOutOffset: 0x10000,
+		UePrefix:      "10.1.0.0/24",
+		ServerIps:     []string{"192.168.0.1", "192.168.0.2"},
+	})
+	if err != nil {
+		t.Fatalf("Generate failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, []zmqencdec.Tunnel{
+		{TeidIn: 100, TeidOut: 0x10064, UeIpV4: 0x0a010001, SrvIpV4: 0xc0a80001},
+		{TeidIn: 110, TeidOut: 0x1006e, UeIpV4: 0x0a010002, SrvIpV4: 0xc0a80002},
+		{TeidIn: 120, TeidOut: 0x10078, UeIpV4: 0x0a010003, SrvIpV4: 0xc0a80001},
+	}, tunnels)
+}
+
+func TestGenerateRandom(t *testing.T) {
+	spec := &GeneratorSpec{
+		Count:      200,
+		TeidRandom: true,
+		UePrefix:   "10.2.0.0/24",
+		UeRandom:   true,
+		ServerIps:  []string{"192.168.0.1"},
+		Seed:       7,
+	}
+	tunnels, err := Generate(spec)
+	if err != nil {
+		t.Fatalf("Generate failed. Err:%v", err)
+	}
+	again, err := Generate(spec)
+	if err != nil {
+		t.Fatalf("Generate failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, tunnels, again)
+
+	teids := make(map[uint32]bool)
+	ues := make(map[uint32]bool)
+	for _, tunnel := range tunnels {
+		assert.Assert(t, tunnel.TeidIn != 0)
+		assert.Assert(t, tunnel.UeIpV4 > 0x0a020000 && tunnel.UeIpV4 < 0x0a0200ff, "\nThe UE address %s must be a host of the prefix.", FormatIPv4(tunnel.UeIpV4))
+		teids[tunnel.TeidIn] = true
+		ues[tunnel.UeIpV4] = true
+	}
+	assert.Equal(t, 200, len(teids), "\nThe TEIDs must be unique.")
+	assert.Equal(t, 200, len(ues), "\nThe UE addresses must be unique.")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, test := range []struct {
+		spec GeneratorSpec
+		err  string
+	}{
+		{GeneratorSpec{Count: 0, UePrefix: "10.0.0.0/24", ServerIps: []string{"1.1.1.1"}}, "count 0"},
+		{GeneratorSpec{Count: 1, UePrefix: "10.0.0.0/24"}, "no server address"},
+		{GeneratorSpec{Count: 1, UePrefix: "10.0.0.0/24", ServerIps: []string{"::1"}}, "invalid IPv4 address"},
+		{GeneratorSpec{Count: 1, UePrefix: "2001:db8::/64", ServerIps: []string{"1.1.1.1"}}, "not IPv4"},
+		{GeneratorSpec{Count: 255, UePrefix: "10.0.0.0/24", ServerIps: []string{"1.1.1.1"}}, "has 254 addresses"},
+		{GeneratorSpec{Count: 2, TeidStart: 0xffffffff, UePrefix: "10.0.0.0/24", ServerIps: []string{"1.1.1.1"}}, "overflows"},
+	} {
+		_, err := Generate(&test.spec)
+		assert.ErrorContains(t, err, test.err)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	tunnels := make([]zmqencdec.Tunnel, 2*MaxTunnelsPerRequest+1)
+	batches := Batches(tunnels, 0)
+	assert.Equal(t, 3, len(batches))
+	assert.Equal(t, MaxTunnelsPerRequest, len(batches[0]))
+	assert.Equal(t, 1, len(batches[2]))
+
+	batches = Batches(tunnels[:5], 2)
+	assert.Equal(t, 3, len(batches))
+	assert.Equal(t, 2, cap(batches[0]), "\nAppending to a batch must not overwrite the next one.")
+}
+
+func TestIPv4(t *testing.T) {
+	ip, err := ParseIPv4("10.0.0.1")
+	if err != nil {
+		t.Fatalf("ParseIPv4 failed. Err:%v", err)
+	}
+	assert.Equal(t, uint32(0x0a000001), ip)
+	assert.Equal(t, "10.0.0.1", FormatIPv4(ip))
+	_, err = ParseIPv4("10.0.0")
+	assert.ErrorContains(t, err, "invalid IPv4 address")
+}
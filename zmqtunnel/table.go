@@ -0,0 +1,148 @@
+package zmqtunnel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"zmqclient/zmqencdec"
+)
+
+// table columns, in the order they are written
+const (
+	COLUMN_FLOW_ID  = "flow_id"
+	COLUMN_TEID_IN  = "teid_in"
+	COLUMN_TEID_OUT = "teid_out"
+	COLUMN_UE_IP    = "ue_ip"
+	COLUMN_SRV_IP   = "srv_ip"
+)
+
+// Row - one tunnel of a tunnel table
+type Row struct {
+	// FlowId - 0 when the table gives none
+	FlowId uint32
+	Tunnel zmqencdec.Tunnel
+}
+
+// RowError - invalid value of a table row; Line is the line in the file
+type RowError struct {
+	Row    int
+	Line   int
+	Column string
+	Err    error
+}
+
+func (e *RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("row %d (line %d): %v", e.Row, e.Line, e.Err)
+	}
+	return fmt.Sprintf("row %d (line %d): %s: %v", e.Row, e.Line, e.Column, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// TableError - every invalid row of a table
+type TableError struct {
+	Rows []*RowError
+}
+
+func (e *TableError) Error() string {
+	lines := make([]string, len(e.Rows))
+	for i, row := range e.Rows {
+		lines[i] = row.Error()
+	}
+	return fmt.Sprintf("invalid tunnel table. Error: %s", strings.Join(lines, "; "))
+}
+
+// Unwrap - the row errors, for errors.As
+func (e *TableError) Unwrap() []error {
+	errs := make([]error, len(e.Rows))
+	for i, row := range e.Rows {
+		errs[i] = row
+	}
+	return errs
+}
+
+// Tunnels - tunnels of rows, in table order
+func Tunnels(rows []Row) []zmqencdec.Tunnel {
+	tunnels := make([]zmqencdec.Tunnel, len(rows))
+	for i, row := range rows {
+		tunnels[i] = row.Tunnel
+	}
+	return tunnels
+}
+
+// ByFlow - tunnels of rows grouped by flow id, in table order
+func ByFlow(rows []Row) map[uint32][]zmqencdec.Tunnel {
+	flows := make(map[uint32][]zmqencdec.Tunnel)
+	for _, row := range rows {
+		flows[row.FlowId] = append(flows[row.FlowId], row.Tunnel)
+	}
+	return flows
+}
+
+// FlowRows - rows of tunnels, all in flowId
+func FlowRows(flowId uint32, tunnels []zmqencdec.Tunnel) []Row {
+	rows := make([]Row, len(tunnels))
+	for i, tunnel := range tunnels {
+		rows[i] = Row{FlowId: flowId, Tunnel: tunnel}
+	}
+	return rows
+}
+
+// rowParser - collects the errors of one table row
+type rowParser struct {
+	row  int
+	line int
+	errs []*RowError
+}
+
+func (parser *rowParser) fail(column string, err error) {
+	parser.errs = append(parser.errs, &RowError{Row: parser.row, Line: parser.line, Column: column, Err: err})
+}
+
+// teid - decimal or 0x hexadecimal u32
+func (parser *rowParser) teid(column, value string) uint32 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		parser.fail(column, fmt.Errorf("missing value"))
+		return 0
+	}
+	n, err := strconv.ParseUint(value, 0, 32)
+	if err != nil {
+		parser.fail(column, fmt.Errorf("invalid value %q", value))
+		return 0
+	}
+	return uint32(n)
+}
+
+// flowId - optional u32, 0 when empty
+func (parser *rowParser) flowId(value string, fallback uint32) uint32 {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return parser.teid(COLUMN_FLOW_ID, value)
+}
+
+func (parser *rowParser) ip(column, value string) uint32 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		parser.fail(column, fmt.Errorf("missing value"))
+		return 0
+	}
+	ip, err := ParseIPv4(value)
+	if err != nil {
+		parser.fail(column, err)
+		return 0
+	}
+	return ip
+}
+
+// tableError - nil without row errors
+func tableError(errs []*RowError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &TableError{Rows: errs}
+}
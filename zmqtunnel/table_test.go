@@ -0,0 +1,174 @@
+package zmqtunnel
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+var tableRows = []Row{
+	{FlowId: 1, Tunnel: zmqencdec.Tunnel{TeidIn: 100, TeidOut: 200, UeIpV4: 0x0a000001, SrvIpV4: 0xc0a80001}},
+	{FlowId: 2, Tunnel: zmqencdec.Tunnel{TeidIn: 101, TeidOut: 201, UeIpV4: 0x0a000002, SrvIpV4: 0xc0a80001}},
+}
+
+func TestReadCSV(t *testing.T) {
+	table := `# lab export
+srv_ip,ue_ip,teid_in,teid_out,flow_id
+192.168.0.1, 10.0.0.1, 100, 0xc8, 1
+192.168.0.1, 10.0.0.2, 101, 201, 2
+`
+	rows, err := ReadCSV(strings.NewReader(table))
+	if err != nil {
+		t.Fatalf("ReadCSV failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, tableRows, rows)
+
+	buffer := new(bytes.Buffer)
+	if err := WriteCSV(buffer, rows); err != nil {
+		t.Fatalf("WriteCSV failed. Err:%v", err)
+	}
+	assert.Equal(t, "flow_id,teid_in,teid_out,ue_ip,srv_ip\n1,100,200,10.0.0.1,192.168.0.1\n2,101,201,10.0.0.2,192.168.0.1\n", buffer.String())
+
+	again, err := ReadCSV(buffer)
+	if err != nil {
+		t.Fatalf("ReadCSV failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, rows, again)
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	table := `teid_in,teid_out,ue_ip,srv_ip
+100,200,10.0.0.1,192.168.0.1
+x,200,10.0.0.300,192.168.0.1
+101,201,10.0.0.2
+
+102,202,10.0.0.3,
+`
+	_, err := ReadCSV(strings.NewReader(table))
+	var tableErr *TableError
+	assert.Assert(t, errors.As(err, &tableErr), "\nThe row errors must be returned as *TableError. Err:%v", err)
+	var lines []string
+	for _, row := range tableErr.Rows {
+		lines = append(lines, row.Error())
+	}
+	assert.DeepEqual(t, []string{
+		`row 2 (line 3): teid_in: invalid value "x"`,
+		`row 2 (line 3): ue_ip: invalid IPv4 address "10.0.0.300"`,
+		`row 3 (line 4): 3 fields, want 4`,
+		`row 4 (line 6): srv_ip: missing value`,
+	}, lines)
+
+	var rowErr *RowError
+	assert.Assert(t, errors.As(err, &rowErr))
+	assert.Equal(t, 2, rowErr.Row)
+
+	_, err = ReadCSV(strings.NewReader("teid_in,teid_out,ue_ip\n"))
+	assert.ErrorContains(t, err, `missing column "srv_ip"`)
+	_, err = ReadCSV(strings.NewReader("teid_in,teid_out,ue_ip,srv_ip,qos\n"))
+	assert.ErrorContains(t, err, `unknown column "qos"`)
+}
+
+func TestReadYAML(t *testing.T) {
+	table := `flow_id: 1
+tunnels:
+  - teid_in: 100
+    teid_out: 0xc8
+    ue_ip: 10.0.0.1
+    srv_ip: 192.168.0.1
+  - teid_in: 101
+    teid_out: 201
+    ue_ip: 10.0.0.2
+    srv_ip: 192.168.0.1
+    flow_id: 2
+`
+	rows, err := ReadYAML(strings.NewReader(table))
+	if err != nil {
+		t.Fatalf("ReadYAML failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, tableRows, rows)
+
+	buffer := new(bytes.Buffer)
+	if err := WriteYAML(buffer, FlowRows(7, Tunnels(rows))); err != nil {
+		t.Fatalf("WriteYAML failed. Err:%v", err)
+	}
+	assert.Equal(t, `flow_id: 7
+tunnels:
+  - teid_in: 100
+    teid_out: 200
+    ue_ip: 10.0.0.1
+    srv_ip: 192.168.0.1
+  - teid_in: 101
+    teid_out: 201
+    ue_ip: 10.0.0.2
+    srv_ip: 192.168.0.1
+`, buffer.String())
+
+	buffer.Reset()
+	if err := WriteYAML(buffer, rows); err != nil {
+		t.Fatalf("WriteYAML failed. Err:%v", err)
+	}
+	again, err := ReadYAML(buffer)
+	if err != nil {
+		t.Fatalf("ReadYAML failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, rows, again)
+}
+
+func TestReadYAMLErrors(t *testing.T) {
+	table := `tunnels:
+  - teid_in: 100
+    teid_out: 200
+    ue_ip: 10.0.0.1
+    srv_ip: 192.168.0.1
+  - teid_in: 101
+    teid_out: -1
+    ue_ip: 10.0.0.2
+  - teid_in: 102
+    teid_out: 202
+    ue_ip: 10.0.0.3
+    srv_ip: 192.168.0.1
+    qos: 5
+`
+	_, err := ReadYAML(strings.NewReader(table))
+	assert.Error(t, err, `invalid tunnel table. Error: row 2 (line 6): teid_out: invalid value "-1"; `+
+		`row 2 (line 6): srv_ip: missing value; row 3 (line 9): unknown column "qos"`)
+}
+
+func TestTableEncoder(t *testing.T) {
+	csvEncoder := &TableEncoder{Format: FORMAT_CSV}
+	msg, err := csvEncoder.Encode("teid_in,teid_out,ue_ip,srv_ip,flow_id\n100,200,10.0.0.1,192.168.0.1,3\n")
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	assert.Equal(t, zmqencdec.ZMQ_CMD_ADD_TUNNELS, msg.Header.Command)
+	assert.Equal(t, uint32(3), msg.AddTunnelRequest.FlowId)
+	assert.DeepEqual(t, Tunnels(tableRows[:1]), msg.AddTunnelRequest.Tunnels)
+
+	format, err := ParseTableFormat(".yml")
+	if err != nil {
+		t.Fatalf("ParseTableFormat failed. Err:%v", err)
+	}
+	yamlEncoder := &TableEncoder{Format: format}
+	table, err := yamlEncoder.Decode(msg)
+	if err != nil {
+		t.Fatalf("Decode failed. Err:%v", err)
+	}
+	again, err := yamlEncoder.Encode(table)
+	if err != nil {
+		t.Fatalf("Encode failed. Err:%v", err)
+	}
+	assert.DeepEqual(t, msg, again)
+
+	_, err = csvEncoder.Encode("teid_in,teid_out,ue_ip,srv_ip,flow_id\n100,200,10.0.0.1,192.168.0.1,1\n101,201,10.0.0.2,192.168.0.1,2\n")
+	assert.ErrorContains(t, err, "2 flows in one ADD_TUNNELS")
+	msgs, err := csvEncoder.EncodeFlows("teid_in,teid_out,ue_ip,srv_ip,flow_id\n100,200,10.0.0.1,192.168.0.1,1\n101,201,10.0.0.2,192.168.0.1,2\n")
+	if err != nil {
+		t.Fatalf("EncodeFlows failed. Err:%v", err)
+	}
+	assert.Equal(t, 2, len(msgs))
+	assert.Equal(t, uint32(101), msgs[2].AddTunnelRequest.Tunnels[0].TeidIn)
+}
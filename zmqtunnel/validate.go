@@ -0,0 +1,166 @@
+package zmqtunnel
+
+import (
+	"fmt"
+	"strings"
+	"zmqclient/zmqencdec"
+)
+
+// ViolationCode - kind of invalid tunnel
+type ViolationCode int
+
+const (
+	// VIOLATION_RESERVED_TEID - TEID 0
+	VIOLATION_RESERVED_TEID ViolationCode = iota
+	// VIOLATION_DUPLICATE_TEID - TEID given twice in the batch
+	VIOLATION_DUPLICATE_TEID
+	// VIOLATION_KNOWN_TEID - TEID already added to the flow
+	VIOLATION_KNOWN_TEID
+	// VIOLATION_UNKNOWN_TEID - TEID to delete never added to the flow
+	VIOLATION_UNKNOWN_TEID
+	// VIOLATION_ADDRESS - zero, loopback, multicast, broadcast or reserved address
+	VIOLATION_ADDRESS
+)
+
+var violationNames = map[ViolationCode]string{
+	VIOLATION_RESERVED_TEID:  "RESERVED_TEID",
+	VIOLATION_DUPLICATE_TEID: "DUPLICATE_TEID",
+	VIOLATION_KNOWN_TEID:     "KNOWN_TEID",
+	VIOLATION_UNKNOWN_TEID:   "UNKNOWN_TEID",
+	VIOLATION_ADDRESS:        "ADDRESS",
+}
+
+func (code ViolationCode) String() string {
+	if name, ok := violationNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("ViolationCode(%d)", int(code))
+}
+
+// Violation - one invalid value of a request
+type Violation struct {
+	// Index - position of the tunnel or TEID in the request
+	Index  int
+	Field  string
+	Code   ViolationCode
+	Reason string
+}
+
+func (violation *Violation) String() string {
+	return fmt.Sprintf("#%d %s: %s", violation.Index, violation.Field, violation.Reason)
+}
+
+// ValidationError - every violation of an ADD_TUNNELS or DEL_TUNNELS request
+type ValidationError struct {
+	Command    zmqencdec.ZmqMessageType
+	FlowId     uint32
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, len(e.Violations))
+	for i := range e.Violations {
+		reasons[i] = e.Violations[i].String()
+	}
+	return fmt.Sprintf("%s flow %d validation failed. Error: %s", e.Command, e.FlowId, strings.Join(reasons, "; "))
+}
+
+// KnownTunnels - tunnels already on dfxp, e.g. the table kept by zmqclient
+type KnownTunnels interface {
+	HasTunnel(flowId uint32, teid uint32) bool
+}
+
+// Validate - check the ADD_TUNNELS or DEL_TUNNELS request of msg, nil for the
+// other commands. A nil known skips the checks against the added tunnels.
+func Validate(msg *zmqencdec.Message, known KnownTunnels) error {
+	switch msg.Header.Command {
+	case zmqencdec.ZMQ_CMD_ADD_TUNNELS:
+		return ValidateAdd(&msg.AddTunnelRequest, known)
+	case zmqencdec.ZMQ_CMD_DEL_TUNNELS:
+		return ValidateDel(&msg.DelTunnelsRequest, known)
+	}
+	return nil
+}
+
+// ValidateAdd - reserved TEIDs, TEIDs duplicated in the batch or already
+// added, and UE and server addresses that cannot be unicast hosts
+func ValidateAdd(request *zmqencdec.MsgAddTunnelsRequest, known KnownTunnels) error {
+	violations := violationList{}
+	fail := violations.add
+	seen := make(map[uint32]int, len(request.Tunnels))
+	for i, tunnel := range request.Tunnels {
+		switch first, ok := seen[tunnel.TeidIn]; {
+		case tunnel.TeidIn == 0:
+			fail(i, "TeidIn", VIOLATION_RESERVED_TEID, "TEID 0 is reserved")
+		case ok:
+			fail(i, "TeidIn", VIOLATION_DUPLICATE_TEID, "TEID %d already in #%d", tunnel.TeidIn, first)
+		case known != nil && known.HasTunnel(request.FlowId, tunnel.TeidIn):
+			fail(i, "TeidIn", VIOLATION_KNOWN_TEID, "TEID %d already added", tunnel.TeidIn)
+		default:
+			seen[tunnel.TeidIn] = i
+		}
+		if tunnel.TeidOut == 0 {
+			fail(i, "TeidOut", VIOLATION_RESERVED_TEID, "TEID 0 is reserved")
+		}
+		if reason := hostAddress(tunnel.UeIpV4); reason != "" {
+			fail(i, "UeIpV4", VIOLATION_ADDRESS, "%s %s", FormatIPv4(tunnel.UeIpV4), reason)
+		}
+		if reason := hostAddress(tunnel.SrvIpV4); reason != "" {
+			fail(i, "SrvIpV4", VIOLATION_ADDRESS, "%s %s", FormatIPv4(tunnel.SrvIpV4), reason)
+		}
+	}
+	return violations.err(zmqencdec.ZMQ_CMD_ADD_TUNNELS, request.FlowId)
+}
+
+// ValidateDel - reserved TEIDs, TEIDs duplicated in the batch or never added
+func ValidateDel(request *zmqencdec.MsgDelTunnelsRequest, known KnownTunnels) error {
+	violations := violationList{}
+	fail := violations.add
+	seen := make(map[uint32]int, len(request.Teids))
+	for i, teid := range request.Teids {
+		switch first, ok := seen[teid]; {
+		case teid == 0:
+			fail(i, "Teids", VIOLATION_RESERVED_TEID, "TEID 0 is reserved")
+		case ok:
+			fail(i, "Teids", VIOLATION_DUPLICATE_TEID, "TEID %d already in #%d", teid, first)
+		case known != nil && !known.HasTunnel(request.FlowId, teid):
+			fail(i, "Teids", VIOLATION_UNKNOWN_TEID, "TEID %d never added", teid)
+		default:
+			seen[teid] = i
+		}
+	}
+	return violations.err(zmqencdec.ZMQ_CMD_DEL_TUNNELS, request.FlowId)
+}
+
+// hostAddress - why ip cannot be a unicast host address, empty if it can
+func hostAddress(ip uint32) string {
+	switch {
+	case ip == 0:
+		return "is the zero address"
+	case ip == 0xffffffff:
+		return "is the broadcast address"
+	case ip>>24 == 0:
+		return "is in 0.0.0.0/8"
+	case ip>>24 == 127:
+		return "is a loopback address"
+	case ip>>28 == 0xe:
+		return "is a multicast address"
+	case ip>>28 == 0xf:
+		return "is a reserved address"
+	}
+	return ""
+}
+
+type violationList []Violation
+
+func (list *violationList) add(index int, field string, code ViolationCode, reason string, args ...interface{}) {
+	*list = append(*list, Violation{Index: index, Field: field, Code: code, Reason: fmt.Sprintf(reason, args...)})
+}
+
+// err - *ValidationError of the violations, nil without any
+func (list violationList) err(command zmqencdec.ZmqMessageType, flowId uint32) error {
+	if len(list) == 0 {
+		return nil
+	}
+	return &ValidationError{Command: command, FlowId: flowId, Violations: list}
+}
@@ -0,0 +1,76 @@
+package zmqtunnel
+
+import (
+	"errors"
+	"testing"
+	"zmqclient/zmqencdec"
+
+	"gotest.tools/assert"
+)
+
+// knownTeids - KnownTunnels of flow 1
+type knownTeids map[uint32]bool
+
+func (known knownTeids) HasTunnel(flowId uint32, teid uint32) bool {
+	return flowId == 1 && known[teid]
+}
+
+func TestValidateAdd(t *testing.T) {
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_ADD_TUNNELS},
+		AddTunnelRequest: zmqencdec.MsgAddTunnelsRequest{
+			FlowId: 1,
+			Tunnels: []zmqencdec.Tunnel{
+				{TeidIn: 10, TeidOut: 11, UeIpV4: 0x0a000001, SrvIpV4: 0xc0a80001},
+				{TeidIn: 0, TeidOut: 0, UeIpV4: 0, SrvIpV4: 0xc0a80001},
+				{TeidIn: 10, TeidOut: 12, UeIpV4: 0xe0000001, SrvIpV4: 0xffffffff},
+				{TeidIn: 5, TeidOut: 13, UeIpV4: 0x7f000001, SrvIpV4: 0xf0000001},
+			},
+		},
+	}
+	err := Validate(msg, knownTeids{5: true})
+
+	var validationErr *ValidationError
+	assert.Assert(t, errors.As(err, &validationErr), "\nThe violations must be returned as *ValidationError. Err:%v", err)
+	assert.Equal(t, zmqencdec.ZMQ_CMD_ADD_TUNNELS, validationErr.Command)
+	assert.Equal(t, uint32(1), validationErr.FlowId)
+	var codes []string
+	for _, violation := range validationErr.Violations {
+		codes = append(codes, violation.String()+" "+violation.Code.String())
+	}
+	assert.DeepEqual(t, []string{
+		"#1 TeidIn: TEID 0 is reserved RESERVED_TEID",
+		"#1 TeidOut: TEID 0 is reserved RESERVED_TEID",
+		"#1 UeIpV4: 0.0.0.0 is the zero address ADDRESS",
+		"#2 TeidIn: TEID 10 already in #0 DUPLICATE_TEID",
+		"#2 UeIpV4: 224.0.0.1 is a multicast address ADDRESS",
+		"#2 SrvIpV4: 255.255.255.255 is the broadcast address ADDRESS",
+		"#3 TeidIn: TEID 5 already added KNOWN_TEID",
+		"#3 UeIpV4: 127.0.0.1 is a loopback address ADDRESS",
+		"#3 SrvIpV4: 240.0.0.1 is a reserved address ADDRESS",
+	}, codes)
+
+	msg.AddTunnelRequest.Tunnels = msg.AddTunnelRequest.Tunnels[:1]
+	assert.NilError(t, Validate(msg, knownTeids{5: true}))
+	assert.NilError(t, Validate(msg, nil))
+}
+
+func TestValidateDel(t *testing.T) {
+	msg := &zmqencdec.Message{
+		Header: zmqencdec.MsgHeader{Command: zmqencdec.ZMQ_CMD_DEL_TUNNELS},
+		DelTunnelsRequest: zmqencdec.MsgDelTunnelsRequest{
+			FlowId: 1,
+			Teids:  []uint32{5, 0, 5, 6},
+		},
+	}
+	err := Validate(msg, knownTeids{5: true})
+	assert.Error(t, err, "DEL_TUNNELS flow 1 validation failed. Error: #1 Teids: TEID 0 is reserved; "+
+		"#2 Teids: TEID 5 already in #0; #3 Teids: TEID 6 never added")
+
+	// without a table, the TEIDs never added cannot be told
+	msg.DelTunnelsRequest.Teids = []uint32{6}
+	assert.NilError(t, Validate(msg, nil))
+
+	msg.Header.Command = zmqencdec.ZMQ_CMD_DEL_ALL_TUNNELS
+	assert.NilError(t, Validate(msg, knownTeids{}))
+}
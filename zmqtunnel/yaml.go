@@ -0,0 +1,144 @@
+package zmqtunnel
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlTable - YAML tunnel table:
+//
+//	flow_id: 1            # optional, default of the rows
+//	tunnels:
+//	  - teid_in: 100
+//	    teid_out: 0x100   # decimal or hexadecimal
+//	    ue_ip: 10.0.0.1
+//	    srv_ip: 192.168.0.1
+//	    flow_id: 2        # optional
+type yamlTable struct {
+	FlowId  string      `yaml:"flow_id,omitempty"`
+	Tunnels []yaml.Node `yaml:"tunnels"`
+}
+
+type yamlRow struct {
+	FlowId  string `yaml:"flow_id,omitempty"`
+	TeidIn  string `yaml:"teid_in"`
+	TeidOut string `yaml:"teid_out"`
+	UeIp    string `yaml:"ue_ip"`
+	SrvIp   string `yaml:"srv_ip"`
+}
+
+// ReadYAML - rows of a YAML tunnel table, see yamlTable.
+// All invalid rows are reported in one *TableError.
+func ReadYAML(r io.Reader) ([]Row, error) {
+	table := yamlTable{}
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&table); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read yaml failed. Error: %v", err)
+	}
+
+	header := &rowParser{}
+	flowId := header.flowId(table.FlowId, 0)
+	if len(header.errs) > 0 {
+		return nil, fmt.Errorf("read yaml failed. Error: %v", header.errs[0].Err)
+	}
+
+	rows := make([]Row, 0, len(table.Tunnels))
+	var errs []*RowError
+	for i := range table.Tunnels {
+		node := &table.Tunnels[i]
+		parser := &rowParser{row: i + 1, line: node.Line}
+		values := yamlRow{}
+		if err := yamlKnownKeys(node); err != nil {
+			parser.fail("", err)
+			errs = append(errs, parser.errs...)
+			continue
+		}
+		if err := node.Decode(&values); err != nil {
+			parser.fail("", err)
+			errs = append(errs, parser.errs...)
+			continue
+		}
+
+		row := Row{
+			FlowId: parser.flowId(values.FlowId, flowId),
+		}
+		row.Tunnel.TeidIn = parser.teid(COLUMN_TEID_IN, values.TeidIn)
+		row.Tunnel.TeidOut = parser.teid(COLUMN_TEID_OUT, values.TeidOut)
+		row.Tunnel.UeIpV4 = parser.ip(COLUMN_UE_IP, values.UeIp)
+		row.Tunnel.SrvIpV4 = parser.ip(COLUMN_SRV_IP, values.SrvIp)
+		if len(parser.errs) > 0 {
+			errs = append(errs, parser.errs...)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if err := tableError(errs); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// WriteYAML - rows as a YAML tunnel table; a flow id shared by every row is
+// written once at the top
+func WriteYAML(w io.Writer, rows []Row) error {
+	shared := len(rows) > 0
+	for _, row := range rows {
+		shared = shared && row.FlowId == rows[0].FlowId
+	}
+
+	type tunnel struct {
+		FlowId  uint32 `yaml:"flow_id,omitempty"`
+		TeidIn  uint32 `yaml:"teid_in"`
+		TeidOut uint32 `yaml:"teid_out"`
+		UeIp    string `yaml:"ue_ip"`
+		SrvIp   string `yaml:"srv_ip"`
+	}
+	table := struct {
+		FlowId  uint32   `yaml:"flow_id,omitempty"`
+		Tunnels []tunnel `yaml:"tunnels"`
+	}{
+		Tunnels: make([]tunnel, len(rows)),
+	}
+	if shared {
+		table.FlowId = rows[0].FlowId
+	}
+	for i, row := range rows {
+		table.Tunnels[i] = tunnel{
+			TeidIn:  row.Tunnel.TeidIn,
+			TeidOut: row.Tunnel.TeidOut,
+			UeIp:    FormatIPv4(row.Tunnel.UeIpV4),
+			SrvIp:   FormatIPv4(row.Tunnel.SrvIpV4),
+		}
+		if !shared {
+			table.Tunnels[i].FlowId = row.FlowId
+		}
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&table); err != nil {
+		return fmt.Errorf("write yaml failed. Error: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("write yaml failed. Error: %v", err)
+	}
+	return nil
+}
+
+// yamlKnownKeys - node is a mapping of table columns only
+func yamlKnownKeys(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("not a mapping")
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		switch key := node.Content[i].Value; key {
+		case COLUMN_FLOW_ID, COLUMN_TEID_IN, COLUMN_TEID_OUT, COLUMN_UE_IP, COLUMN_SRV_IP:
+		default:
+			return fmt.Errorf("unknown column %q", key)
+		}
+	}
+	return nil
+}